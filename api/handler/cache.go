@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/ankush-web-eng/brolang/cache"
+)
+
+// Cache, when set, keeps the responses of recent compile requests, so a
+// program already run on the same input is answered without running it.
+// Profiled requests, whose timings differ from run to run, are never cached,
+// and neither are programs that crashed their sandbox worker. Every builtin
+// gives the same result on the same input, so nothing else varies.
+var Cache cache.Cache
+
+// SetCache makes the compile handlers reuse responses kept in c.
+func SetCache(c cache.Cache) {
+	Cache = c
+}
+
+// cacheKey hashes everything in a request that decides its response.
+func cacheKey(req CompileRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// cached returns the kept response to req, if there is one.
+func cached(req CompileRequest) (CompileResponse, bool) {
+	if Cache == nil || req.Profile {
+		return CompileResponse{}, false
+	}
+	data, ok := Cache.Get(cacheKey(req))
+	if !ok {
+		cacheLookups.Inc("miss")
+		return CompileResponse{}, false
+	}
+	cacheLookups.Inc("hit")
+	var response CompileResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return CompileResponse{}, false
+	}
+	response.Cached = true
+	return response, true
+}
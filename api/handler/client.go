@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustProxy makes clientID believe the X-Forwarded-For header, for a server
+// behind a reverse proxy. Without a proxy that sets it, clients could claim
+// to be anyone.
+var TrustProxy bool
+
+// clientID names the client a request came from, by its address. Behind a
+// proxy that is the last X-Forwarded-For entry, the one the proxy added;
+// the entries before it come from the client and may be made up.
+func clientID(r *http.Request) string {
+	if TrustProxy {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			forwarded := values[len(values)-1]
+			if i := strings.LastIndexByte(forwarded, ','); i >= 0 {
+				forwarded = forwarded[i+1:]
+			}
+			if last := strings.TrimSpace(forwarded); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientID(t *testing.T) {
+	tests := []struct {
+		trustProxy bool
+		forwarded  []string
+		expected   string
+	}{
+		{false, nil, "192.0.2.1"},
+		{false, []string{"203.0.113.7"}, "192.0.2.1"},
+		{true, nil, "192.0.2.1"},
+		{true, []string{"203.0.113.7"}, "203.0.113.7"},
+		// The client made up the first entry; the proxy appended the last.
+		{true, []string{"10.0.0.1, 203.0.113.7"}, "203.0.113.7"},
+		{true, []string{"10.0.0.1", "198.51.100.4,203.0.113.7"}, "203.0.113.7"},
+		{true, []string{" "}, "192.0.2.1"},
+	}
+
+	defer func(trust bool) { TrustProxy = trust }(TrustProxy)
+	for _, tt := range tests {
+		TrustProxy = tt.trustProxy
+		r := httptest.NewRequest("POST", "/compile", nil)
+		for _, value := range tt.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if got := clientID(r); got != tt.expected {
+			t.Errorf("clientID with trust-proxy=%v and X-Forwarded-For %q - expected %q, got=%q", tt.trustProxy, tt.forwarded, tt.expected, got)
+		}
+	}
+}
@@ -1,31 +1,59 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/ankush-web-eng/brolang/evaluator"
-	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/diagnostic"
 	"github.com/ankush-web-eng/brolang/object"
-	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/queue"
+	"github.com/ankush-web-eng/brolang/runner"
+	"github.com/ankush-web-eng/brolang/sandbox"
+	"github.com/ankush-web-eng/brolang/trace"
 )
 
-var GlobalEnv *object.Environment
+// Sandbox, when set, runs every compile request in a worker process instead
+// of inside the server.
+var Sandbox *sandbox.Pool
 
-// SetGlobalEnvironment sets the global environment.
-func SetGlobalEnvironment(env *object.Environment) {
-	GlobalEnv = env
+// SetSandbox makes CompilerHandler run programs on pool.
+func SetSandbox(pool *sandbox.Pool) {
+	Sandbox = pool
+}
+
+// Queue, when set, bounds how many compile requests run at once and how many
+// may wait, taking turns between clients.
+var Queue *queue.Queue
+
+// SetQueue makes CompilerHandler wait for a slot on q before running.
+func SetQueue(q *queue.Queue) {
+	Queue = q
 }
 
 type CompileRequest struct {
-	Code string `json:"code"`
+	Code             string `json:"code"`
+	Stdin            string `json:"stdin,omitempty"`            // the lines suna_bhai reads
+	TypeCheck        bool   `json:"typeCheck,omitempty"`        // run the static type checker before evaluating
+	DisableOptimizer bool   `json:"disableOptimizer,omitempty"` // skip constant folding and dead branch pruning
+	Profile          bool   `json:"profile,omitempty"`          // report hits and time per line
+	LegacyScoping    bool   `json:"legacyScoping,omitempty"`    // agar bodies share the outer scope, assignment can declare
 }
 
 type CompileResponse struct {
-	Result string `json:"result"`
-	Error  string `json:"error,omitempty"`
+	Result      string                  `json:"result"`
+	Error       string                  `json:"error,omitempty"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics,omitempty"`
+	Profile     []trace.LineProfile     `json:"profile,omitempty"`
+	Stack       []object.Frame          `json:"stack,omitempty"`       // where a runtime error happened, innermost first
+	Crash       *sandbox.CrashError     `json:"crash,omitempty"`       // set when the program took its sandbox worker down
+	QueueWait   int64                   `json:"queueWaitNs,omitempty"` // nanoseconds spent waiting for a free slot
+	Cached      bool                    `json:"cached,omitempty"`      // the result of an earlier run of the same program and input
 }
 
 func CompilerHandler(w http.ResponseWriter, r *http.Request) {
@@ -35,8 +63,7 @@ func CompilerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CompileRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decode(w, r, &req) {
 		return
 	}
 
@@ -48,191 +75,127 @@ func CompilerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Break the code into small parts and parse it
-	l := lexer.New(req.Code)
-	p := parser.New(l)
-	program := p.ParseProgram()
-
-	// if there are any errors while parsing the code, return the error
-	var customErrors strings.Builder
-	if len(p.Errors()) > 0 {
-		fmt.Printf("Parser has %v error:\n", p.Errors())
-		for _, value := range p.Errors() {
-			customErrors.WriteString(value)
-			customErrors.WriteString(" ")
-		}
-		response := CompileResponse{
-			Error: customErrors.String(),
-		}
+	// The same program on the same input has the same result
+	if response, ok := cached(req); ok {
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	// Initialize a global environment to hanydle variables
-	env := object.NewEnvironment()
+	// Wait for our turn, unless there is no queue
+	ticket, ok := enqueue(w, r)
+	if !ok {
+		return
+	}
+	release, waited, err := wait(r.Context(), ticket)
+	if err != nil {
+		return // the client went away while waiting
+	}
+	defer release()
 
-	// Get the evaluated code and return to the client
-	result := evaluator.Eval(program, env)
+	// Parse, check and evaluate the code, then return the output to the client
+	response, err := compile(r.Context(), req)
+	if err != nil {
+		http.Error(w, errNotRun, http.StatusServiceUnavailable)
+		return
+	}
+	response.QueueWait = waited
 
-	// env.OutputBuilder.WriteString(result.Inspect())
+	json.NewEncoder(w).Encode(response)
+}
 
-	response := CompileResponse{
-		Result: env.OutputBuilder.String(), // Use accumulated output
+// errNotRun is the error for a program that could not be run at all.
+const errNotRun = "Program chala hi nahi bhai, thodi der me try kar"
+
+// enqueue takes a place in the queue for a request, answering 429 itself if
+// there is no room, or 503 if the server is shutting down. The ticket is nil
+// if there is no queue.
+func enqueue(w http.ResponseWriter, r *http.Request) (*queue.Ticket, bool) {
+	if draining.Load() {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(CompileResponse{Error: errDraining})
+		return nil, false
+	}
+	if Queue == nil {
+		return nil, true
 	}
+	ticket, err := Queue.Enqueue(clientID(r))
+	if err != nil {
+		var full *queue.FullError
+		errors.As(err, &full)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(full.RetryAfter.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(CompileResponse{Error: "Line me lag ja bhai, bahut log pehle se hain!!"})
+		return nil, false
+	}
+	return ticket, true
+}
 
-	if result.Type() == object.ERROR_OBJ {
-		response.Error = result.Inspect()
+// wait waits for a ticket's turn, returning the nanoseconds it waited.
+func wait(ctx context.Context, ticket *queue.Ticket) (release func(), waited int64, err error) {
+	if ticket == nil {
+		return func() {}, 0, nil
 	}
+	release, d, err := ticket.Wait(ctx)
+	return release, d.Nanoseconds(), err
+}
 
-	json.NewEncoder(w).Encode(response)
+// compile runs a request and builds the response, keeping it in the cache
+// when another run would give the same one, which a stopped run would not.
+// A program that crashed its sandbox worker gets a response; only a program
+// that could not be run at all gets an error.
+func compile(ctx context.Context, req CompileRequest) (CompileResponse, error) {
+	res, err := run(ctx, req)
+	var crash *sandbox.CrashError
+	if errors.As(err, &crash) {
+		return CompileResponse{Error: crash.Error(), Crash: crash}, nil
+	} else if err != nil {
+		return CompileResponse{}, err
+	}
+	response := CompileResponse{
+		Result:      res.Output,
+		Error:       res.Error,
+		Diagnostics: res.Diagnostics,
+		Profile:     res.Profile,
+		Stack:       res.Stack,
+	}
+	if Cache != nil && !req.Profile && ctx.Err() == nil {
+		if data, err := json.Marshal(response); err == nil {
+			Cache.Set(cacheKey(req), data)
+		}
+	}
+	return response, nil
+}
+
+// run runs a compile request in the sandbox if there is one, or in this
+// process otherwise, and records how it went.
+func run(ctx context.Context, req CompileRequest) (*runner.Result, error) {
+	start := time.Now()
+	res, err := evaluate(ctx, req)
+	observe(ctx, res, err, time.Since(start))
+	return res, err
 }
 
-// package handler
-
-// import (
-// 	"context"
-// 	"encoding/json"
-// 	"log"
-// 	"net/http"
-// 	"strings"
-
-// 	"github.com/ankush-web-eng/brolang/evaluator"
-// 	"github.com/ankush-web-eng/brolang/lexer"
-// 	"github.com/ankush-web-eng/brolang/object"
-// 	"github.com/ankush-web-eng/brolang/parser"
-// 	"github.com/go-redis/redis/v8"
-// )
-
-// var GlobalEnv *object.Environment
-// var redisClient *redis.Client
-// var ctx = context.Background()
-
-// // SetGlobalEnvironment initializes the global environment for evaluation
-// func SetGlobalEnvironment(env *object.Environment) {
-// 	GlobalEnv = env
-// }
-
-// // InitializeRedisClient sets up the Redis client connection
-// func InitializeRedisClient() {
-// 	redisClient = redis.NewClient(&redis.Options{
-// 		Addr: "localhost:6379",
-// 	})
-// 	if err := redisClient.Ping(ctx).Err(); err != nil {
-// 		log.Fatalf("Failed to connect to Redis: %v", err)
-// 	}
-// }
-
-// type CompileRequest struct {
-// 	Code      string `json:"code"`
-// 	RequestID string `json:"requestId"`
-// }
-
-// type CompileResponse struct {
-// 	Result string `json:"result,omitempty"`
-// 	Error  string `json:"error,omitempty"`
-// }
-
-// type HTTPError struct {
-// 	Status  int
-// 	Message string
-// }
-
-// func (e *HTTPError) Error() string {
-// 	return e.Message
-// }
-
-// func validateRequest(r *http.Request) (*CompileRequest, error) {
-// 	if r.Method != http.MethodPost {
-// 		return nil, &HTTPError{
-// 			Status:  http.StatusMethodNotAllowed,
-// 			Message: "Method not allowed",
-// 		}
-// 	}
-
-// 	var req CompileRequest
-// 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-// 		return nil, &HTTPError{
-// 			Status:  http.StatusBadRequest,
-// 			Message: "Invalid request body",
-// 		}
-// 	}
-
-// 	if req.Code == "" {
-// 		return nil, &HTTPError{
-// 			Status:  http.StatusBadRequest,
-// 			Message: "Kuchh likh to sahi be!",
-// 		}
-// 	}
-
-// 	if req.RequestID == "" {
-// 		return nil, &HTTPError{
-// 			Status:  http.StatusBadRequest,
-// 			Message: "Missing requestId",
-// 		}
-// 	}
-
-// 	return &req, nil
-// }
-
-// // compileCode compiles the given code and publishes the result to Redis
-// func compileCode(code, requestId string, env *object.Environment) {
-// 	l := lexer.New(code)
-// 	p := parser.New(l)
-// 	program := p.ParseProgram()
-
-// 	if len(p.Errors()) > 0 {
-// 		var errorMsg strings.Builder
-// 		for _, msg := range p.Errors() {
-// 			errorMsg.WriteString(msg + " ")
-// 		}
-
-// 		// Publish error to Redis channel identified by requestId
-// 		publishToRedis(requestId, CompileResponse{Error: errorMsg.String()})
-// 		return
-// 	}
-
-// 	result := evaluator.Eval(program, env)
-// 	if result != nil && result.Type() == object.ERROR_OBJ {
-// 		// Publish runtime error to Redis
-// 		publishToRedis(requestId, CompileResponse{Error: result.Inspect()})
-// 		return
-// 	}
-
-// 	// Publish successful result to Redis
-// 	publishToRedis(requestId, CompileResponse{Result: env.OutputBuilder.String()})
-// }
-
-// // publishToRedis sends the response to the Redis Pub/Sub channel
-// func publishToRedis(channel string, response CompileResponse) {
-// 	data, err := json.Marshal(response)
-// 	if err != nil {
-// 		log.Printf("Failed to marshal response: %v", err)
-// 		return
-// 	}
-
-// 	if err := redisClient.Publish(ctx, channel, data).Err(); err != nil {
-// 		log.Printf("Failed to publish to Redis: %v", err)
-// 	}
-// }
-
-// // CompilerHandler is the HTTP handler for the compile endpoint
-// func CompilerHandler(w http.ResponseWriter, r *http.Request) {
-// 	w.Header().Set("Content-Type", "application/json")
-
-// 	req, err := validateRequest(r)
-// 	if err != nil {
-// 		if httpErr, ok := err.(*HTTPError); ok {
-// 			http.Error(w, httpErr.Message, httpErr.Status)
-// 			return
-// 		}
-// 		http.Error(w, "Internal server error", http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	// Call compileCode in a separate goroutine to allow asynchronous processing
-// 	go compileCode(req.Code, req.RequestID, GlobalEnv)
-
-// 	// Respond immediately to the HTTP request indicating successful submission
-// 	w.WriteHeader(http.StatusAccepted)
-// 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "Code submitted successfully"})
-// }
+func evaluate(ctx context.Context, req CompileRequest) (*runner.Result, error) {
+	if Sandbox != nil {
+		return Sandbox.Run(ctx, sandbox.Job{
+			Code:             req.Code,
+			Stdin:            req.Stdin,
+			TypeCheck:        req.TypeCheck,
+			DisableOptimizer: req.DisableOptimizer,
+			Profile:          req.Profile,
+			LegacyScoping:    req.LegacyScoping,
+		})
+	}
+	opts := runner.Options{
+		Context:          ctx,
+		TypeCheck:        req.TypeCheck,
+		DisableOptimizer: req.DisableOptimizer,
+		Profile:          req.Profile,
+		LegacyScoping:    req.LegacyScoping,
+	}
+	if req.Stdin != "" {
+		opts.Stdin = strings.NewReader(req.Stdin)
+	}
+	return runner.Run(req.Code, opts), nil
+}
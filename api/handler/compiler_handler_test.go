@@ -2,9 +2,14 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/ankush-web-eng/brolang/cache"
+	"github.com/ankush-web-eng/brolang/queue"
 )
 
 func TestCompilerHandler(t *testing.T) {
@@ -44,3 +49,117 @@ func TestCompilerHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestCompilerHandlerTypeCheck(t *testing.T) {
+	req := CompileRequest{
+		Code: `bhai_sun x: int = "five";
+            bol_bhai(x);`,
+		TypeCheck: true,
+	}
+	reqBody, _ := json.Marshal(req)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/compile", bytes.NewBuffer(reqBody))
+	CompilerHandler(w, r)
+
+	var resp CompileResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if resp.Result != "" {
+		t.Errorf("program should not run, got output %q", resp.Result)
+	}
+	if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Code != "type-mismatch" {
+		t.Errorf("expected one type-mismatch diagnostic, got=%v", resp.Diagnostics)
+	}
+}
+
+func TestCompilerHandlerStack(t *testing.T) {
+	req := CompileRequest{Code: "chal_bhai (bhai_sun i = 0; i < 2; i = i + 1) {\n    bol_bhai(x);\n}"}
+	reqBody, _ := json.Marshal(req)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/compile", bytes.NewBuffer(reqBody))
+	CompilerHandler(w, r)
+
+	var resp struct {
+		Error string
+		Stack []map[string]interface{}
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	if resp.Error == "" || len(resp.Stack) != 2 {
+		t.Fatalf("expected an error with two frames, got=%+v", resp)
+	}
+	if resp.Stack[0]["kind"] != "bol_bhai" || resp.Stack[0]["line"] != 2.0 || resp.Stack[0]["column"] != 5.0 {
+		t.Errorf("wrong first frame. got=%v", resp.Stack[0])
+	}
+	if resp.Stack[1]["kind"] != "chal_bhai" || resp.Stack[1]["iteration"] != 1.0 {
+		t.Errorf("wrong loop frame. got=%v", resp.Stack[1])
+	}
+}
+
+func TestCompilerHandlerQueueFull(t *testing.T) {
+	q := queue.New(queue.Config{Concurrency: 1})
+	SetQueue(q)
+	defer SetQueue(nil)
+
+	release, _, err := q.Acquire(context.Background(), "someone else")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqBody, _ := json.Marshal(CompileRequest{Code: "bol_bhai(1);"})
+
+	w := httptest.NewRecorder()
+	CompilerHandler(w, httptest.NewRequest("POST", "/compile", bytes.NewBuffer(reqBody)))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got=%d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got=%q", got)
+	}
+
+	release()
+	w = httptest.NewRecorder()
+	CompilerHandler(w, httptest.NewRequest("POST", "/compile", bytes.NewBuffer(reqBody)))
+	var resp CompileResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp.Result != "1\n" {
+		t.Errorf("expected the program to run once the slot was free, got status %d and %+v", w.Code, resp)
+	}
+}
+
+func TestCompilerHandlerCache(t *testing.T) {
+	SetCache(cache.NewLRU(cache.Config{MaxEntries: 10}))
+	defer SetCache(nil)
+
+	compileOnce := func(req CompileRequest) CompileResponse {
+		reqBody, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		CompilerHandler(w, httptest.NewRequest("POST", "/compile", bytes.NewBuffer(reqBody)))
+		var resp CompileResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	code := "bhai_sun n = suna_bhai();\nbol_bhai(n * 2);"
+	tests := []struct {
+		req            CompileRequest
+		expectedResult string
+		expectedCached bool
+	}{
+		{CompileRequest{Code: code, Stdin: "4\n"}, "8\n", false},
+		{CompileRequest{Code: code, Stdin: "4\n"}, "8\n", true},
+		{CompileRequest{Code: code, Stdin: "5\n"}, "10\n", false},
+		{CompileRequest{Code: code, Stdin: "4\n", TypeCheck: true}, "8\n", false},
+		{CompileRequest{Code: code, Stdin: "4\n", Profile: true}, "8\n", false},
+		{CompileRequest{Code: code, Stdin: "4\n", Profile: true}, "8\n", false},
+	}
+
+	for i, tt := range tests {
+		resp := compileOnce(tt.req)
+		if resp.Result != tt.expectedResult || resp.Cached != tt.expectedCached {
+			t.Errorf("test[%d] - expected result %q with cached=%v, got=%q with cached=%v",
+				i, tt.expectedResult, tt.expectedCached, resp.Result, resp.Cached)
+		}
+	}
+}
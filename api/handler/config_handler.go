@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ankush-web-eng/brolang/config"
+)
+
+// Settings is the configuration the server was started with.
+var Settings *config.Config
+
+// SetConfig records the configuration shown by ConfigHandler.
+func SetConfig(cfg *config.Config) {
+	Settings = cfg
+}
+
+// ConfigHandler shows the server's settings by name, with secrets hidden, to
+// help work out how a server was configured.
+func ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if Settings == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Settings.Settings())
+}
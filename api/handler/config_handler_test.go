@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/config"
+)
+
+func TestConfigHandler(t *testing.T) {
+	cfg := config.Default()
+	cfg.RedisPassword = "bahut-secret"
+	SetConfig(cfg)
+	defer SetConfig(nil)
+
+	w := httptest.NewRecorder()
+	ConfigHandler(w, httptest.NewRequest("GET", "/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got=%d", http.StatusOK, w.Code)
+	}
+	var settings map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings["addr"] != ":8080" {
+		t.Errorf("expected addr :8080, got=%q", settings["addr"])
+	}
+	if settings["redis-password"] != "********" {
+		t.Errorf("expected the Redis password hidden, got=%q", settings["redis-password"])
+	}
+
+	w = httptest.NewRecorder()
+	ConfigHandler(w, httptest.NewRequest("POST", "/config", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got=%d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
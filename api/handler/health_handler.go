@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// The server is ready until Drain is called. From then on it turns new
+// programs away while the ones it took finish, until Stop cancels them.
+var (
+	draining atomic.Bool
+	jobs     atomic.Int64 // background jobs not yet done
+
+	stopCtx, stop = context.WithCancel(context.Background())
+)
+
+// BaseContext is the context every request starts from, for
+// http.Server.BaseContext, so that Stop reaches every running program.
+func BaseContext(net.Listener) context.Context {
+	return stopCtx
+}
+
+// Drain makes the server unready and turns away new programs.
+func Drain() {
+	draining.Store(true)
+}
+
+// WaitJobs waits until every background job is done, or ctx ends.
+func WaitJobs(ctx context.Context) error {
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for jobs.Load() > 0 {
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Stop cancels every running program, in requests and background jobs.
+func Stop() {
+	stop()
+}
+
+// errDraining is the error for a program sent while the server shuts down.
+const errDraining = "Server band ho raha h bhai, thodi der me try kar"
+
+// HealthHandler answers as long as the server is up.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// ReadyHandler says whether the server takes programs, so a load balancer
+// stops sending it any once it starts shutting down.
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadyHandler(t *testing.T) {
+	status := func(h http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(method, "/", strings.NewReader(body)))
+		return w
+	}
+
+	if w := status(ReadyHandler, "GET", ""); w.Code != http.StatusOK {
+		t.Errorf("expected a ready server, got status %d", w.Code)
+	}
+
+	Drain()
+	defer draining.Store(false)
+	if w := status(ReadyHandler, "GET", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a draining server to be unready, got status %d", w.Code)
+	}
+	if w := status(HealthHandler, "GET", ""); w.Code != http.StatusOK {
+		t.Errorf("expected a draining server to be healthy, got status %d", w.Code)
+	}
+	w := status(CompilerHandler, "POST", `{"code": "bol_bhai(1);"}`)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After while draining, got status %d", w.Code)
+	}
+	if w := status(SubmitJobHandler, "POST", `{"code": "bol_bhai(1);"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected jobs turned away while draining, got status %d", w.Code)
+	}
+}
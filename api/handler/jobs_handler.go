@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/pubsub"
+	"github.com/ankush-web-eng/brolang/queue"
+)
+
+// Broker carries the status of background jobs to whoever asks for it.
+var Broker pubsub.Broker
+
+// SetBroker sets the broker the job handlers publish to.
+func SetBroker(b pubsub.Broker) {
+	Broker = b
+}
+
+// JobRequest is a compile request to run in the background.
+type JobRequest struct {
+	CompileRequest
+	RequestID string `json:"requestId,omitempty"` // chosen by the client, or made up if empty
+}
+
+// The states of a job.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+)
+
+// JobStatus is published on a job's channel every time its state changes.
+type JobStatus struct {
+	RequestID string           `json:"requestId"`
+	Status    string           `json:"status"`
+	Result    *CompileResponse `json:"result,omitempty"` // set once the job is done
+}
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// jobChannel is the broker channel of a job.
+func jobChannel(id string) string {
+	return "brolang:job:" + id
+}
+
+// SubmitJobHandler queues a program to run in the background and answers at
+// once with its request ID. The result is published on the broker.
+func SubmitJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req JobRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Code == "" {
+		http.Error(w, "Kuchh likh to sahi be!", http.StatusBadRequest)
+		return
+	}
+	if req.RequestID == "" {
+		req.RequestID = newRequestID()
+	} else if !requestIDPattern.MatchString(req.RequestID) {
+		http.Error(w, "requestId me sirf letters, digits, - aur _ chalenge, 64 tak", http.StatusBadRequest)
+		return
+	} else if _, exists, _ := Broker.Last(r.Context(), jobChannel(req.RequestID)); exists {
+		http.Error(w, "Ye requestId pehle se chal rahi h bhai", http.StatusConflict)
+		return
+	}
+
+	// A cached job is done as soon as it is submitted.
+	status := JobStatus{RequestID: req.RequestID, Status: JobQueued}
+	var ticket *queue.Ticket
+	if response, ok := cached(req.CompileRequest); ok {
+		status.Status, status.Result = JobDone, &response
+	} else if ticket, ok = enqueue(w, r); !ok {
+		return
+	}
+	if err := publish(r.Context(), status); err != nil {
+		if ticket != nil {
+			ticket.Cancel()
+		}
+		logger(r.Context()).Error("publishing job", "job", req.RequestID, "error", err)
+		http.Error(w, errNotRun, http.StatusServiceUnavailable)
+		return
+	}
+	if status.Status == JobQueued {
+		// The job outlives the request, but not Stop.
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		unhook := context.AfterFunc(stopCtx, cancel)
+		jobs.Add(1)
+		go func() {
+			defer jobs.Add(-1)
+			defer unhook()
+			defer cancel()
+			runJob(ctx, req, ticket)
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// runJob waits for a job's turn, runs it and publishes the result. The job
+// outlives the request that submitted it, so ctx must not end with it.
+func runJob(ctx context.Context, req JobRequest, ticket *queue.Ticket) {
+	var response CompileResponse
+	release, waited, err := wait(ctx, ticket)
+	if err != nil {
+		response = CompileResponse{Error: errDraining}
+	} else {
+		defer release()
+		publish(ctx, JobStatus{RequestID: req.RequestID, Status: JobRunning})
+		if response, err = compile(ctx, req.CompileRequest); err != nil {
+			response = CompileResponse{Error: errNotRun}
+		}
+	}
+	response.QueueWait = waited
+
+	// Publish even if ctx was cancelled, so the job does not stay running.
+	ctx = context.WithoutCancel(ctx)
+	if err := publish(ctx, JobStatus{RequestID: req.RequestID, Status: JobDone, Result: &response}); err != nil {
+		logger(ctx).Error("publishing job", "job", req.RequestID, "error", err)
+	}
+}
+
+// JobHandler reports the latest status of the job named in the path, for
+// clients that poll.
+func JobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msg, ok, err := Broker.Last(r.Context(), jobChannel(r.PathValue("id")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if !ok {
+		http.Error(w, "Aisa koi job hi nahi h bhai", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(msg)
+}
+
+// JobEventsHandler streams the status of the job named in the path as
+// server-sent events, from its latest status until it is done.
+func JobEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel := jobChannel(r.PathValue("id"))
+	sub, err := Broker.Subscribe(r.Context(), channel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+	msg, ok, err := Broker.Last(r.Context(), channel)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if !ok {
+		http.Error(w, "Aisa koi job hi nahi h bhai", http.StatusNotFound)
+		return
+	}
+
+	// A job may take longer than the server's write timeout.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	for {
+		fmt.Fprintf(w, "data: %s\n\n", msg)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		var status JobStatus
+		if json.Unmarshal(msg, &status); status.Status == JobDone {
+			return
+		}
+
+		select {
+		case msg, ok = <-sub.Messages():
+			if !ok {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func publish(ctx context.Context, status JobStatus) error {
+	msg, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return Broker.Publish(ctx, jobChannel(status.RequestID), msg)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/pubsub"
+)
+
+func newJobServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	SetBroker(pubsub.NewMemory(time.Minute))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", SubmitJobHandler)
+	mux.HandleFunc("/jobs/{id}", JobHandler)
+	mux.HandleFunc("/jobs/{id}/events", JobEventsHandler)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		srv.Close()
+		Broker.Close()
+		SetBroker(nil)
+	})
+	return srv
+}
+
+func submitJob(t *testing.T, srv *httptest.Server, req JobRequest) (*http.Response, JobStatus) {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(srv.URL+"/jobs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status JobStatus
+	json.NewDecoder(resp.Body).Decode(&status)
+	return resp, status
+}
+
+func TestJobPoll(t *testing.T) {
+	srv := newJobServer(t)
+
+	resp, status := submitJob(t, srv, JobRequest{CompileRequest: CompileRequest{Code: "bol_bhai(6 * 7);"}})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status %d, got=%d", http.StatusAccepted, resp.StatusCode)
+	}
+	if status.Status != JobQueued || len(status.RequestID) != 32 {
+		t.Fatalf("expected a queued job with a generated ID, got=%+v", status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := http.Get(srv.URL + "/jobs/" + status.RequestID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var polled JobStatus
+		json.NewDecoder(resp.Body).Decode(&polled)
+		resp.Body.Close()
+		if polled.Status == JobDone {
+			if polled.Result == nil || polled.Result.Result != "42\n" {
+				t.Errorf("expected output %q, got=%+v", "42\n", polled.Result)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job not done in time, last status %+v", polled)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	resp, err := http.Get(srv.URL + "/jobs/nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown job - expected status %d, got=%d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	srv := newJobServer(t)
+
+	_, status := submitJob(t, srv, JobRequest{
+		CompileRequest: CompileRequest{Code: "bol_bhai(x);"},
+		RequestID:      "class-7b_42",
+	})
+	if status.RequestID != "class-7b_42" {
+		t.Fatalf("expected the client's request ID, got=%q", status.RequestID)
+	}
+
+	resp, err := http.Get(srv.URL + "/jobs/class-7b_42/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected an event stream, got=%q", ct)
+	}
+
+	// The stream ends once the job is done.
+	var last JobStatus
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			json.Unmarshal([]byte(data), &last)
+		}
+	}
+	if last.Status != JobDone || last.Result == nil || !strings.Contains(last.Result.Error, "x kaha likha h") {
+		t.Errorf("expected the job to end with an undefined variable error, got=%+v", last)
+	}
+}
+
+func TestSubmitJobErrors(t *testing.T) {
+	srv := newJobServer(t)
+	submitJob(t, srv, JobRequest{CompileRequest: CompileRequest{Code: "bol_bhai(1);"}, RequestID: "taken"})
+
+	tests := []struct {
+		req      JobRequest
+		expected int
+	}{
+		{JobRequest{}, http.StatusBadRequest},
+		{JobRequest{CompileRequest: CompileRequest{Code: "bol_bhai(1);"}, RequestID: "no spaces"}, http.StatusBadRequest},
+		{JobRequest{CompileRequest: CompileRequest{Code: "bol_bhai(1);"}, RequestID: "taken"}, http.StatusConflict},
+	}
+
+	for i, tt := range tests {
+		if resp, _ := submitJob(t, srv, tt.req); resp.StatusCode != tt.expected {
+			t.Errorf("test[%d] - expected status %d, got=%d", i, tt.expected, resp.StatusCode)
+		}
+	}
+}
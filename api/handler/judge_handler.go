@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/judge"
+	"github.com/ankush-web-eng/brolang/runner"
+)
+
+// maxJudgeCases bounds the test cases of one judge request.
+const maxJudgeCases = 100
+
+// JudgeTimeout bounds the time all the cases of one judge request take
+// together, as they share one turn in the queue; 0 means no limit.
+var JudgeTimeout time.Duration
+
+type JudgeRequest struct {
+	Code             string       `json:"code"`
+	Cases            []judge.Case `json:"cases"`
+	TypeCheck        bool         `json:"typeCheck,omitempty"`
+	DisableOptimizer bool         `json:"disableOptimizer,omitempty"`
+	LegacyScoping    bool         `json:"legacyScoping,omitempty"`
+}
+
+// JudgeHandler runs a program against test cases, each on its own, and
+// reports a verdict per case and an overall score. The cases take one turn
+// in the queue together.
+func JudgeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req JudgeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Code == "" {
+		http.Error(w, "Kuchh likh to sahi be!", http.StatusBadRequest)
+		return
+	}
+	if len(req.Cases) == 0 || len(req.Cases) > maxJudgeCases {
+		http.Error(w, fmt.Sprintf("Test case 1 se %d tak de bhai", maxJudgeCases), http.StatusBadRequest)
+		return
+	}
+
+	ticket, ok := enqueue(w, r)
+	if !ok {
+		return
+	}
+	release, _, err := wait(r.Context(), ticket)
+	if err != nil {
+		return // the client went away while waiting
+	}
+	defer release()
+
+	ctx := r.Context()
+	if JudgeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, JudgeTimeout)
+		defer cancel()
+	}
+	report, err := judge.Run(ctx, req.Code, req.Cases, func(ctx context.Context, code, stdin string) (*runner.Result, error) {
+		return run(ctx, CompileRequest{
+			Code:             code,
+			Stdin:            stdin,
+			TypeCheck:        req.TypeCheck,
+			DisableOptimizer: req.DisableOptimizer,
+			LegacyScoping:    req.LegacyScoping,
+		})
+	})
+	if err != nil {
+		http.Error(w, errNotRun, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/judge"
+)
+
+func TestJudgeHandler(t *testing.T) {
+	req := JudgeRequest{
+		Code: "bhai_sun n = suna_bhai();\nbol_bhai(n + 1);",
+		Cases: []judge.Case{
+			{Stdin: "1\n", Expected: "2\n"},
+			{Stdin: "5\n", Expected: "7\n"},
+		},
+	}
+	reqBody, _ := json.Marshal(req)
+
+	w := httptest.NewRecorder()
+	JudgeHandler(w, httptest.NewRequest("POST", "/judge", bytes.NewBuffer(reqBody)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got=%d: %s", http.StatusOK, w.Code, w.Body)
+	}
+
+	var report judge.Report
+	json.NewDecoder(w.Body).Decode(&report)
+	if len(report.Cases) != 2 || report.Cases[0].Verdict != judge.Accepted || report.Cases[1].Verdict != judge.WrongAnswer {
+		t.Errorf("expected accepted then wrong-answer, got=%+v", report.Cases)
+	}
+	if report.Score != 50 {
+		t.Errorf("expected a score of 50, got=%v", report.Score)
+	}
+
+	// All the cases share JudgeTimeout.
+	JudgeTimeout = 100 * time.Millisecond
+	defer func() { JudgeTimeout = 0 }()
+	spin := "chal_bhai (bhai_sun i me 0..9000) { chal_bhai (bhai_sun j me 0..9000) { } }"
+	reqBody, _ = json.Marshal(JudgeRequest{Code: spin, Cases: []judge.Case{{}, {}, {}}})
+	start := time.Now()
+	w = httptest.NewRecorder()
+	JudgeHandler(w, httptest.NewRequest("POST", "/judge", bytes.NewBuffer(reqBody)))
+	report = judge.Report{}
+	json.NewDecoder(w.Body).Decode(&report)
+	if len(report.Cases) != 3 || report.Cases[0].Verdict != judge.TimeLimit || report.Cases[2].Verdict != judge.TimeLimit {
+		t.Errorf("expected every case to exceed the time limit, got=%+v", report.Cases)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the request to stop at JudgeTimeout, took %v", elapsed)
+	}
+
+	w = httptest.NewRecorder()
+	reqBody, _ = json.Marshal(JudgeRequest{Code: req.Code})
+	JudgeHandler(w, httptest.NewRequest("POST", "/judge", bytes.NewBuffer(reqBody)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("no cases - expected status %d, got=%d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/ratelimit"
+)
+
+// APIKeys are the keys a client may send in X-API-Key to be rate limited by
+// key rather than by address, such as a classroom sharing one address.
+var APIKeys map[string]bool
+
+// SetAPIKeys sets the accepted API keys.
+func SetAPIKeys(keys []string) {
+	APIKeys = map[string]bool{}
+	for _, key := range keys {
+		APIKeys[key] = true
+	}
+}
+
+var rateLimited = Metrics.Counter("brolang_rate_limited_total",
+	"Requests turned away for going over their rate limit, by route.", "route")
+
+// RateLimit lets through requests to route only as fast as limiter allows
+// each client, answering 429 for the rest. Every answer carries
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers. A request
+// with an unknown API key is charged to its address before it is refused, so
+// keys cannot be guessed faster than the address may send requests.
+func RateLimit(route string, limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := "addr:" + clientID(r)
+		apiKey := r.Header.Get("X-API-Key")
+		if APIKeys[apiKey] {
+			key = "key:" + apiKey
+		}
+
+		d := limiter.Allow(key)
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(d.Reset))
+		if !d.Allowed {
+			rateLimited.Inc(route)
+			w.Header().Set("Retry-After", ceilSeconds(d.RetryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(CompileResponse{Error: "Saans to le le bhai, bahut requests bhej di!!"})
+			return
+		}
+		if apiKey != "" && !APIKeys[apiKey] {
+			http.Error(w, "Ye API key kahan se laya bhai?", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// LimitBody stops handlers from reading more than max bytes of a request
+// body. A body that says it is bigger is turned away before it is read.
+func LimitBody(max int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > max {
+			http.Error(w, errTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next(w, r)
+	}
+}
+
+const errTooLarge = "Itna bada request kaun bhejta h bhai?"
+
+// decode reads the JSON request body into v, answering 413 for a body over
+// its limit and 400 for anything else wrong with it.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		http.Error(w, errTooLarge, http.StatusRequestEntityTooLarge)
+		return false
+	case err != nil:
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
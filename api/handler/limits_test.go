@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	SetAPIKeys([]string{"class-10b"})
+	defer SetAPIKeys(nil)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	h := RateLimit("/compile", ratelimit.New(ratelimit.Limit{Events: 1, Per: time.Minute, Burst: 2}), ok)
+	addr := "192.0.2.1:1234"
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/compile", nil)
+		r.RemoteAddr = addr
+		if apiKey != "" {
+			r.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	limited := rateLimited.Value("/compile")
+	for i, remaining := range []string{"1", "0"} {
+		w := send("")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != remaining || w.Header().Get("RateLimit-Limit") != "2" {
+			t.Errorf("request %d - expected 200 with %s remaining, got status %d and headers %v", i, remaining, w.Code, w.Header())
+		}
+	}
+	w := send("")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" || w.Header().Get("RateLimit-Reset") != "120" {
+		t.Errorf("expected 429 with Retry-After 60, got status %d and headers %v", w.Code, w.Header())
+	}
+	if rateLimited.Value("/compile")-limited != 1 {
+		t.Error("expected the turned away request to be counted")
+	}
+
+	if w := send("class-10b"); w.Code != http.StatusOK {
+		t.Errorf("expected an API key to have its own bucket, got status %d", w.Code)
+	}
+	if w := send("nakli"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected an unknown API key to be charged to the address, got status %d", w.Code)
+	}
+
+	addr = "192.0.2.2:1234"
+	for i, expected := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if w := send("nakli"); w.Code != expected {
+			t.Errorf("guess %d - expected status %d, got %d", i, expected, w.Code)
+		}
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	h := LimitBody(64, CompilerHandler)
+	body := `{"code": "bol_bhai(` + strings.Repeat("1", 100) + `);"}`
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("POST", "/compile", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a declared length over the limit, got status %d", w.Code)
+	}
+
+	// Without a length the body is cut off as it is read.
+	r := httptest.NewRequest("POST", "/compile", io.MultiReader(strings.NewReader(body)))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	h(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a body over the limit, got status %d", w.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/lint"
+)
+
+type LintRequest struct {
+	Code string `json:"code"`
+}
+
+type LintResponse struct {
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// LintHandler reports problems in a program without running it.
+func LintHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req LintRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	response := LintResponse{Diagnostics: lint.Source(req.Code)}
+	if response.Diagnostics == nil {
+		response.Diagnostics = []diagnostic.Diagnostic{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLintHandler(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedCodes []string
+	}{
+		{
+			`bhai_sun x = 5;
+            bol_bhai(x);`,
+			[]string{},
+		},
+		{
+			`bhai_sun x = 5;
+            bol_bhai(y);`,
+			[]string{"unused-variable", "undefined-identifier"},
+		},
+	}
+
+	for _, tt := range tests {
+		reqBody, _ := json.Marshal(LintRequest{Code: tt.input})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/lint", bytes.NewBuffer(reqBody))
+		r.Header.Set("Content-Type", "application/json")
+
+		LintHandler(w, r)
+
+		var resp LintResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+
+		if len(resp.Diagnostics) != len(tt.expectedCodes) {
+			t.Fatalf("expected %d diagnostics, got=%v", len(tt.expectedCodes), resp.Diagnostics)
+		}
+		for i, code := range tt.expectedCodes {
+			if resp.Diagnostics[i].Code != code {
+				t.Errorf("diagnostic %d: expected=%q, got=%q", i, code, resp.Diagnostics[i].Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+)
+
+type requestIDKey struct{}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID Instrument gave the request ctx belongs to, or ""
+// outside one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logger returns the default logger, tagged with the request ID of ctx if it
+// has one.
+func logger(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("requestId", id)
+	}
+	return slog.Default()
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/metrics"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/runner"
+	"github.com/ankush-web-eng/brolang/sandbox"
+)
+
+// Metrics holds everything MetricsHandler reports.
+var Metrics = metrics.NewRegistry()
+
+var (
+	httpRequests = Metrics.Counter("brolang_http_requests_total",
+		"HTTP requests served, by route, method and status code.", "route", "method", "code")
+	httpDuration = Metrics.Histogram("brolang_http_request_duration_seconds",
+		"How long HTTP requests took to answer, by route.", metrics.DefaultBuckets, "route")
+	evaluations = Metrics.Histogram("brolang_evaluation_duration_seconds",
+		"How long programs took to parse, check and run, waiting in the queue aside.", metrics.DefaultBuckets)
+	programErrors = Metrics.Counter("brolang_program_errors_total",
+		"Programs that failed, by kind (parse or runtime) and error code.", "kind", "code")
+	loopLimitHits = Metrics.Counter("brolang_loop_limit_hits_total",
+		"Programs stopped for running a loop too many times.")
+	sandboxCrashes = Metrics.Counter("brolang_sandbox_crashes_total",
+		"Programs that took their sandbox worker down, by reason.", "reason")
+	cacheLookups = Metrics.Counter("brolang_cache_lookups_total",
+		"Compile requests looked up in the cache, by result (hit or miss).", "result")
+)
+
+func init() {
+	Metrics.GaugeFunc("brolang_queue_running", "Programs running now.", func() float64 {
+		if Queue == nil {
+			return 0
+		}
+		running, _ := Queue.Len()
+		return float64(running)
+	})
+	Metrics.GaugeFunc("brolang_queue_waiting", "Programs waiting for their turn.", func() float64 {
+		if Queue == nil {
+			return 0
+		}
+		_, waiting := Queue.Len()
+		return float64(waiting)
+	})
+}
+
+// MetricsHandler serves the metrics in the Prometheus text format.
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	Metrics.ServeHTTP(w, r)
+}
+
+// Instrument wraps the handler of route so that every request gets a request
+// ID, is counted and timed, and is logged once it is answered.
+func Instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := withRequestID(r.Context(), id)
+
+		sw := &statusWriter{ResponseWriter: w}
+		next(sw, r.WithContext(ctx))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		elapsed := time.Since(start)
+		httpRequests.Inc(route, r.Method, strconv.Itoa(sw.status))
+		httpDuration.Observe(elapsed.Seconds(), route)
+		logger(ctx).Info("request", "method", r.Method, "path", r.URL.Path, "status", sw.status, "duration", elapsed, "client", clientID(r))
+	}
+}
+
+// observe records the metrics of one run of a program, and logs it.
+func observe(ctx context.Context, res *runner.Result, err error, elapsed time.Duration) {
+	evaluations.Observe(elapsed.Seconds())
+	log := logger(ctx)
+	var crash *sandbox.CrashError
+	if errors.As(err, &crash) {
+		sandboxCrashes.Inc(crash.Reason)
+		log.Warn("sandbox crash", "reason", crash.Reason, "detail", crash.Detail, "duration", elapsed)
+		return
+	} else if err != nil {
+		log.Error("running program", "error", err)
+		return
+	}
+
+	switch {
+	case diagnostic.HasErrors(res.Diagnostics):
+		for _, d := range res.Diagnostics {
+			if d.Severity == diagnostic.Error {
+				programErrors.Inc("parse", d.Code)
+				break
+			}
+		}
+	case res.Error != "":
+		code := res.ErrorCode
+		if code == "" {
+			code = object.CodeRuntime
+		}
+		programErrors.Inc("runtime", code)
+		if code == object.CodeLoopLimit {
+			loopLimitHits.Inc()
+		}
+	}
+	log.Debug("evaluated", "duration", elapsed, "errorCode", res.ErrorCode, "diagnostics", len(res.Diagnostics))
+}
+
+// statusWriter remembers the status code a handler answered with.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets server-sent events through.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/object"
+)
+
+func TestInstrument(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	compile := Instrument("/compile", CompilerHandler)
+	send := func(code, requestID string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(CompileRequest{Code: code})
+		r := httptest.NewRequest("POST", "/compile", bytes.NewReader(body))
+		if requestID != "" {
+			r.Header.Set("X-Request-ID", requestID)
+		}
+		w := httptest.NewRecorder()
+		compile(w, r)
+		return w
+	}
+
+	requests := httpRequests.Value("/compile", "POST", "200")
+	parseErrors := programErrors.Value("parse", "syntax")
+	loopLimits := loopLimitHits.Value()
+	runtimeErrors := programErrors.Value("runtime", object.CodeLoopLimit)
+
+	w := send("bol_bhai(1);", "pehli-request")
+	if id := w.Header().Get("X-Request-ID"); id != "pehli-request" {
+		t.Errorf("expected the client's request ID back, got=%q", id)
+	}
+	if w = send("bhai_sun = ;", "bad id!"); len(w.Header().Get("X-Request-ID")) != 32 {
+		t.Errorf("expected a new request ID for an invalid one, got=%q", w.Header().Get("X-Request-ID"))
+	}
+	send("chal_bhai (bhai_sun i me 0..20000) {\n}", "")
+
+	if got := httpRequests.Value("/compile", "POST", "200") - requests; got != 3 {
+		t.Errorf("expected 3 requests counted, got=%v", got)
+	}
+	if got := programErrors.Value("parse", "syntax") - parseErrors; got != 1 {
+		t.Errorf("expected 1 parse error counted, got=%v", got)
+	}
+	if loopLimitHits.Value()-loopLimits != 1 || programErrors.Value("runtime", object.CodeLoopLimit)-runtimeErrors != 1 {
+		t.Errorf("expected 1 loop limit hit counted")
+	}
+	if !strings.Contains(logs.String(), `"requestId":"pehli-request"`) {
+		t.Errorf("expected the request ID in the logs, got:\n%s", logs.String())
+	}
+
+	w = httptest.NewRecorder()
+	MetricsHandler(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, name := range []string{
+		`brolang_http_requests_total{route="/compile",method="POST",code="200"}`,
+		`brolang_http_request_duration_seconds_bucket{route="/compile",le="+Inf"}`,
+		"brolang_evaluation_duration_seconds_count",
+		"brolang_loop_limit_hits_total",
+		"brolang_queue_waiting 0",
+	} {
+		if !strings.Contains(w.Body.String(), name) {
+			t.Errorf("expected %s in the metrics, got:\n%s", name, w.Body.String())
+		}
+	}
+	if w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("wrong content type %q", w.Header().Get("Content-Type"))
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got=%d", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ankush-web-eng/brolang/snippet"
+)
+
+// Snippets keeps the programs shared through SaveSnippetHandler.
+var Snippets snippet.Store
+
+// SetSnippetStore sets where shared programs are kept.
+func SetSnippetStore(store snippet.Store) {
+	Snippets = store
+}
+
+// maxSnippetSize bounds the code and input of a shared program together.
+const maxSnippetSize = 64 << 10
+
+type SnippetRequest struct {
+	Code  string `json:"code"`
+	Stdin string `json:"stdin,omitempty"` // the lines suna_bhai reads
+}
+
+// SaveSnippetHandler stores a program and answers with its snippet, whose ID
+// can be shared as a link.
+func SaveSnippetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SnippetRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Code == "" {
+		http.Error(w, "Kuchh likh to sahi be!", http.StatusBadRequest)
+		return
+	}
+	if len(req.Code)+len(req.Stdin) > maxSnippetSize {
+		http.Error(w, "Itna lamba program kaun share karta h bhai?", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	s, err := snippet.Create(r.Context(), Snippets, req.Code, req.Stdin)
+	if err != nil {
+		logger(r.Context()).Error("saving snippet", "error", err)
+		http.Error(w, "Snippet save nahi hua bhai, thodi der me try kar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/snippets/"+s.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// SnippetHandler returns the snippet named in the path.
+func SnippetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s, err := Snippets.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, snippet.ErrNotFound) {
+		http.Error(w, "Aisa koi snippet hi nahi h bhai", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logger(r.Context()).Error("reading snippet", "id", r.PathValue("id"), "error", err)
+		http.Error(w, "Snippet padh nahi paya bhai", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
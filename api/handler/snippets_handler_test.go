@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/snippet"
+)
+
+func TestSnippets(t *testing.T) {
+	store, err := snippet.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSnippetStore(store)
+	defer SetSnippetStore(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/snippets", SaveSnippetHandler)
+	mux.HandleFunc("/snippets/{id}", SnippetHandler)
+
+	body, _ := json.Marshal(SnippetRequest{Code: "bhai_sun n = suna_bhai();\nbol_bhai(n);", Stdin: "7\n"})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/snippets", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got=%d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	var saved snippet.Snippet
+	json.NewDecoder(w.Body).Decode(&saved)
+	if saved.ID == "" || w.Header().Get("Location") != "/snippets/"+saved.ID {
+		t.Fatalf("expected an ID and a Location header, got=%+v and %q", saved, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/snippets/"+saved.ID, nil))
+	var got snippet.Snippet
+	json.NewDecoder(w.Body).Decode(&got)
+	if got.Code != saved.Code || got.Stdin != "7\n" {
+		t.Errorf("expected the saved snippet back, got=%+v", got)
+	}
+
+	tests := []struct {
+		method, path, body string
+		expected           int
+	}{
+		{"GET", "/snippets/nahi-hai", "", http.StatusNotFound},
+		{"POST", "/snippets", `{"code": ""}`, http.StatusBadRequest},
+		{"POST", "/snippets", `{"code": "` + strings.Repeat("x", maxSnippetSize+1) + `"}`, http.StatusRequestEntityTooLarge},
+		{"DELETE", "/snippets/" + saved.ID, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.expected {
+			t.Errorf("%s %s - expected status %d, got=%d", tt.method, tt.path, tt.expected, w.Code)
+		}
+	}
+}
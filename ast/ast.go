@@ -67,12 +67,29 @@ func (ps *PrintStatement) String() string {
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  *TypeAnnotation // optional, nil when no annotation was written
 	Value Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 
+// TypeAnnotation is the optional type written after a name in bhai_sun,
+// e.g. `bhai_sun arr: []int = [1, 2]`. Array types have a non-nil Elem.
+type TypeAnnotation struct {
+	Token token.Token
+	Name  string
+	Elem  *TypeAnnotation
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string {
+	if ta.Elem != nil {
+		return "[]" + ta.Elem.String()
+	}
+	return ta.Name
+}
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -122,6 +139,28 @@ type IndexExpression struct {
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// SliceExpression is `left[start:end]`. Start and End are nil when left out,
+// meaning the start and the end of left.
+type SliceExpression struct {
+	Token token.Token // the '[' token
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+
+// IndexAssignStatement is `arr[i] = value`, replacing one element of an array.
+type IndexAssignStatement struct {
+	Token  token.Token // the first token of Target
+	Target *IndexExpression
+	Value  Expression
+}
+
+func (ia *IndexAssignStatement) statementNode()       {}
+func (ia *IndexAssignStatement) TokenLiteral() string { return ia.Token.Literal }
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -191,6 +230,31 @@ type ForExpression struct {
 func (fe *ForExpression) expressionNode()      {}
 func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
 
+// ForEachExpression is `chal_bhai (bhai_sun x me arr) { ... }`, running the
+// body once for every element of an array, character of a string or number of
+// a RangeExpression. Index is nil unless written, as in `bhai_sun i, x me arr`.
+type ForEachExpression struct {
+	Token    token.Token // the chal_bhai token
+	Index    *Identifier
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForEachExpression) expressionNode()      {}
+func (fe *ForEachExpression) TokenLiteral() string { return fe.Token.Literal }
+
+// RangeExpression is `start..end`, the numbers from start up to but not
+// including end. It only appears as the Iterable of a ForEachExpression.
+type RangeExpression struct {
+	Token token.Token // the '..' token
+	Start Expression
+	End   Expression
+}
+
+func (re *RangeExpression) expressionNode()      {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+
 type BreakStatement struct {
 	Token token.Token
 }
@@ -204,3 +268,26 @@ type ContinueStatement struct {
 
 func (cs *ContinueStatement) statementNode()       {}
 func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// TryStatement is `koshish_kar_bhai { ... } pakad_bhai (galti) { ... } aakhir_me { ... }`.
+// At least one of Catch and Finally is set; CatchName is optional even with a Catch.
+type TryStatement struct {
+	Token     token.Token
+	Body      *BlockStatement
+	CatchName *Identifier
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// ThrowStatement is `fek_bhai value`, raising a string as a new error or
+// re-raising a caught one.
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
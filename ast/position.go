@@ -0,0 +1,83 @@
+package ast
+
+import "github.com/ankush-web-eng/brolang/token"
+
+// StartToken returns the first token of node, whose Line and Column give the
+// position the node starts at in the source. Infix and index expressions keep
+// their operator token, so for them we descend into the left operand.
+func StartToken(node Node) token.Token {
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) > 0 {
+			return StartToken(n.Statements[0])
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			return StartToken(n.Left)
+		}
+		return n.Token
+	case *IndexExpression:
+		if n.Left != nil {
+			return StartToken(n.Left)
+		}
+		return n.Token
+	case *SliceExpression:
+		if n.Left != nil {
+			return StartToken(n.Left)
+		}
+		return n.Token
+	case *IndexAssignStatement:
+		return n.Token
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			return StartToken(n.Expression)
+		}
+		return n.Token
+	case *CallExpression:
+		return n.Token
+	case *AssignStatement:
+		return n.Token
+	case *PrintStatement:
+		return n.Token
+	case *LetStatement:
+		return n.Token
+	case *TypeAnnotation:
+		return n.Token
+	case *Identifier:
+		return n.Token
+	case *IntegerLiteral:
+		return n.Token
+	case *StringLiteral:
+		return n.Token
+	case *Boolean:
+		return n.Token
+	case *ArrayLiteral:
+		return n.Token
+	case *PrefixExpression:
+		return n.Token
+	case *BlockStatement:
+		return n.Token
+	case *IfExpression:
+		return n.Token
+	case *WhileExpression:
+		return n.Token
+	case *ForExpression:
+		return n.Token
+	case *ForEachExpression:
+		return n.Token
+	case *RangeExpression:
+		if n.Start != nil {
+			return StartToken(n.Start)
+		}
+		return n.Token
+	case *BreakStatement:
+		return n.Token
+	case *ContinueStatement:
+		return n.Token
+	case *TryStatement:
+		return n.Token
+	case *ThrowStatement:
+		return n.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,461 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling v.Visit(node);
+// node must not be nil. If the visitor w returned by v.Visit(node) is not nil,
+// Walk is invoked recursively with visitor w for each of the non-nil children
+// of node, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if isNil(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatementList(v, n.Statements)
+
+	case *BlockStatement:
+		walkStatementList(v, n.Statements)
+
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *AssignStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *IndexAssignStatement:
+		if n.Target != nil {
+			Walk(v, n.Target)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *PrintStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case *BreakStatement, *ContinueStatement:
+		// nothing to do
+
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean:
+		// nothing to do
+
+	case *TypeAnnotation:
+		if n.Elem != nil {
+			Walk(v, n.Elem)
+		}
+
+	case *ArrayLiteral:
+		walkExpressionList(v, n.Elements)
+
+	case *IndexExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+
+	case *SliceExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Start != nil {
+			Walk(v, n.Start)
+		}
+		if n.End != nil {
+			Walk(v, n.End)
+		}
+
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *CallExpression:
+		if n.Function != nil {
+			Walk(v, n.Function)
+		}
+		walkExpressionList(v, n.Arguments)
+
+	case *IfExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		for _, elseIf := range n.ElseIf {
+			if elseIf != nil {
+				Walk(v, elseIf)
+			}
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *WhileExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *ForExpression:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Update != nil {
+			Walk(v, n.Update)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *ForEachExpression:
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		if n.Iterable != nil {
+			Walk(v, n.Iterable)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *RangeExpression:
+		if n.Start != nil {
+			Walk(v, n.Start)
+		}
+		if n.End != nil {
+			Walk(v, n.End)
+		}
+
+	case *TryStatement:
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+		if n.CatchName != nil {
+			Walk(v, n.CatchName)
+		}
+		if n.Catch != nil {
+			Walk(v, n.Catch)
+		}
+		if n.Finally != nil {
+			Walk(v, n.Finally)
+		}
+
+	case *ThrowStatement:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+// isNil reports whether node is nil or a typed nil pointer. The parser can
+// leave typed nils behind (for example a *LetStatement that failed to parse),
+// so both walkers skip them instead of dereferencing them.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+func walkStatementList(v Visitor, list []Statement) {
+	for _, stmt := range list {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+func walkExpressionList(v Visitor, list []Expression) {
+	for _, exp := range list {
+		if exp != nil {
+			Walk(v, exp)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling f(node);
+// node must not be nil. If f returns true, Inspect invokes f recursively for
+// each of the non-nil children of node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite traverses an AST in depth-first order and replaces every node with
+// the result of f. Children are rewritten before their parent, so f always
+// sees a node whose subtrees have already been rewritten.
+//
+// Returning nil for an element of a statement or expression list removes it
+// from the list; returning nil for any other field clears that field.
+// Returning a node of the wrong kind for a field (for example an expression
+// where a statement is required) panics.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if isNil(node) {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = rewriteStatementList(n.Statements, f)
+
+	case *BlockStatement:
+		n.Statements = rewriteStatementList(n.Statements, f)
+
+	case *LetStatement:
+		n.Name = rewriteIdentifier(n.Name, f)
+		n.Type = rewriteTypeAnnotation(n.Type, f)
+		n.Value = rewriteExpression(n.Value, f)
+
+	case *AssignStatement:
+		n.Name = rewriteIdentifier(n.Name, f)
+		n.Value = rewriteExpression(n.Value, f)
+
+	case *IndexAssignStatement:
+		n.Target = rewriteIndex(n.Target, f)
+		n.Value = rewriteExpression(n.Value, f)
+
+	case *PrintStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+
+	case *ExpressionStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+
+	case *BreakStatement, *ContinueStatement:
+		// nothing to do
+
+	case *Identifier, *IntegerLiteral, *StringLiteral, *Boolean:
+		// nothing to do
+
+	case *TypeAnnotation:
+		n.Elem = rewriteTypeAnnotation(n.Elem, f)
+
+	case *ArrayLiteral:
+		n.Elements = rewriteExpressionList(n.Elements, f)
+
+	case *IndexExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Index = rewriteExpression(n.Index, f)
+
+	case *SliceExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Start = rewriteExpression(n.Start, f)
+		n.End = rewriteExpression(n.End, f)
+
+	case *PrefixExpression:
+		n.Right = rewriteExpression(n.Right, f)
+
+	case *InfixExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+
+	case *CallExpression:
+		n.Function = rewriteExpression(n.Function, f)
+		n.Arguments = rewriteExpressionList(n.Arguments, f)
+
+	case *IfExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteBlock(n.Consequence, f)
+		elseIfs := n.ElseIf[:0]
+		for _, elseIf := range n.ElseIf {
+			if elseIf == nil {
+				continue
+			}
+			if r := Rewrite(elseIf, f); !isNil(r) {
+				elseIfs = append(elseIfs, mustBe[*IfExpression](r))
+			}
+		}
+		n.ElseIf = elseIfs
+		n.Alternative = rewriteBlock(n.Alternative, f)
+
+	case *WhileExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Body = rewriteBlock(n.Body, f)
+
+	case *ForExpression:
+		n.Init = rewriteStatement(n.Init, f)
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Update = rewriteStatement(n.Update, f)
+		n.Body = rewriteBlock(n.Body, f)
+
+	case *ForEachExpression:
+		n.Index = rewriteIdentifier(n.Index, f)
+		n.Value = rewriteIdentifier(n.Value, f)
+		n.Iterable = rewriteExpression(n.Iterable, f)
+		n.Body = rewriteBlock(n.Body, f)
+
+	case *RangeExpression:
+		n.Start = rewriteExpression(n.Start, f)
+		n.End = rewriteExpression(n.End, f)
+
+	case *TryStatement:
+		n.Body = rewriteBlock(n.Body, f)
+		n.CatchName = rewriteIdentifier(n.CatchName, f)
+		n.Catch = rewriteBlock(n.Catch, f)
+		n.Finally = rewriteBlock(n.Finally, f)
+
+	case *ThrowStatement:
+		n.Value = rewriteExpression(n.Value, f)
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func rewriteStatementList(list []Statement, f func(Node) Node) []Statement {
+	out := list[:0]
+	for _, stmt := range list {
+		if r := rewriteStatement(stmt, f); !isNil(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func rewriteExpressionList(list []Expression, f func(Node) Node) []Expression {
+	out := list[:0]
+	for _, exp := range list {
+		if r := rewriteExpression(exp, f); !isNil(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func rewriteStatement(stmt Statement, f func(Node) Node) Statement {
+	if isNil(stmt) {
+		return nil
+	}
+	if r := Rewrite(stmt, f); !isNil(r) {
+		return mustBe[Statement](r)
+	}
+	return nil
+}
+
+func rewriteExpression(exp Expression, f func(Node) Node) Expression {
+	if isNil(exp) {
+		return nil
+	}
+	if r := Rewrite(exp, f); !isNil(r) {
+		return mustBe[Expression](r)
+	}
+	return nil
+}
+
+func rewriteIdentifier(ident *Identifier, f func(Node) Node) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	if r := Rewrite(ident, f); !isNil(r) {
+		return mustBe[*Identifier](r)
+	}
+	return nil
+}
+
+func rewriteIndex(ie *IndexExpression, f func(Node) Node) *IndexExpression {
+	if ie == nil {
+		return nil
+	}
+	if r := Rewrite(ie, f); !isNil(r) {
+		return mustBe[*IndexExpression](r)
+	}
+	return nil
+}
+
+func rewriteTypeAnnotation(ta *TypeAnnotation, f func(Node) Node) *TypeAnnotation {
+	if ta == nil {
+		return nil
+	}
+	if r := Rewrite(ta, f); !isNil(r) {
+		return mustBe[*TypeAnnotation](r)
+	}
+	return nil
+}
+
+func rewriteBlock(block *BlockStatement, f func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	if r := Rewrite(block, f); !isNil(r) {
+		return mustBe[*BlockStatement](r)
+	}
+	return nil
+}
+
+// mustBe converts a rewritten node back to the type its slot requires.
+func mustBe[T Node](n Node) T {
+	t, ok := n.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot use %T where %s is required", n, reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	return t
+}
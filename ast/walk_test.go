@@ -0,0 +1,247 @@
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+func parseProgram(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// nodeTypes records the dynamic type of every node Walk visits, in order.
+type nodeTypes []string
+
+func (n *nodeTypes) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*n = append(*n, "end")
+		return nil
+	}
+	*n = append(*n, fmt.Sprintf("%T", node))
+	return n
+}
+
+func TestWalkVisitsEveryNodeType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`bhai_sun x = 5;`,
+			[]string{"*ast.Program", "*ast.LetStatement", "*ast.Identifier", "*ast.IntegerLiteral"},
+		},
+		{
+			`x = "bro";`,
+			[]string{"*ast.Program", "*ast.AssignStatement", "*ast.Identifier", "*ast.StringLiteral"},
+		},
+		{
+			`bol_bhai(sach);`,
+			[]string{"*ast.Program", "*ast.PrintStatement", "*ast.Boolean"},
+		},
+		{
+			`arr[1 + 2]`,
+			[]string{"*ast.Program", "*ast.ExpressionStatement", "*ast.IndexExpression",
+				"*ast.Identifier", "*ast.InfixExpression", "*ast.IntegerLiteral", "*ast.IntegerLiteral"},
+		},
+		{
+			`bhai_sun y = [1, 2];`,
+			[]string{"*ast.Program", "*ast.LetStatement", "*ast.Identifier", "*ast.ArrayLiteral",
+				"*ast.IntegerLiteral", "*ast.IntegerLiteral"},
+		},
+		{
+			`foo(1, x)`,
+			[]string{"*ast.Program", "*ast.ExpressionStatement", "*ast.CallExpression",
+				"*ast.Identifier", "*ast.IntegerLiteral", "*ast.Identifier"},
+		},
+		{
+			`agar (x == 1) { bas_kar_bhai; } nahi_to_agar (x == 2) { aage_bhad_bhai; } nahi_to { x }`,
+			[]string{"*ast.Program", "*ast.ExpressionStatement", "*ast.IfExpression",
+				"*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+				"*ast.BlockStatement", "*ast.BreakStatement",
+				"*ast.IfExpression", "*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+				"*ast.BlockStatement", "*ast.ContinueStatement",
+				"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.Identifier"},
+		},
+		{
+			`jaha_tak (x < 3) { x = x + 1; }`,
+			[]string{"*ast.Program", "*ast.ExpressionStatement", "*ast.WhileExpression",
+				"*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+				"*ast.BlockStatement", "*ast.AssignStatement", "*ast.Identifier",
+				"*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral"},
+		},
+		{
+			`chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) { bol_bhai(i); }`,
+			[]string{"*ast.Program", "*ast.ExpressionStatement", "*ast.ForExpression",
+				"*ast.LetStatement", "*ast.Identifier", "*ast.IntegerLiteral",
+				"*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+				"*ast.AssignStatement", "*ast.Identifier", "*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+				"*ast.BlockStatement", "*ast.PrintStatement", "*ast.Identifier"},
+		},
+	}
+
+	for i, tt := range tests {
+		var visited nodeTypes
+		ast.Walk(&visited, parseProgram(t, tt.input))
+
+		var got []string
+		for _, name := range visited {
+			if name != "end" {
+				got = append(got, name)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("test %d - wrong visit order.\nexpected=%v\ngot=%v", i, tt.expected, got)
+		}
+	}
+}
+
+func TestWalkPrefixExpression(t *testing.T) {
+	node := &ast.PrefixExpression{
+		Token:    token.Token{Type: token.MINUS, Literal: "-"},
+		Operator: "-",
+		Right:    &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "5"}, Value: 5},
+	}
+
+	var visited nodeTypes
+	ast.Walk(&visited, node)
+
+	expected := nodeTypes{"*ast.PrefixExpression", "*ast.IntegerLiteral", "end", "end"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visit order. expected=%v, got=%v", expected, visited)
+	}
+}
+
+func TestWalkSkipsNilChildren(t *testing.T) {
+	program := &ast.Program{Statements: []ast.Statement{
+		(*ast.LetStatement)(nil),
+		&ast.ExpressionStatement{},
+		&ast.ExpressionStatement{Expression: &ast.ForExpression{Body: &ast.BlockStatement{}}},
+	}}
+
+	var visited nodeTypes
+	ast.Walk(&visited, program)
+
+	expected := nodeTypes{
+		"*ast.Program",
+		"*ast.ExpressionStatement", "end",
+		"*ast.ExpressionStatement", "*ast.ForExpression", "*ast.BlockStatement", "end", "end", "end",
+		"end",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("wrong visit order.\nexpected=%v\ngot=%v", expected, visited)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	program := parseProgram(t, `
+    bhai_sun x = 1;
+    jaha_tak (x < 3) {
+        bhai_sun y = x;
+        x = x + 1;
+    }
+    `)
+
+	// Count identifiers outside loop bodies only.
+	var names []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStatement:
+			return false
+		case *ast.Identifier:
+			names = append(names, n.Value)
+		}
+		return true
+	})
+
+	expected := []string{"x", "x"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong identifiers. expected=%v, got=%v", expected, names)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	program := parseProgram(t, `
+    bhai_sun x = 1 + 2;
+    5;
+    agar (x == 3) {
+        bol_bhai(2 * 3);
+        "noop";
+    } nahi_to_agar (x == 4) {
+        bol_bhai(x);
+    }
+    `)
+
+	// Fold integer additions/multiplications and drop literal and empty statements.
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		switch n := n.(type) {
+		case *ast.InfixExpression:
+			left, lok := n.Left.(*ast.IntegerLiteral)
+			right, rok := n.Right.(*ast.IntegerLiteral)
+			if !lok || !rok {
+				return n
+			}
+			switch n.Operator {
+			case "+":
+				return &ast.IntegerLiteral{Token: n.Token, Value: left.Value + right.Value}
+			case "*":
+				return &ast.IntegerLiteral{Token: n.Token, Value: left.Value * right.Value}
+			}
+		case *ast.ExpressionStatement:
+			switch n.Expression.(type) {
+			case nil, *ast.IntegerLiteral, *ast.StringLiteral:
+				return nil
+			}
+		}
+		return n
+	})
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements has wrong length. expected=2, got=%d", len(program.Statements))
+	}
+
+	let := program.Statements[0].(*ast.LetStatement)
+	if lit, ok := let.Value.(*ast.IntegerLiteral); !ok || lit.Value != 3 {
+		t.Errorf("let value not folded. got=%T (%+v)", let.Value, let.Value)
+	}
+
+	ifExp := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if len(ifExp.Consequence.Statements) != 1 {
+		t.Fatalf("consequence has wrong length. expected=1, got=%d", len(ifExp.Consequence.Statements))
+	}
+	print := ifExp.Consequence.Statements[0].(*ast.PrintStatement)
+	if lit, ok := print.Expression.(*ast.IntegerLiteral); !ok || lit.Value != 6 {
+		t.Errorf("print argument not folded. got=%T (%+v)", print.Expression, print.Expression)
+	}
+	if len(ifExp.ElseIf) != 1 {
+		t.Errorf("else-if branch lost. got=%d", len(ifExp.ElseIf))
+	}
+}
+
+func TestRewriteWrongKindPanics(t *testing.T) {
+	program := parseProgram(t, `bol_bhai(1);`)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Rewrite to panic")
+		}
+	}()
+
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.PrintStatement); ok {
+			return &ast.IntegerLiteral{Value: 1}
+		}
+		return n
+	})
+}
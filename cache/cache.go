@@ -0,0 +1,107 @@
+// Package cache keeps recent results so the same request need not be worked
+// out again. Keys and values are opaque bytes to the cache; callers pick
+// the key, typically a hash of everything that decides the value.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache stores values by key. Values may be dropped at any time.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// Config describes an LRU cache. Zero values mean no limit.
+type Config struct {
+	MaxEntries int           // number of values kept
+	MaxBytes   int64         // total size of the keys and values kept
+	TTL        time.Duration // how long a value is kept after it was set
+}
+
+// LRU is an in-memory Cache that drops the least recently used values when
+// it is full, and values older than its TTL.
+type LRU struct {
+	cfg Config
+
+	mu    sync.Mutex
+	bytes int64
+	order *list.List // most recently used first
+	items map[string]*list.Element
+
+	hits, misses int64
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time // zero if there is no TTL
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// NewLRU returns an empty cache.
+func NewLRU(cfg Config) *LRU {
+	return &LRU{cfg: cfg, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *LRU) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(el)
+		c.misses++
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	c.hits++
+	return e.value, true
+}
+
+// Set stores value under key, unless it is bigger than the whole cache.
+func (c *LRU) Set(key string, value []byte) {
+	e := &entry{key: key, value: value}
+	if c.cfg.TTL > 0 {
+		e.expires = time.Now().Add(c.cfg.TTL)
+	}
+	if c.cfg.MaxBytes > 0 && e.size() > c.cfg.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.order.PushFront(e)
+	c.bytes += e.size()
+	for (c.cfg.MaxEntries > 0 && c.order.Len() > c.cfg.MaxEntries) ||
+		(c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+// Stats reports how many values and bytes are kept, and how many lookups
+// found a value and how many did not.
+func (c *LRU) Stats() (entries int, bytes, hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), c.bytes, c.hits, c.misses
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := c.order.Remove(el).(*entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size()
+}
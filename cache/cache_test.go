@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(Config{MaxEntries: 2})
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a")
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b, the least recently used, to be dropped")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	if entries, _, hits, misses := c.Stats(); entries != 2 || hits != 3 || misses != 1 {
+		t.Errorf("expected 2 entries, 3 hits and 1 miss, got=%d, %d and %d", entries, hits, misses)
+	}
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	c := NewLRU(Config{MaxBytes: 10})
+	c.Set("a", []byte("1234"))
+	c.Set("b", []byte("1234"))
+	if _, bytes, _, _ := c.Stats(); bytes != 10 {
+		t.Fatalf("expected 10 bytes kept, got=%d", bytes)
+	}
+
+	c.Set("c", []byte("12"))
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to be dropped to make room")
+	}
+	c.Set("huge", []byte("0123456789"))
+	if _, ok := c.Get("huge"); ok {
+		t.Error("expected a value bigger than the cache not to be kept")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("expected b to survive a value that was never kept")
+	}
+
+	c.Set("b", []byte("12345"))
+	if got, _ := c.Get("b"); string(got) != "12345" {
+		t.Errorf("expected the new value of b, got=%q", got)
+	}
+	if _, bytes, _, _ := c.Stats(); bytes != 9 {
+		t.Errorf("expected 9 bytes kept after replacing b, got=%d", bytes)
+	}
+}
+
+func TestLRUTTL(t *testing.T) {
+	c := NewLRU(Config{TTL: 10 * time.Millisecond})
+	c.Set("a", []byte("1"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a before its TTL")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a to expire")
+	}
+	if entries, _, _, _ := c.Stats(); entries != 0 {
+		t.Errorf("expected the expired value to be dropped, got %d entries", entries)
+	}
+}
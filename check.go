@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/typecheck"
+)
+
+// checkCommand implements `brolang check [file...]`, running the static type
+// checker without evaluating anything. It exits with status 1 on any error.
+func checkCommand(args []string) int {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range args {
+		code, err := readSource(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brolang check: %v\n", err)
+			status = 1
+			continue
+		}
+
+		for _, d := range typecheck.Source(code) {
+			fmt.Printf("%s:%s\n", name, d)
+			status = 1
+		}
+	}
+	return status
+}
@@ -0,0 +1,65 @@
+//go:build js && wasm
+
+// Command brolang-wasm exposes the interpreter to JavaScript so the
+// playground can run programs without a server. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o brolang.wasm ./cmd/brolang-wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// Once started it defines a global brolang.run(code, stdin) that returns
+// {output, error, diagnostics, stack}: what the program printed, the error it
+// stopped with, any positioned syntax errors and where a runtime error happened.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/runner"
+)
+
+func main() {
+	js.Global().Set("brolang", js.ValueOf(map[string]interface{}{
+		"run": js.FuncOf(run),
+	}))
+
+	// Keep the Go runtime alive so run can be called again.
+	select {}
+}
+
+type result struct {
+	Output      string                  `json:"output"`
+	Error       string                  `json:"error,omitempty"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics,omitempty"`
+	Stack       []object.Frame          `json:"stack,omitempty"`
+}
+
+func run(this js.Value, args []js.Value) (ret interface{}) {
+	// A panic in the evaluator would otherwise stop the Go program and every
+	// later call.
+	defer func() {
+		if r := recover(); r != nil {
+			ret = js.ValueOf(map[string]interface{}{"output": "", "error": fmt.Sprint(r)})
+		}
+	}()
+
+	code, stdin := "", ""
+	if len(args) > 0 {
+		code = args[0].String()
+	}
+	if len(args) > 1 && args[1].Type() == js.TypeString {
+		stdin = args[1].String()
+	}
+
+	res := runner.Run(code, runner.Options{Stdin: strings.NewReader(stdin)})
+
+	b, err := json.Marshal(result{Output: res.Output, Error: res.Error, Diagnostics: res.Diagnostics, Stack: res.Stack})
+	if err != nil {
+		return js.ValueOf(map[string]interface{}{"output": "", "error": err.Error()})
+	}
+	return js.Global().Get("JSON").Call("parse", string(b))
+}
@@ -0,0 +1,364 @@
+// Package config holds the settings of the API server.
+//
+// Every setting has one name, used as a command-line flag (-cache-size), as
+// an environment variable (BROLANG_CACHE_SIZE) and as a key of the optional
+// JSON config file ("cache-size"). Flags win over the environment, and the
+// environment over the file.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/ratelimit"
+	"github.com/ankush-web-eng/brolang/sandbox"
+)
+
+// Config is the server's settings.
+type Config struct {
+	Addr       string   // address to listen on
+	Origins    []string // origins allowed to call the API from a browser; * matches any run of characters
+	MaxBody    int64    // bytes of request body read at most
+	TrustProxy bool     // tell clients apart by X-Forwarded-For
+
+	ReadTimeout  time.Duration // for reading a whole request
+	WriteTimeout time.Duration // for answering a request, from the end of reading it
+	Grace        time.Duration // how long running programs get to finish on shutdown
+
+	RateLimits map[string]ratelimit.Limit // by route; routes not listed are not limited
+	APIKeys    []string                   // secret; a client with one is limited by key instead of address
+
+	Concurrency int // programs run at once
+	Queue       int // programs that may wait for their turn
+	PerClient   int // programs one client may have waiting
+
+	Sandbox bool          // run programs in worker processes
+	Workers int           // sandbox workers; 0 means Concurrency
+	Timeout time.Duration // wall-clock limit for a sandboxed program
+	Limits  sandbox.Limits
+
+	Broker        string        // memory or redis
+	Redis         string        // address of the Redis server
+	RedisPassword string        // secret
+	Keep          time.Duration // how long a background job's result is kept
+
+	CacheSize    int64
+	CacheEntries int
+	CacheTTL     time.Duration
+
+	Snippets string // directory of shared programs
+
+	LogLevel  string // debug, info, warn or error
+	LogFormat string // text or json
+}
+
+// secrets are the settings never shown by Settings.
+var secrets = map[string]bool{"redis-password": true, "api-keys": true}
+
+// Default returns the settings used when nothing else is said.
+func Default() *Config {
+	return &Config{
+		Addr:         ":8080",
+		Origins:      []string{"http://localhost:3000", "https://brolang.ankushsingh.tech"},
+		MaxBody:      1 << 20,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: time.Minute,
+		Grace:        30 * time.Second,
+		RateLimits: map[string]ratelimit.Limit{
+			"/compile":  {Events: 60, Per: time.Minute, Burst: 20},
+			"/lint":     {Events: 120, Per: time.Minute, Burst: 30},
+			"/judge":    {Events: 20, Per: time.Minute, Burst: 5},
+			"/jobs":     {Events: 60, Per: time.Minute, Burst: 20},
+			"/snippets": {Events: 20, Per: time.Minute, Burst: 10},
+		},
+		Concurrency:  runtime.NumCPU(),
+		Queue:        64,
+		PerClient:    8,
+		Timeout:      10 * time.Second,
+		Limits:       sandbox.DefaultLimits,
+		Broker:       "memory",
+		Redis:        "localhost:6379",
+		Keep:         10 * time.Minute,
+		CacheSize:    64 << 20,
+		CacheEntries: 10000,
+		CacheTTL:     time.Hour,
+		Snippets:     "snippets",
+		LogLevel:     "info",
+		LogFormat:    "text",
+	}
+}
+
+// flags binds every setting of c to a flag, with its current value as the
+// default.
+func (c *Config) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&c.Addr, "addr", c.Addr, "address to listen on")
+	fs.Var((*list)(&c.Origins), "origins", "comma-separated origins allowed to call the API from a browser; * matches anything")
+	fs.Int64Var(&c.MaxBody, "max-body", c.MaxBody, "bytes of request body read at most")
+	fs.BoolVar(&c.TrustProxy, "trust-proxy", c.TrustProxy, "tell clients apart by X-Forwarded-For, when behind a reverse proxy")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "time allowed for reading a whole request")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "time allowed for answering a request, waiting in the queue included")
+	fs.DurationVar(&c.Grace, "grace", c.Grace, "how long running programs get to finish when the server is told to stop")
+	fs.Var((*limits)(&c.RateLimits), "rate-limits", "comma-separated ROUTE=EVENTS/UNIT[:BURST] limits per client, such as /compile=60/m:20; unit is s, m or h")
+	fs.Var((*list)(&c.APIKeys), "api-keys", "comma-separated API keys; a client sending one in X-API-Key is limited by key instead of address")
+
+	fs.IntVar(&c.Concurrency, "concurrency", c.Concurrency, "number of programs run at once")
+	fs.IntVar(&c.Queue, "queue", c.Queue, "number of programs that may wait for their turn before clients get 429")
+	fs.IntVar(&c.PerClient, "per-client", c.PerClient, "number of programs one client may have waiting (0 for no limit beyond -queue)")
+
+	fs.BoolVar(&c.Sandbox, "sandbox", c.Sandbox, "run each program in a separate worker process (off by default; turn it on for untrusted code)")
+	fs.IntVar(&c.Workers, "workers", c.Workers, "number of sandbox workers (default -concurrency)")
+	fs.DurationVar(&c.Timeout, "timeout", c.Timeout, "wall-clock limit for a sandboxed program")
+	fs.DurationVar(&c.Limits.CPUTime, "cpu", c.Limits.CPUTime, "CPU time limit for a sandboxed program")
+	fs.Int64Var(&c.Limits.Memory, "memory", c.Limits.Memory, "memory limit in bytes for a sandboxed program")
+	fs.IntVar(&c.Limits.Output, "output", c.Limits.Output, "output limit in bytes for a sandboxed program")
+
+	fs.StringVar(&c.Broker, "broker", c.Broker, "where background job results are published: memory or redis")
+	fs.StringVar(&c.Redis, "redis", c.Redis, "address of the Redis server for -broker=redis")
+	fs.StringVar(&c.RedisPassword, "redis-password", c.RedisPassword, "password of the Redis server")
+	fs.DurationVar(&c.Keep, "keep", c.Keep, "how long the result of a background job is kept")
+
+	fs.Int64Var(&c.CacheSize, "cache-size", c.CacheSize, "bytes of results kept for programs run again on the same input (0 to turn the cache off)")
+	fs.IntVar(&c.CacheEntries, "cache-entries", c.CacheEntries, "number of results kept in the cache")
+	fs.DurationVar(&c.CacheTTL, "cache-ttl", c.CacheTTL, "how long a result stays in the cache")
+
+	fs.StringVar(&c.Snippets, "snippets", c.Snippets, "directory where shared programs are kept")
+
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "lowest level logged: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log as text or json")
+	return fs
+}
+
+// Load works out the settings from args, the environment and the config file
+// named by -config or BROLANG_CONFIG, and checks them. For -h it returns
+// flag.ErrHelp, having printed the usage.
+func Load(name string, args []string) (*Config, error) {
+	c := Default()
+	fs := c.flags(name)
+	file := fs.String("config", os.Getenv("BROLANG_CONFIG"), "JSON file of settings, keyed by flag name")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: brolang %s [flags]\n", name)
+		fmt.Fprintln(fs.Output(), "Every flag can also be set as BROLANG_<NAME> in the environment, or in the -config file.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := map[string]bool{"config": true}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	if *file != "" {
+		settings, err := readFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range settings {
+			if fs.Lookup(key) == nil || key == "config" {
+				return nil, fmt.Errorf("%s: unknown setting %q", *file, key)
+			}
+			if explicit[key] {
+				continue
+			}
+			if err := fs.Set(key, value); err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", *file, key, err)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || err != nil {
+			return
+		}
+		env := EnvName(f.Name)
+		if value, ok := os.LookupEnv(env); ok {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("%s: %v", env, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// EnvName is the environment variable of a setting.
+func EnvName(setting string) string {
+	return "BROLANG_" + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// readFile reads a JSON object of settings, turning every value into the
+// text its flag would take.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	settings := map[string]string{}
+	for key, value := range raw {
+		var s string
+		var list []string
+		switch {
+		case json.Unmarshal(value, &s) == nil:
+			settings[key] = s
+		case json.Unmarshal(value, &list) == nil:
+			settings[key] = strings.Join(list, ",")
+		default:
+			settings[key] = string(value)
+		}
+	}
+	return settings, nil
+}
+
+// Validate checks that the settings make sense together.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(c.Addr != "", "addr must not be empty")
+	for _, origin := range c.Origins {
+		check(origin != "" && !strings.ContainsAny(origin, " ,"), "origin %q is not valid", origin)
+	}
+	check(c.MaxBody > 0, "max-body must be positive, got %d", c.MaxBody)
+	check(c.ReadTimeout > 0 && c.WriteTimeout > 0, "read-timeout and write-timeout must be positive")
+	check(c.Grace >= 0, "grace must not be negative, got %v", c.Grace)
+	for route := range c.RateLimits {
+		check(strings.HasPrefix(route, "/"), "rate limit route %q must start with /", route)
+	}
+	check(c.Concurrency > 0, "concurrency must be positive, got %d", c.Concurrency)
+	check(c.Queue >= 0, "queue must not be negative, got %d", c.Queue)
+	check(c.PerClient >= 0, "per-client must not be negative, got %d", c.PerClient)
+	check(c.Workers >= 0, "workers must not be negative, got %d", c.Workers)
+	check(c.Timeout >= 0 && c.Limits.CPUTime >= 0 && c.Limits.Memory >= 0 && c.Limits.Output >= 0,
+		"timeout, cpu, memory and output must not be negative")
+	check(c.Broker == "memory" || c.Broker == "redis", "broker must be memory or redis, got %q", c.Broker)
+	check(c.Broker != "redis" || c.Redis != "", "redis must be set for -broker=redis")
+	check(c.Keep > 0, "keep must be positive, got %v", c.Keep)
+	check(c.CacheSize >= 0 && c.CacheEntries >= 0 && c.CacheTTL >= 0, "cache-size, cache-entries and cache-ttl must not be negative")
+	check(c.Snippets != "", "snippets must not be empty")
+	check(c.LogLevel == "debug" || c.LogLevel == "info" || c.LogLevel == "warn" || c.LogLevel == "error",
+		"log-level must be debug, info, warn or error, got %q", c.LogLevel)
+	check(c.LogFormat == "text" || c.LogFormat == "json", "log-format must be text or json, got %q", c.LogFormat)
+	return errors.Join(errs...)
+}
+
+// Settings lists every setting by name with its value as a flag would take
+// it, with secrets hidden.
+func (c *Config) Settings() map[string]string {
+	copied := *c
+	settings := map[string]string{}
+	copied.flags("").VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secrets[f.Name] && value != "" {
+			value = "********"
+		}
+		settings[f.Name] = value
+	})
+	return settings
+}
+
+// AllowOrigin reports whether a browser page from origin may call the API.
+func (c *Config) AllowOrigin(origin string) bool {
+	for _, pattern := range c.Origins {
+		if match(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether s matches pattern, where * stands for any run of
+// characters.
+func match(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	if len(parts) == 1 {
+		return s == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// list is a flag.Value of comma-separated strings.
+type list []string
+
+func (l *list) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// limits is a flag.Value of comma-separated ROUTE=LIMIT pairs.
+type limits map[string]ratelimit.Limit
+
+func (l *limits) String() string {
+	if l == nil {
+		return ""
+	}
+	var pairs []string
+	for route, limit := range *l {
+		pairs = append(pairs, route+"="+limit.String())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (l *limits) Set(value string) error {
+	*l = limits{}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%q is not ROUTE=LIMIT", pair)
+		}
+		parsed, err := ratelimit.ParseLimit(limit)
+		if err != nil {
+			return err
+		}
+		(*l)[route] = parsed
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/ratelimit"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "brolang.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+	"addr": ":9000",
+	"queue": 5,
+	"per-client": 2,
+	"cache-ttl": "5m",
+	"trust-proxy": true,
+	"origins": ["https://*.example.com", "http://localhost:3000"],
+	"rate-limits": ["/compile=2/s:10", "/judge=5/m"]
+}`)
+	t.Setenv("BROLANG_CONFIG", path)
+	t.Setenv("BROLANG_QUEUE", "7")
+	t.Setenv("BROLANG_PER_CLIENT", "3")
+
+	c, err := Load("serve", []string{"-per-client=4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":9000" || !c.TrustProxy || c.CacheTTL != 5*time.Minute {
+		t.Errorf("expected the file's settings, got addr=%q trust-proxy=%v cache-ttl=%v", c.Addr, c.TrustProxy, c.CacheTTL)
+	}
+	if c.Queue != 7 {
+		t.Errorf("expected the environment to win over the file, got queue=%d", c.Queue)
+	}
+	if c.PerClient != 4 {
+		t.Errorf("expected the flag to win over the environment, got per-client=%d", c.PerClient)
+	}
+	if len(c.Origins) != 2 || c.Origins[0] != "https://*.example.com" {
+		t.Errorf("wrong origins: %q", c.Origins)
+	}
+	if len(c.RateLimits) != 2 || c.RateLimits["/compile"] != (ratelimit.Limit{Events: 2, Per: time.Second, Burst: 10}) {
+		t.Errorf("wrong rate limits: %v", c.RateLimits)
+	}
+	if c.LogLevel != "info" {
+		t.Errorf("expected the default log level, got=%q", c.LogLevel)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		file     string
+		expected string
+	}{
+		{nil, `{"bhai": 1}`, `unknown setting "bhai"`},
+		{nil, `{"queue": "bahut"}`, "queue"},
+		{[]string{"-concurrency=0", "-log-format=xml"}, "", "concurrency must be positive"},
+		{[]string{"-log-format=xml"}, "", "log-format must be text or json"},
+		{[]string{"-broker=kafka"}, "", "broker must be memory or redis"},
+		{[]string{"-rate-limits=/compile=bahut"}, "", "EVENTS/UNIT"},
+		{[]string{"-rate-limits=compile=1/s"}, "", "must start with /"},
+	}
+
+	for _, tt := range tests {
+		args := tt.args
+		if tt.file != "" {
+			args = append(args, "-config", writeConfig(t, tt.file))
+		}
+		_, err := Load("serve", args)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Load(%q) - expected an error containing %q, got=%v", args, tt.expected, err)
+		}
+	}
+}
+
+func TestAllowOrigin(t *testing.T) {
+	c := &Config{Origins: []string{"http://localhost:3000", "https://*.brolang.dev", "http://127.0.0.1:*"}}
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+		{"https://play.brolang.dev", true},
+		{"https://brolang.dev", false},
+		{"https://evil.com/.brolang.dev.evil.com", false},
+		{"http://127.0.0.1:5173", true},
+	}
+
+	for _, tt := range tests {
+		if got := c.AllowOrigin(tt.origin); got != tt.expected {
+			t.Errorf("AllowOrigin(%q) - expected %v, got=%v", tt.origin, tt.expected, got)
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	c := Default()
+	c.Origins = []string{"https://a.com", "https://b.com"}
+	settings := c.Settings()
+	if settings["origins"] != "https://a.com,https://b.com" {
+		t.Errorf("wrong origins setting: %q", settings["origins"])
+	}
+	if settings["redis-password"] != "" {
+		t.Errorf("expected an unset password to show empty, got=%q", settings["redis-password"])
+	}
+
+	c.RedisPassword = "bahut-secret"
+	c.APIKeys = []string{"class-10b"}
+	if got := c.Settings()["redis-password"]; got != "********" {
+		t.Errorf("expected the password hidden, got=%q", got)
+	}
+	if got := c.Settings()["api-keys"]; got != "********" {
+		t.Errorf("expected the API keys hidden, got=%q", got)
+	}
+	if got := c.Settings()["rate-limits"]; !strings.HasPrefix(got, "/compile=60/m:20,") {
+		t.Errorf("wrong rate-limits setting: %q", got)
+	}
+	if c.RedisPassword != "bahut-secret" {
+		t.Error("Settings changed the config")
+	}
+}
@@ -0,0 +1,188 @@
+package dap
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/object"
+)
+
+// errTerminated stops the program when the client ends the session.
+var errTerminated = errors.New("debugger ne program rok diya")
+
+type stepMode int
+
+const (
+	modeContinue stepMode = iota
+	modeStepIn            // stop at the next statement
+	modeStepOver          // stop at the next statement that is not nested deeper
+	modeStepOut           // stop at the next statement outside the current block
+)
+
+type command struct {
+	mode      stepMode
+	terminate bool
+}
+
+// position identifies a statement by where it starts. The program is parsed
+// again when it runs, so statements cannot be matched by pointer.
+type position struct{ line, column int }
+
+func positionOf(stmt ast.Statement) position {
+	tok := ast.StartToken(stmt)
+	return position{tok.Line, tok.Column}
+}
+
+// debugger is the object.Hook that pauses the program. BeforeStatement runs
+// on the program's goroutine and blocks there while the program is stopped;
+// everything else is called by the server.
+type debugger struct {
+	depths map[position]int // nesting depth of every statement, 0 at the top level
+	lines  map[int]bool     // lines on which a statement starts
+
+	// stopped is called on the program's goroutine each time it pauses.
+	stopped func(reason string, env *object.Environment)
+	resume  chan command
+
+	mu             sync.Mutex
+	breakpoints    map[int]bool
+	mode           stepMode
+	stepDepth      int
+	entry          bool
+	pauseRequested bool
+	terminated     bool
+	stmt           ast.Statement // the statement about to run while paused
+	env            *object.Environment
+}
+
+func newDebugger(program *ast.Program, stopOnEntry bool, stopped func(string, *object.Environment)) *debugger {
+	d := &debugger{
+		depths:      map[position]int{},
+		lines:       map[int]bool{},
+		stopped:     stopped,
+		resume:      make(chan command, 1),
+		breakpoints: map[int]bool{},
+		entry:       stopOnEntry,
+	}
+	d.record(program.Statements, 0)
+	return d
+}
+
+// record notes the depth and line of every statement the evaluator will
+// announce: those of the program and of blocks, at any depth.
+func (d *debugger) record(stmts []ast.Statement, depth int) {
+	for _, stmt := range stmts {
+		d.depths[positionOf(stmt)] = depth
+		d.lines[ast.StartToken(stmt).Line] = true
+
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if block, ok := n.(*ast.BlockStatement); ok && block != stmt {
+				d.record(block.Statements, depth+1)
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// setBreakpoints replaces the breakpoints and reports which lines hold a statement.
+func (d *debugger) setBreakpoints(lines []int) []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = map[int]bool{}
+	out := make([]Breakpoint, len(lines))
+	for i, line := range lines {
+		out[i] = Breakpoint{Line: line, Verified: d.lines[line]}
+		if d.lines[line] {
+			d.breakpoints[line] = true
+		} else {
+			out[i].Message = "no statement starts on this line"
+		}
+	}
+	return out
+}
+
+// BeforeStatement implements object.Hook.
+func (d *debugger) BeforeStatement(stmt ast.Statement, env *object.Environment) error {
+	d.mu.Lock()
+	if d.terminated {
+		d.mu.Unlock()
+		return errTerminated
+	}
+	reason := d.stopReason(stmt)
+	if reason == "" {
+		d.mu.Unlock()
+		return nil
+	}
+	d.stmt, d.env = stmt, env
+	d.entry, d.pauseRequested = false, false
+	d.mu.Unlock()
+
+	d.stopped(reason, env)
+	cmd := <-d.resume
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stmt, d.env = nil, nil
+	if cmd.terminate || d.terminated {
+		d.terminated = true
+		return errTerminated
+	}
+	d.mode = cmd.mode
+	d.stepDepth = d.depths[positionOf(stmt)]
+	return nil
+}
+
+func (d *debugger) stopReason(stmt ast.Statement) string {
+	depth := d.depths[positionOf(stmt)]
+	switch {
+	case d.entry:
+		return "entry"
+	case d.pauseRequested:
+		return "pause"
+	case d.mode == modeStepIn,
+		d.mode == modeStepOver && depth <= d.stepDepth,
+		d.mode == modeStepOut && depth < d.stepDepth:
+		return "step"
+	case d.breakpoints[ast.StartToken(stmt).Line]:
+		return "breakpoint"
+	}
+	return ""
+}
+
+// paused returns the statement and environment the program is stopped at,
+// or nil if it is running.
+func (d *debugger) paused() (ast.Statement, *object.Environment) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stmt, d.env
+}
+
+// proceed resumes a stopped program in the given mode. It reports false if
+// the program was not stopped.
+func (d *debugger) proceed(mode stepMode) bool {
+	if stmt, _ := d.paused(); stmt == nil {
+		return false
+	}
+	d.resume <- command{mode: mode}
+	return true
+}
+
+func (d *debugger) pause() {
+	d.mu.Lock()
+	d.pauseRequested = true
+	d.mu.Unlock()
+}
+
+// terminate stops the program at its next statement, or right away if it is stopped.
+func (d *debugger) terminate() {
+	d.mu.Lock()
+	d.terminated = true
+	stopped := d.stmt != nil
+	d.mu.Unlock()
+	if stopped {
+		d.resume <- command{terminate: true}
+	}
+}
@@ -0,0 +1,380 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/runner"
+)
+
+// threadID is the only thread a Brolang program has.
+const threadID = 1
+
+// Server is a Debug Adapter Protocol server that runs one Brolang program.
+// Requests are handled one at a time on the goroutine calling Run; the
+// program runs on its own goroutine and blocks in the debugger while stopped.
+type Server struct {
+	in *bufio.Reader
+
+	wmu sync.Mutex // guards out and seq, written from both goroutines
+	out io.Writer
+	seq int
+
+	launch      *LaunchArguments
+	code        string
+	breakpoints []int // lines requested before the program was parsed
+	dbg         *debugger
+	started     bool
+	done        chan struct{} // closed once the program has finished
+
+	// written is how much of the program's output has been sent to the
+	// client. Only the program's goroutine touches it.
+	written int
+
+	// refs holds what each variablesReference points at while the program
+	// is stopped: an *object.Environment or an *object.Array.
+	refs []interface{}
+}
+
+// NewServer returns a server reading requests from in and writing responses
+// and events to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		done: make(chan struct{}),
+	}
+}
+
+// Run serves requests until the client disconnects or closes the input. A
+// program that is still running is terminated first.
+func (s *Server) Run() error {
+	defer s.stop()
+	for {
+		body, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		result, err := s.handle(&req)
+		s.respond(&req, result, err)
+		if req.Command == "launch" && err == nil {
+			s.event("initialized", nil)
+		}
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// stop terminates the program, if it started, and waits for it to finish.
+func (s *Server) stop() {
+	if !s.started {
+		return
+	}
+	if s.dbg != nil {
+		s.dbg.terminate()
+	}
+	<-s.done
+}
+
+func (s *Server) send(v interface{}, seq *int) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.seq++
+	*seq = s.seq
+	writeMessage(s.out, v)
+}
+
+func (s *Server) respond(req *request, body interface{}, err error) {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	s.send(resp, &resp.Seq)
+}
+
+func (s *Server) event(name string, body interface{}) {
+	ev := &event{Type: "event", Event: name, Body: body}
+	s.send(ev, &ev.Seq)
+}
+
+func (s *Server) handle(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{SupportsConfigurationDoneRequest: true, SupportsTerminateRequest: true}, nil
+
+	case "launch":
+		var args LaunchArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.load(&args)
+
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+
+	case "configurationDone":
+		return nil, s.start()
+
+	case "threads":
+		return map[string]interface{}{"threads": []Thread{{ID: threadID, Name: "main"}}}, nil
+
+	case "stackTrace":
+		return s.stackTrace(), nil
+
+	case "scopes":
+		return map[string]interface{}{"scopes": s.scopes()}, nil
+
+	case "variables":
+		var args VariablesArguments
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		vars, err := s.variables(args.VariablesReference)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"variables": vars}, nil
+
+	case "continue":
+		return map[string]interface{}{"allThreadsContinued": true}, s.proceed(modeContinue)
+	case "next":
+		return nil, s.proceed(modeStepOver)
+	case "stepIn":
+		return nil, s.proceed(modeStepIn)
+	case "stepOut":
+		return nil, s.proceed(modeStepOut)
+
+	case "pause":
+		if s.dbg != nil {
+			s.dbg.pause()
+		}
+		return nil, nil
+
+	case "terminate", "disconnect":
+		s.stop()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request %q", req.Command)
+}
+
+// load reads and parses the program to debug. Syntax errors fail the launch
+// so the editor shows them instead of starting a session.
+func (s *Server) load(args *LaunchArguments) error {
+	if s.launch != nil {
+		return fmt.Errorf("a program is already launched")
+	}
+	code, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+	p := parser.New(lexer.New(string(code)))
+	program := p.ParseProgram()
+	if diags := p.Diagnostics(); len(diags) > 0 {
+		return fmt.Errorf("%s: %s", filepath.Base(args.Program), diags[0])
+	}
+
+	s.launch, s.code = args, string(code)
+	if !args.NoDebug {
+		s.dbg = newDebugger(program, args.StopOnEntry, s.stopped)
+		s.dbg.setBreakpoints(s.breakpoints)
+	}
+	return nil
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) map[string]interface{} {
+	lines := make([]int, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		lines[i] = bp.Line
+	}
+
+	var out []Breakpoint
+	if s.dbg != nil {
+		out = s.dbg.setBreakpoints(lines)
+	} else {
+		// Not launched yet: keep the lines for launch and trust them for now.
+		s.breakpoints = lines
+		out = make([]Breakpoint, len(lines))
+		for i, line := range lines {
+			out[i] = Breakpoint{Line: line, Verified: true}
+		}
+	}
+	return map[string]interface{}{"breakpoints": out}
+}
+
+// start runs the launched program on its own goroutine.
+func (s *Server) start() error {
+	if s.launch == nil {
+		return fmt.Errorf("launch the program first")
+	}
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	opts := runner.Options{DisableOptimizer: true, Stdin: strings.NewReader(s.launch.Stdin)}
+	if s.dbg != nil {
+		// The debugger finds statements by position, so the program has to run
+		// exactly as it was parsed.
+		opts.Hook = s.dbg
+	}
+	go func() {
+		defer close(s.done)
+		res := runner.Run(s.code, opts)
+		s.flush(res.Output)
+
+		exitCode := 0
+		if res.Error != "" && !s.terminated() {
+			msg := res.Error + "\n"
+			for _, frame := range res.Stack {
+				msg += "\t" + frame.String() + "\n"
+			}
+			s.event("output", OutputEventBody{Category: "stderr", Output: msg})
+			exitCode = 1
+		}
+		s.event("exited", ExitedEventBody{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+	return nil
+}
+
+func (s *Server) terminated() bool {
+	if s.dbg == nil {
+		return false
+	}
+	s.dbg.mu.Lock()
+	defer s.dbg.mu.Unlock()
+	return s.dbg.terminated
+}
+
+// stopped is called by the debugger on the program's goroutine when it pauses.
+func (s *Server) stopped(reason string, env *object.Environment) {
+	s.flush(output(env))
+	s.event("stopped", StoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+}
+
+// output is everything the program has printed so far. Loops collect the
+// output of their environment and hand it to the outer one as they go, so the
+// builders from the outermost environment inwards hold it in order.
+func output(env *object.Environment) string {
+	if env == nil {
+		return ""
+	}
+	return output(env.Outer) + env.OutputBuilder.String()
+}
+
+// flush sends the part of the program's output the client has not seen yet.
+func (s *Server) flush(all string) {
+	if len(all) <= s.written {
+		return
+	}
+	s.event("output", OutputEventBody{Category: "stdout", Output: all[s.written:]})
+	s.written = len(all)
+}
+
+func (s *Server) proceed(mode stepMode) error {
+	if s.dbg == nil || !s.dbg.proceed(mode) {
+		return fmt.Errorf("the program is not stopped")
+	}
+	s.refs = nil
+	return nil
+}
+
+func (s *Server) stackTrace() map[string]interface{} {
+	frames := []StackFrame{}
+	if s.dbg != nil {
+		if stmt, _ := s.dbg.paused(); stmt != nil {
+			tok := ast.StartToken(stmt)
+			frames = append(frames, StackFrame{
+				ID:     1,
+				Name:   "main",
+				Source: Source{Name: filepath.Base(s.launch.Program), Path: s.launch.Program},
+				Line:   tok.Line,
+				Column: tok.Column,
+			})
+		}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+// scopes lists the environments visible from the current statement, innermost
+// first. Every block, loop and pakad_bhai has an environment of its own.
+func (s *Server) scopes() []Scope {
+	scopes := []Scope{}
+	if s.dbg == nil {
+		return scopes
+	}
+	_, env := s.dbg.paused()
+	for ; env != nil; env = env.Outer {
+		name := "Block"
+		if env.Outer == nil {
+			name = "Globals"
+		}
+		scopes = append(scopes, Scope{Name: name, VariablesReference: s.reference(env)})
+	}
+	return scopes
+}
+
+func (s *Server) reference(v interface{}) int {
+	s.refs = append(s.refs, v)
+	return len(s.refs)
+}
+
+func (s *Server) variables(ref int) ([]Variable, error) {
+	if ref < 1 || ref > len(s.refs) {
+		return nil, fmt.Errorf("unknown variables reference %d", ref)
+	}
+	vars := []Variable{}
+	switch v := s.refs[ref-1].(type) {
+	case *object.Environment:
+		for _, name := range v.Names() {
+			val, _ := v.Get(name)
+			vars = append(vars, s.variable(name, val))
+		}
+	case *object.Array:
+		for i, el := range v.Elements {
+			vars = append(vars, s.variable(strconv.Itoa(i), el))
+		}
+	}
+	return vars, nil
+}
+
+func (s *Server) variable(name string, val object.Object) Variable {
+	if val == nil {
+		return Variable{Name: name, Value: "null"}
+	}
+	v := Variable{Name: name, Value: val.Inspect(), Type: strings.ToLower(string(val.Type()))}
+	switch val := val.(type) {
+	case *object.String:
+		v.Value = strconv.Quote(val.Value)
+	case *object.Array:
+		v.VariablesReference = s.reference(val)
+	}
+	return v
+}
@@ -0,0 +1,333 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testProgram = `bhai_sun total = 0;
+chal_bhai (bhai_sun i = 0; i < 2; i = i + 1) {
+    bhai_sun sq = i * i;
+    bol_bhai(sq);
+}
+bhai_sun naam = "bro";
+bol_bhai(naam);
+`
+
+// message is any response or event the server sends.
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client drives a server over pipes, like an editor would.
+type client struct {
+	t    *testing.T
+	in   io.WriteCloser
+	msgs chan message
+	seq  int
+	done chan error
+
+	// pending holds the events that arrived while waiting for a response.
+	pending []message
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	c := &client{t: t, in: reqW, msgs: make(chan message, 100), done: make(chan error, 1)}
+
+	go func() {
+		err := NewServer(reqR, respW).Run()
+		respW.Close()
+		c.done <- err
+	}()
+	go func() {
+		defer close(c.msgs)
+		r := bufio.NewReader(respR)
+		for {
+			body, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(body, &msg); err != nil {
+				t.Errorf("server wrote invalid JSON: %v", err)
+				return
+			}
+			c.msgs <- msg
+		}
+	}()
+	return c
+}
+
+func (c *client) next() message {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.msgs:
+		if !ok {
+			c.t.Fatal("server closed the connection")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+	return message{}
+}
+
+// request sends a request and returns its response. Events that arrive before
+// it are kept for waitFor.
+func (c *client) request(command string, args interface{}) message {
+	c.t.Helper()
+	c.seq++
+	if err := writeMessage(c.in, map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.next()
+		if msg.Type == "response" && msg.RequestSeq == c.seq {
+			return msg
+		}
+		c.pending = append(c.pending, msg)
+	}
+}
+
+// waitFor returns the given event, collecting the events that arrive before it.
+func (c *client) waitFor(name string) (message, []message) {
+	c.t.Helper()
+	var events []message
+	for {
+		var msg message
+		if len(c.pending) > 0 {
+			msg, c.pending = c.pending[0], c.pending[1:]
+		} else {
+			msg = c.next()
+		}
+		if msg.Type == "event" && msg.Event == name {
+			return msg, events
+		}
+		events = append(events, msg)
+	}
+}
+
+// stopAt waits for the program to stop and checks why and where.
+func (c *client) stopAt(reason string, line int) string {
+	c.t.Helper()
+	ev, events := c.waitFor("stopped")
+	var stopped StoppedEventBody
+	json.Unmarshal(ev.Body, &stopped)
+	if stopped.Reason != reason {
+		c.t.Errorf("expected to stop for %q, got=%q", reason, stopped.Reason)
+	}
+
+	resp := c.request("stackTrace", map[string]int{"threadId": threadID})
+	var trace struct{ StackFrames []StackFrame }
+	json.Unmarshal(resp.Body, &trace)
+	if len(trace.StackFrames) != 1 || trace.StackFrames[0].Line != line {
+		c.t.Errorf("expected to stop at line %d, got=%+v", line, trace.StackFrames)
+	}
+	return outputOf(events)
+}
+
+func outputOf(events []message) string {
+	var out strings.Builder
+	for _, ev := range events {
+		if ev.Event == "output" {
+			var body OutputEventBody
+			json.Unmarshal(ev.Body, &body)
+			out.WriteString(body.Output)
+		}
+	}
+	return out.String()
+}
+
+// variables returns the variables of every scope, innermost first, as name=value.
+func (c *client) variables() [][]string {
+	c.t.Helper()
+	resp := c.request("scopes", map[string]int{"frameId": 1})
+	var scopes struct{ Scopes []Scope }
+	json.Unmarshal(resp.Body, &scopes)
+
+	var out [][]string
+	for _, scope := range scopes.Scopes {
+		resp := c.request("variables", VariablesArguments{VariablesReference: scope.VariablesReference})
+		var vars struct{ Variables []Variable }
+		json.Unmarshal(resp.Body, &vars)
+		list := []string{scope.Name}
+		for _, v := range vars.Variables {
+			list = append(list, v.Name+"="+v.Value)
+		}
+		out = append(out, list)
+	}
+	return out
+}
+
+func writeProgram(t *testing.T, code string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.bro")
+	if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func (c *client) launch(args LaunchArguments, breakpoints ...int) {
+	c.t.Helper()
+	c.request("initialize", map[string]string{"adapterID": "brolang"})
+	if resp := c.request("launch", args); !resp.Success {
+		c.t.Fatalf("launch failed: %s", resp.Message)
+	}
+	c.waitFor("initialized")
+
+	bps := []SourceBreakpoint{}
+	for _, line := range breakpoints {
+		bps = append(bps, SourceBreakpoint{Line: line})
+	}
+	c.request("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: args.Program}, Breakpoints: bps})
+	c.request("configurationDone", nil)
+}
+
+func (c *client) finish(exitCode int) string {
+	c.t.Helper()
+	ev, events := c.waitFor("exited")
+	var exited ExitedEventBody
+	json.Unmarshal(ev.Body, &exited)
+	if exited.ExitCode != exitCode {
+		c.t.Errorf("expected exit code %d, got=%d", exitCode, exited.ExitCode)
+	}
+	c.waitFor("terminated")
+	c.request("disconnect", nil)
+	c.in.Close()
+	if err := <-c.done; err != nil {
+		c.t.Errorf("server stopped with error: %v", err)
+	}
+	return outputOf(events)
+}
+
+func TestBreakpointsAndStepping(t *testing.T) {
+	c := newClient(t)
+	c.launch(LaunchArguments{Program: writeProgram(t, testProgram)}, 3)
+
+	c.stopAt("breakpoint", 3)
+	// The loop body gets a fresh scope on every pass, inside the loop's own.
+	if vars := fmtVars(c.variables()); vars != "[[Block] [Block i=0] [Globals total=0]]" {
+		t.Errorf("wrong variables in the first iteration. got=%s", vars)
+	}
+
+	c.request("next", map[string]int{"threadId": threadID})
+	c.stopAt("step", 4)
+
+	// Stepping over the last statement of the body goes round the loop.
+	c.request("next", map[string]int{"threadId": threadID})
+	if out := c.stopAt("step", 3); out != "0\n" {
+		t.Errorf("wrong output before the second iteration. got=%q", out)
+	}
+	if vars := fmtVars(c.variables()); vars != "[[Block] [Block i=1] [Globals total=0]]" {
+		t.Errorf("wrong variables in the second iteration. got=%s", vars)
+	}
+
+	c.request("stepOut", map[string]int{"threadId": threadID})
+	if out := c.stopAt("step", 6); out != "1\n" {
+		t.Errorf("wrong output after the loop. got=%q", out)
+	}
+	if vars := fmtVars(c.variables()); vars != "[[Globals total=0]]" {
+		t.Errorf("wrong variables after the loop. got=%s", vars)
+	}
+
+	c.request("stepIn", map[string]int{"threadId": threadID})
+	c.stopAt("step", 7)
+	if vars := fmtVars(c.variables()); vars != `[[Globals naam="bro" total=0]]` {
+		t.Errorf("wrong variables at the end. got=%s", vars)
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	if out := c.finish(0); out != "bro\n" {
+		t.Errorf("wrong final output. got=%q", out)
+	}
+}
+
+func fmtVars(vars [][]string) string {
+	parts := make([]string, len(vars))
+	for i, scope := range vars {
+		parts[i] = "[" + strings.Join(scope, " ") + "]"
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func TestStepIntoLoopAndArrays(t *testing.T) {
+	code := "bhai_sun xs = [\"ek\", \"do\"];\nchal_bhai (bhai_sun i = 0; i < 1; i = i + 1) {\n    bol_bhai(i);\n}\n"
+	c := newClient(t)
+	c.launch(LaunchArguments{Program: writeProgram(t, code), StopOnEntry: true})
+
+	c.stopAt("entry", 1)
+	c.request("next", map[string]int{"threadId": threadID})
+	c.stopAt("step", 2)
+
+	resp := c.request("scopes", map[string]int{"frameId": 1})
+	var scopes struct{ Scopes []Scope }
+	json.Unmarshal(resp.Body, &scopes)
+	resp = c.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference})
+	var vars struct{ Variables []Variable }
+	json.Unmarshal(resp.Body, &vars)
+	if len(vars.Variables) != 1 || vars.Variables[0].Type != "array" || vars.Variables[0].VariablesReference == 0 {
+		t.Fatalf("expected an expandable array, got=%+v", vars.Variables)
+	}
+	resp = c.request("variables", VariablesArguments{VariablesReference: vars.Variables[0].VariablesReference})
+	json.Unmarshal(resp.Body, &vars)
+	if len(vars.Variables) != 2 || vars.Variables[1].Name != "1" || vars.Variables[1].Value != `"do"` {
+		t.Errorf("wrong array elements. got=%+v", vars.Variables)
+	}
+
+	c.request("stepIn", map[string]int{"threadId": threadID})
+	c.stopAt("step", 3)
+	c.request("continue", map[string]int{"threadId": threadID})
+	if out := c.finish(0); out != "0\n" {
+		t.Errorf("wrong output. got=%q", out)
+	}
+}
+
+func TestRuntimeErrorAndBadBreakpoint(t *testing.T) {
+	c := newClient(t)
+	path := writeProgram(t, "bol_bhai(1);\nbol_bhai(x);\n")
+	c.launch(LaunchArguments{Program: path, StopOnEntry: true})
+	c.stopAt("entry", 1)
+
+	resp := c.request("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 5}}})
+	var bps struct{ Breakpoints []Breakpoint }
+	json.Unmarshal(resp.Body, &bps)
+	if len(bps.Breakpoints) != 1 || bps.Breakpoints[0].Verified {
+		t.Errorf("expected an unverified breakpoint, got=%+v", bps.Breakpoints)
+	}
+	c.request("continue", map[string]int{"threadId": threadID})
+
+	out := c.finish(1)
+	if !strings.HasPrefix(out, "1\n") || !strings.Contains(out, "x") {
+		t.Errorf("expected the output and the error, got=%q", out)
+	}
+}
+
+func TestLaunchSyntaxError(t *testing.T) {
+	c := newClient(t)
+	c.request("initialize", nil)
+	resp := c.request("launch", LaunchArguments{Program: writeProgram(t, "bol_bhai(5;")})
+	if resp.Success || !strings.Contains(resp.Message, "main.bro") {
+		t.Errorf("expected launch to fail with the syntax error, got=%+v", resp)
+	}
+	c.request("disconnect", nil)
+	c.in.Close()
+	if err := <-c.done; err != nil {
+		t.Errorf("server stopped with error: %v", err)
+	}
+}
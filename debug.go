@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/dap"
+)
+
+// debugCommand implements `brolang debug`, a debug adapter speaking the Debug
+// Adapter Protocol over stdin and stdout so editors can step through programs.
+func debugCommand(args []string) int {
+	if err := dap.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "brolang debug: %v\n", err)
+		return 1
+	}
+	return 0
+}
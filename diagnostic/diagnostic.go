@@ -0,0 +1,50 @@
+package diagnostic
+
+import (
+	"fmt"
+
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a program before or while it runs,
+// pinned to the position of the token that caused it.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// New creates a diagnostic at the position of the given token.
+func New(tok token.Token, severity Severity, code, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		Line:     tok.Line,
+		Column:   tok.Column,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// String formats the diagnostic as "line:column: severity: message (code)".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", d.Line, d.Column, d.Severity, d.Message, d.Code)
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
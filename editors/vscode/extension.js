@@ -0,0 +1,31 @@
+// Starts `brolang lsp` for .bro files and `brolang debug` for debug sessions.
+const vscode = require("vscode");
+const { LanguageClient } = require("vscode-languageclient/node");
+
+let client;
+
+function activate(context) {
+  const command = vscode.workspace.getConfiguration("brolang").get("path", "brolang");
+  client = new LanguageClient(
+    "brolang",
+    "Brolang",
+    { command, args: ["lsp"] },
+    { documentSelector: [{ scheme: "file", language: "brolang" }] },
+  );
+  context.subscriptions.push(client);
+  client.start();
+
+  context.subscriptions.push(
+    vscode.debug.registerDebugAdapterDescriptorFactory("brolang", {
+      createDebugAdapterDescriptor() {
+        return new vscode.DebugAdapterExecutable(command, ["debug"]);
+      },
+    }),
+  );
+}
+
+function deactivate() {
+  return client ? client.stop() : undefined;
+}
+
+module.exports = { activate, deactivate };
@@ -2,9 +2,14 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/ankush-web-eng/brolang/ast"
 	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/resolve"
+	"github.com/ankush-web-eng/brolang/token"
 )
 
 // Eval evaluates the given AST node in the specified environment.
@@ -17,21 +22,27 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Program:
 		return evalProgram(node, env)
 	case *ast.LetStatement:
-		return evalLetStatement(node, env)
+		return traceSet(node, node.Name, evalLetStatement(node, env), env)
 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 	case *ast.ForExpression:
 		return evalForExpression(node, env)
+	case *ast.ForEachExpression:
+		return evalForEachExpression(node, env)
 	case *ast.WhileExpression:
 		return evalWhileExpression(node, env)
 	case *ast.BreakStatement:
 		return evalBreakStatement()
 	case *ast.ContinueStatement:
 		return evalContinueStatement()
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return evalBlockScope(node, env)
 	case *ast.PrintStatement:
 		return evalPrintStatement(node, env)
 
@@ -46,6 +57,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			}
 			return NULL
 		}
+		if node.Function.TokenLiteral() == "suna_bhai" {
+			return evalInput(node, env)
+		}
+		if node.Function.TokenLiteral() == "jod_bhai" {
+			return evalAppend(node, env)
+		}
 		return newError("Ye konsa function h ??: %s", node.Function.TokenLiteral())
 
 	case *ast.IntegerLiteral:
@@ -58,7 +75,16 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.AssignStatement:
-		return evalAssignStatement(node, env)
+		return traceSet(node, node.Name, evalAssignStatement(node, env), env)
+	case *ast.IndexAssignStatement:
+		return evalIndexAssignStatement(node, env)
+
+	case *ast.PrefixExpression:
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
@@ -84,6 +110,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 
 	default:
 		return newError("unknown node type: %T", node)
@@ -96,10 +124,18 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 		return newError("Kuch likh to sahi be!!")
 	}
 
+	if !env.LegacyScoping {
+		env.Depths = resolve.Program(program).Depths
+	}
+
 	var result object.Object
 	for _, stmt := range program.Statements {
+		if err := beforeStatement(stmt, env); err != nil {
+			return err
+		}
 		result = Eval(stmt, env)
-		if result != nil && result.Type() == object.ERROR_OBJ {
+		if err, ok := result.(*object.Error); ok {
+			locateError(err, stmt, env)
 			return result
 		}
 	}
@@ -125,7 +161,9 @@ func evalLetStatement(ls *ast.LetStatement, env *object.Environment) object.Obje
 	return value
 }
 
-// evaluates an assign statement by evaluating the value and setting it in the environment.
+// evaluates an assign statement by evaluating the value and storing it in the
+// nearest environment that already holds the variable. Assigning a name that
+// was never declared is an error, unless legacy scoping declares it here.
 func evalAssignStatement(stmt *ast.AssignStatement, env *object.Environment) object.Object {
 	// First evaluate the value to be assigned
 	val := Eval(stmt.Value, env)
@@ -133,24 +171,17 @@ func evalAssignStatement(stmt *ast.AssignStatement, env *object.Environment) obj
 		return val
 	}
 
-	// Try to set directly in current environment first
-	if _, ok := env.Get(stmt.Name.Value); ok {
-		return env.Set(stmt.Name.Value, val)
+	name := stmt.Name.Value
+	if depth, ok := env.Depths[stmt.Name]; ok && env.AssignAt(depth, name, val) {
+		return val
 	}
-
-	// If not found in current, explicitly check outer environments
-	if env.Outer != nil {
-		current := env.Outer
-		for current != nil {
-			if _, ok := current.Get(stmt.Name.Value); ok {
-				return current.Set(stmt.Name.Value, val)
-			}
-			current = current.Outer
-		}
+	if env.Assign(name, val) {
+		return val
 	}
-
-	// If variable doesn't exist anywhere in chain, create it in current environment
-	return env.Set(stmt.Name.Value, val)
+	if env.LegacyScoping {
+		return env.Set(name, val)
+	}
+	return newCodedError(object.CodeUndefined, "Pehle bhai_sun to likh! %s naam ka koi variable hi nahi h jisme daalu!!", name)
 }
 
 // evaluates an if expression by evaluating the condition and then the consequence or alternative.
@@ -162,6 +193,7 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 
 	if isTruthy(condition) {
 		result := Eval(ie.Consequence, env)
+		enclosed(result, "agar", ie.Token, 0)
 		// Propagate break/continue through if statements
 		if result != nil && (result.Type() == "BREAK" || result.Type() == "CONTINUE") {
 			return result
@@ -178,6 +210,7 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 
 		if isTruthy(condition) {
 			result := Eval(elseIf.Consequence, env)
+			enclosed(result, "agar", ie.Token, 0)
 			// Propagate break/continue through else-if statements
 			if result != nil && (result.Type() == "BREAK" || result.Type() == "CONTINUE") {
 				return result
@@ -188,6 +221,7 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 
 	if ie.Alternative != nil {
 		result := Eval(ie.Alternative, env)
+		enclosed(result, "agar", ie.Token, 0)
 		// Propagate break/continue through else statements
 		if result != nil && (result.Type() == "BREAK" || result.Type() == "CONTINUE") {
 			return result
@@ -198,12 +232,31 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	return NULL
 }
 
+// evalBlockScope runs a block in an environment of its own, so what it
+// declares ends with it, and passes what it printed on to env. With legacy
+// scoping the block runs in env itself.
+func evalBlockScope(block *ast.BlockStatement, env *object.Environment) object.Object {
+	if env.LegacyScoping {
+		return evalBlockStatement(block, env)
+	}
+	blockEnv := object.NewEnclosedEnvironment(env)
+	result := evalBlockStatement(block, blockEnv)
+	env.OutputBuilder.WriteString(blockEnv.OutputBuilder.String())
+	return result
+}
+
 // evalBlockStatement evaluates a block of statements.
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	// newEnv := object.NewEnvironment() // Create a new environment for block
 	var result object.Object
 	for _, stmt := range block.Statements {
+		if err := beforeStatement(stmt, env); err != nil {
+			return err
+		}
 		result = Eval(stmt, env)
+		if err, ok := result.(*object.Error); ok {
+			locateError(err, stmt, env)
+		}
 		// if result != nil {
 		// 	// Add output to environment's OutputBuilder
 		// 	if result.Type() != object.ERROR_OBJ {
@@ -281,8 +334,8 @@ func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Ob
 
 	for {
 		iterations++
-		if iterations > maxIterations {
-			return newError("Mere server ka bill tera BAAP bharega ? Itni badi loop chala raha h!!")
+		if err := checkLoopLimit(iterations); err != nil {
+			return err
 		}
 
 		if fe.Condition != nil {
@@ -295,12 +348,7 @@ func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Ob
 			}
 		}
 
-		result = Eval(fe.Body, loopEnv)
-
-		// Append loopEnv's output to env's output after each iteration
-		env.OutputBuilder.WriteString(loopEnv.OutputBuilder.String())
-		loopEnv.OutputBuilder.Reset()
-
+		result = evalLoopBody(fe.Body, "chal_bhai", fe.Token, iterations, loopEnv, env)
 		if isError(result) {
 			return result
 		}
@@ -338,8 +386,8 @@ func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) objec
 
 	for {
 		iterations++
-		if iterations > maxIterations {
-			return newError("Mere server ka bill tera BAAP bharega ? Itni badi loop chala raha h!!")
+		if err := checkLoopLimit(iterations); err != nil {
+			return err
 		}
 
 		condition := Eval(we.Condition, loopEnv)
@@ -351,12 +399,7 @@ func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) objec
 			break
 		}
 
-		result = Eval(we.Body, loopEnv)
-
-		// Append loopEnv's output to env's output after each iteration
-		env.OutputBuilder.WriteString(loopEnv.OutputBuilder.String())
-		loopEnv.OutputBuilder.Reset()
-
+		result = evalLoopBody(we.Body, "jaha_tak", we.Token, iterations, loopEnv, env)
 		if isError(result) {
 			return result
 		}
@@ -373,6 +416,204 @@ func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) objec
 	return result
 }
 
+// evaluate for-each expressions (loops over arrays, strings and ranges). The
+// number of passes is fixed when the loop starts, but array elements are read
+// as the loop reaches them, so a change to a later element is seen.
+func evalForEachExpression(fe *ast.ForEachExpression, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	length, item, err := iterate(fe.Iterable, loopEnv)
+	if err != nil {
+		return err
+	}
+
+	var result object.Object = NULL
+	for i := int64(0); i < length; i++ {
+		iterations := int(i) + 1
+		if err := checkLoopLimit(iterations); err != nil {
+			return err
+		}
+
+		if fe.Index != nil {
+			traceSet(fe.Index, fe.Index, loopEnv.Set(fe.Index.Value, &object.Integer{Value: i}), env)
+		}
+		traceSet(fe.Value, fe.Value, loopEnv.Set(fe.Value.Value, item(i)), env)
+
+		result = evalLoopBody(fe.Body, "chal_bhai", fe.Token, iterations, loopEnv, env)
+		if isError(result) {
+			return result
+		}
+
+		// Handle break; continue needs nothing more than moving on
+		if _, ok := result.(*object.BreakControl); ok {
+			return NULL
+		}
+	}
+
+	return result
+}
+
+// iterate evaluates what a for-each loop runs over and returns how many
+// passes it makes and the value for each pass.
+func iterate(exp ast.Expression, env *object.Environment) (int64, func(int64) object.Object, object.Object) {
+	if rng, ok := exp.(*ast.RangeExpression); ok {
+		start := Eval(rng.Start, env)
+		if isError(start) {
+			return 0, nil, start
+		}
+		end := Eval(rng.End, env)
+		if isError(end) {
+			return 0, nil, end
+		}
+		from, fok := start.(*object.Integer)
+		to, tok := end.(*object.Integer)
+		if !fok || !tok {
+			return 0, nil, newCodedError(object.CodeType, "Range ke dono sire integer hone chahiye bhai, %s..%s nahi chalega!!", start.Type(), end.Type())
+		}
+		var length int64
+		if to.Value > from.Value {
+			length = to.Value - from.Value
+			if length < 0 {
+				// The range is wider than an int64; the loop limit ends it long before.
+				length = math.MaxInt64
+			}
+		}
+		return length, func(i int64) object.Object { return &object.Integer{Value: from.Value + i} }, nil
+	}
+
+	value := Eval(exp, env)
+	if isError(value) {
+		return 0, nil, value
+	}
+	switch value := value.(type) {
+	case *object.Array:
+		return int64(len(value.Elements)), func(i int64) object.Object { return value.Elements[i] }, nil
+	case *object.String:
+		chars := []rune(value.Value)
+		return int64(len(chars)), func(i int64) object.Object { return &object.String{Value: string(chars[i])} }, nil
+	}
+	return 0, nil, newCodedError(object.CodeType, "chal_bhai me sirf array, string ya range pe chal sakte h, %s pe nahi!!", value.Type())
+}
+
+// checkLoopLimit stops a loop about to start its given pass if that is one
+// too many.
+func checkLoopLimit(iterations int) *object.Error {
+	if iterations > maxIterations {
+		return newCodedError(object.CodeLoopLimit, "Mere server ka bill tera BAAP bharega ? Itni badi loop chala raha h!!")
+	}
+	return nil
+}
+
+// evalLoopBody runs one pass of a loop body in loopEnv and hands what it
+// printed on to env. An error coming out of the body gets the loop, named by
+// kind, added to its stack.
+func evalLoopBody(body *ast.BlockStatement, kind string, tok token.Token, iterations int, loopEnv, env *object.Environment) object.Object {
+	traceIteration(tok, iterations, env)
+	result := Eval(body, loopEnv)
+
+	// Append loopEnv's output to env's output after each iteration
+	env.OutputBuilder.WriteString(loopEnv.OutputBuilder.String())
+	loopEnv.OutputBuilder.Reset()
+
+	if isError(result) {
+		enclosed(result, kind, tok, iterations)
+	}
+	return result
+}
+
+// -------Catching and raising errors-------
+
+// evalTryStatement runs the body and hands an error from it to pakad_bhai,
+// then runs aakhir_me whatever happened. An error, break or continue coming
+// out of aakhir_me replaces the outcome of the rest. Fatal errors are neither
+// caught nor delayed by aakhir_me.
+func evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
+	result := Eval(ts.Body, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Fatal {
+		if ts.Catch != nil {
+			result = evalCatchBlock(ts, err, env)
+			enclosed(result, "pakad_bhai", ts.Token, 0)
+		} else {
+			enclosed(result, "koshish_kar_bhai", ts.Token, 0)
+		}
+	}
+
+	if ts.Finally == nil {
+		return result
+	}
+	if err, ok := result.(*object.Error); ok && err.Fatal {
+		return result
+	}
+	finally := Eval(ts.Finally, env)
+	if finally != nil {
+		switch finally.Type() {
+		case object.ERROR_OBJ:
+			enclosed(finally, "aakhir_me", ts.Token, 0)
+			return finally
+		case "BREAK", "CONTINUE":
+			return finally
+		}
+	}
+	return result
+}
+
+// evalCatchBlock runs pakad_bhai in a scope of its own holding the caught
+// error, passing its output on to env like a loop does.
+func evalCatchBlock(ts *ast.TryStatement, err *object.Error, env *object.Environment) object.Object {
+	catchEnv := object.NewEnclosedEnvironment(env)
+	if ts.CatchName != nil {
+		catchEnv.Set(ts.CatchName.Value, &object.Exception{Error: err})
+	}
+	result := Eval(ts.Catch, catchEnv)
+	env.OutputBuilder.WriteString(catchEnv.OutputBuilder.String())
+	return result
+}
+
+// evalThrowStatement raises a string as a new error, or re-raises an error
+// caught by pakad_bhai with its message and code.
+func evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	value := Eval(ts.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	switch value := value.(type) {
+	case *object.String:
+		return newCodedError(object.CodeUser, "%s", value.Value)
+	case *object.Exception:
+		return newCodedError(value.Error.Code, "%s", value.Error.Message)
+	case nil:
+		return newCodedError(object.CodeType, "fek_bhai ko kuch to de fekne ke liye!!")
+	default:
+		return newCodedError(object.CodeType, "fek_bhai ko string ya pakda hua error chahiye, %s nahi!!", value.Type())
+	}
+}
+
+// -------Reading input-------
+
+// evalInput reads the next line of the environment's input. Lines that are
+// whole numbers become integers, anything else a string.
+func evalInput(node *ast.CallExpression, env *object.Environment) object.Object {
+	if len(node.Arguments) > 0 {
+		return newError("suna_bhai() ke andar kuch mat daal, bas sun!!")
+	}
+	if env.Input == nil {
+		return newCodedError(object.CodeInput, "Kaun sunayega bhai? Input to diya hi nahi!!")
+	}
+
+	line, err := env.Input.ReadString('\n')
+	if err != nil && line == "" {
+		return newCodedError(object.CodeInput, "Input khatam ho gaya bhai, aur kuch nahi hai sunane ko!!")
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	if value, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64); err == nil {
+		return &object.Integer{Value: value}
+	}
+	return &object.String{Value: line}
+}
+
 // -------About Arrays and Indexing-------
 
 // evaluates an array literal by evaluating each element.
@@ -391,7 +632,7 @@ func evalArrayLiteral(node *ast.ArrayLiteral, env *object.Environment) object.Ob
 		firstType := elements[0].Type()
 		for _, el := range elements[1:] {
 			if el.Type() != firstType {
-				return newError("Girgit mat ban, datatype mat badle array ke elements ka. %s ko %s se saath mix mat kar!!",
+				return newCodedError(object.CodeType, "Girgit mat ban, datatype mat badle array ke elements ka. %s ko %s se saath mix mat kar!!",
 					firstType, el.Type())
 			}
 		}
@@ -405,24 +646,230 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.EXCEPTION_OBJ:
+		return evalExceptionIndexExpression(left, index)
 	default:
-		return newError("Kya coder banega re tu!! Sabse basic data structure bhi nahi aata tujhe!!: %s", left.Type())
+		return newCodedError(object.CodeType, "Kya coder banega re tu!! Sabse basic data structure bhi nahi aata tujhe!!: %s", left.Type())
 	}
 }
 
 // evaluates an array index expression by checking if the index is within bounds.
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
+	i, err := elementIndex(index, len(arrayObject.Elements), "array")
+	if err != nil {
+		return err
+	}
+	return arrayObject.Elements[i]
+}
+
+// evaluates s[i], the character at i as a string of its own.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	chars := []rune(str.(*object.String).Value)
+	i, err := elementIndex(index, len(chars), "string")
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: string(chars[i])}
+}
+
+// elementIndex checks an index into an array or string of the given length.
+// Negative indexes count from the end, so -1 is the last element.
+func elementIndex(index object.Object, length int, what string) (int, *object.Error) {
 	idx, ok := index.(*object.Integer)
 	if !ok {
-		return newError("Beta tum se nahi ho payega, jao arrays padh ke aao striver sir se! Integer daal be,S %s", index.Type())
+		return 0, newCodedError(object.CodeType, "Beta tum se nahi ho payega, jao arrays padh ke aao striver sir se! Integer daal be,S %s", index.Type())
+	}
+
+	i := idx.Value
+	if i < 0 {
+		i += int64(length)
+	}
+	if i < 0 || i >= int64(length) {
+		return 0, newCodedError(object.CodeIndex, "Aukaat m rehle aukaat m, %d index pe kuch nahi hai! Bahar mat jaa %s se!!", idx.Value, what)
+	}
+	return int(i), nil
+}
+
+// evaluates arr[start:end] or s[start:end] into a new array or string holding
+// the elements from start up to, but not including, end.
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(se.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var length int
+	var what string
+	switch left := left.(type) {
+	case *object.Array:
+		length, what = len(left.Elements), "array"
+	case *object.String:
+		length, what = len([]rune(left.Value)), "string"
+	default:
+		return newCodedError(object.CodeType, "Kya coder banega re tu!! Sabse basic data structure bhi nahi aata tujhe!!: %s", left.Type())
+	}
+
+	start, err := sliceBound(se.Start, 0, length, what, env)
+	if err != nil {
+		return err
+	}
+	end, err := sliceBound(se.End, length, length, what, env)
+	if err != nil {
+		return err
+	}
+	if start > end {
+		return newCodedError(object.CodeIndex, "Ulta slice nahi banta bhai, %d se %d tak kuch nahi hai!!", start, end)
+	}
+
+	if str, ok := left.(*object.String); ok {
+		return &object.String{Value: string([]rune(str.Value)[start:end])}
+	}
+	elements := make([]object.Object, end-start)
+	copy(elements, left.(*object.Array).Elements[start:end])
+	return &object.Array{Elements: elements}
+}
+
+// sliceBound evaluates one bound of a slice, which may equal length.
+func sliceBound(exp ast.Expression, missing, length int, what string, env *object.Environment) (int, object.Object) {
+	if exp == nil {
+		return missing, nil
+	}
+	bound := Eval(exp, env)
+	if isError(bound) {
+		return 0, bound
+	}
+	idx, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, newCodedError(object.CodeType, "Beta tum se nahi ho payega, jao arrays padh ke aao striver sir se! Integer daal be,S %s", bound.Type())
+	}
+
+	i := idx.Value
+	if i < 0 {
+		i += int64(length)
+	}
+	if i < 0 || i > int64(length) {
+		return 0, newCodedError(object.CodeIndex, "Aukaat m rehle aukaat m, %d index pe kuch nahi hai! Bahar mat jaa %s se!!", idx.Value, what)
+	}
+	return int(i), nil
+}
+
+// evaluates arr[i] = value, replacing the element in place so every variable
+// holding the array sees the change.
+func evalIndexAssignStatement(stmt *ast.IndexAssignStatement, env *object.Environment) object.Object {
+	left := Eval(stmt.Target.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := Eval(stmt.Target.Index, env)
+	if isError(index) {
+		return index
+	}
+	val := Eval(stmt.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	array, ok := left.(*object.Array)
+	if !ok {
+		if left.Type() == object.STRING_OBJ {
+			return newCodedError(object.CodeType, "String pathar ki lakeer h bhai, uska ek character nahi badal sakte!!")
+		}
+		return newCodedError(object.CodeType, "Kya coder banega re tu!! Sabse basic data structure bhi nahi aata tujhe!!: %s", left.Type())
+	}
+	i, err := elementIndex(index, len(array.Elements), "array")
+	if err != nil {
+		return err
+	}
+	if err := checkElementType(array, val, i); err != nil {
+		return err
 	}
 
-	if idx.Value < 0 || idx.Value >= int64(len(arrayObject.Elements)) {
-		return newError("Aukaat m rehle aukaat m, %d index pe kuch nahi hai! Bahar mat jaa array se!!", idx.Value)
+	array.Elements[i] = val
+	if root := rootIdentifier(stmt.Target); root != nil {
+		if rootVal, ok := env.Get(root.Value); ok {
+			traceSet(stmt, root, rootVal, env)
+		}
 	}
+	return val
+}
 
-	return arrayObject.Elements[idx.Value]
+// evaluates jod_bhai(arr, values...), which adds the values to the end of
+// arr in place and returns it.
+func evalAppend(node *ast.CallExpression, env *object.Environment) object.Object {
+	if len(node.Arguments) < 2 {
+		return newError("jod_bhai(array, value) likh bhai, kisme kya jodna h wo bhi bata!!")
+	}
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newCodedError(object.CodeType, "jod_bhai pehle array maangta h, %s nahi!!", args[0].Type())
+	}
+	for _, val := range args[1:] {
+		if err := checkElementType(array, val, -1); err != nil {
+			return err
+		}
+		array.Elements = append(array.Elements, val)
+	}
+	return array
+}
+
+// checkElementType keeps arrays to one element type: it returns the error an
+// array literal would give if val sat next to the elements of array other
+// than the one at skip.
+func checkElementType(array *object.Array, val object.Object, skip int) *object.Error {
+	for i, el := range array.Elements {
+		if i == skip {
+			continue
+		}
+		if el.Type() != val.Type() {
+			return newCodedError(object.CodeType, "Girgit mat ban, datatype mat badle array ke elements ka. %s ko %s se saath mix mat kar!!",
+				el.Type(), val.Type())
+		}
+		// The elements already share a type, so one comparison is enough.
+		break
+	}
+	return nil
+}
+
+// rootIdentifier returns the variable an index assignment changes, such as
+// grid in grid[0][1] = 5, or nil if the array is not held in a variable.
+func rootIdentifier(ie *ast.IndexExpression) *ast.Identifier {
+	var exp ast.Expression = ie
+	for {
+		switch e := exp.(type) {
+		case *ast.IndexExpression:
+			exp = e.Left
+		case *ast.Identifier:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// evaluates galti["message"] or galti["code"] on an error caught by pakad_bhai.
+func evalExceptionIndexExpression(exception, index object.Object) object.Object {
+	err := exception.(*object.Exception).Error
+	key, ok := index.(*object.String)
+	if !ok {
+		return newCodedError(object.CodeType, "Error ke andar %s se kya dhundh raha h? \"message\" ya \"code\" likh!!", index.Type())
+	}
+
+	switch key.Value {
+	case "message":
+		return &object.String{Value: err.Message}
+	case "code":
+		return &object.String{Value: err.Code}
+	default:
+		return newCodedError(object.CodeIndex, "Error me %q naam ka kuch nahi hai, sirf \"message\" aur \"code\" hai!!", key.Value)
+	}
 }
 
 // evaluates a list of expressions.
@@ -446,13 +893,34 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return newError("nil identifier")
 	}
 
+	if depth, ok := env.Depths[node]; ok {
+		if val, ok := env.GetAt(depth, node.Value); ok {
+			return val
+		}
+	}
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
-	return newError("Abe hosh me rehle! %s kaha likha h tune bataiyo zara...", node.Value)
+	return newCodedError(object.CodeUndefined, "Abe hosh me rehle! %s kaha likha h tune bataiyo zara...", node.Value)
 }
 
-// -------Infix Expressions-------
+// -------Prefix and Infix Expressions-------
+
+// evaluates a prefix expression (-x or !x).
+func evalPrefixExpression(operator string, right object.Object) object.Object {
+	switch operator {
+	case "-":
+		integer, ok := right.(*object.Integer)
+		if !ok {
+			return newCodedError(object.CodeType, "Bete '-' aur %s ka sambandh nahi ban sakta!!", right.Type())
+		}
+		return &object.Integer{Value: -integer.Value}
+	case "!":
+		return &object.Boolean{Value: !isTruthy(right)}
+	default:
+		return newError("Ye konsa operator h!?!?: %s", operator)
+	}
+}
 
 // evaluates an infix expression by evaluating the left and right expressions.
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
@@ -460,7 +928,7 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	default:
-		return newError("Bete %s, '%s', aur %s ka sambandh nahi ban sakta!!", left.Type(), operator, right.Type())
+		return newCodedError(object.CodeType, "Bete %s, '%s', aur %s ka sambandh nahi ban sakta!!", left.Type(), operator, right.Type())
 	}
 }
 
@@ -476,9 +944,13 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return &object.Integer{Value: leftVal - rightVal}
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
-	case "%":
+	case "/", "%":
+		if rightVal == 0 {
+			return newCodedError(object.CodeDivide, "Zero se divide karega? Maths ki class bunk ki thi kya!!")
+		}
+		if operator == "/" {
+			return &object.Integer{Value: leftVal / rightVal}
+		}
 		return &object.Integer{Value: leftVal % rightVal}
 	case "<":
 		return &object.Boolean{Value: leftVal < rightVal}
@@ -499,6 +971,98 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 
 // -------Helper functions for error handling and truthiness-------
 
+// beforeStatement traces stmt and gives the environment's hook, if any, a
+// chance to observe or stop the program before stmt runs.
+func beforeStatement(stmt ast.Statement, env *object.Environment) *object.Error {
+	if env.Tracer != nil {
+		tok := ast.StartToken(stmt)
+		env.Tracer.Trace(object.TraceEvent{Kind: object.TraceStatement, Line: tok.Line, Column: tok.Column})
+	}
+	if env.Hook == nil {
+		return nil
+	}
+	if err := env.Hook.BeforeStatement(stmt, env); err != nil {
+		e := newError("%s", err)
+		e.Fatal = true
+		locateError(e, stmt, env)
+		return e
+	}
+	return nil
+}
+
+// locateError records stmt as the origin of err and traces it, unless a
+// statement nested inside stmt already did.
+func locateError(err *object.Error, stmt ast.Statement, env *object.Environment) {
+	if err.Line != 0 {
+		return
+	}
+	tok := ast.StartToken(stmt)
+	err.Line, err.Column = tok.Line, tok.Column
+	err.Stack = append(err.Stack, object.Frame{Kind: statementKind(stmt), Line: tok.Line, Column: tok.Column})
+	if env.Tracer != nil {
+		env.Tracer.Trace(object.TraceEvent{Kind: object.TraceError, Line: tok.Line, Column: tok.Column, Value: err})
+	}
+}
+
+// statementKind names a statement in an error's stack.
+func statementKind(stmt ast.Statement) string {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return "bhai_sun"
+	case *ast.AssignStatement, *ast.IndexAssignStatement:
+		return "assignment"
+	case *ast.PrintStatement:
+		return "bol_bhai"
+	case *ast.TryStatement:
+		return "koshish_kar_bhai"
+	case *ast.ThrowStatement:
+		return "fek_bhai"
+	case *ast.BreakStatement:
+		return "bas_kar_bhai"
+	case *ast.ContinueStatement:
+		return "aage_bhad_bhai"
+	case *ast.ExpressionStatement:
+		switch expr := stmt.Expression.(type) {
+		case *ast.IfExpression:
+			return "agar"
+		case *ast.ForExpression, *ast.ForEachExpression:
+			return "chal_bhai"
+		case *ast.WhileExpression:
+			return "jaha_tak"
+		case *ast.CallExpression:
+			return expr.Function.TokenLiteral()
+		}
+	}
+	return "expression"
+}
+
+// enclosed adds the construct at tok to the stack of result, if it is an
+// error coming out of that construct's body.
+func enclosed(result object.Object, kind string, tok token.Token, iteration int) {
+	if err, ok := result.(*object.Error); ok {
+		err.Stack = append(err.Stack, object.Frame{Kind: kind, Line: tok.Line, Column: tok.Column, Iteration: iteration})
+	}
+}
+
+// traceSet reports the value a bhai_sun, assignment or for-each loop variable
+// stored and returns it.
+func traceSet(node ast.Node, name *ast.Identifier, value object.Object, env *object.Environment) object.Object {
+	if env.Tracer == nil || name == nil || value == nil || isError(value) {
+		return value
+	}
+	tok := ast.StartToken(node)
+	env.Tracer.Trace(object.TraceEvent{Kind: object.TraceSet, Line: tok.Line, Column: tok.Column, Name: name.Value, Value: value})
+	return value
+}
+
+// traceIteration reports that the loop starting at tok runs its body for the
+// given time.
+func traceIteration(tok token.Token, iteration int, env *object.Environment) {
+	if env.Tracer != nil {
+		env.Tracer.Trace(object.TraceEvent{Kind: object.TraceIteration, Line: tok.Line, Column: tok.Column, Iteration: iteration})
+	}
+}
+
 func isError(obj object.Object) bool {
 	if obj == nil {
 		return false
@@ -507,7 +1071,12 @@ func isError(obj object.Object) bool {
 }
 
 func newError(format string, args ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, args...)}
+	return newCodedError(object.CodeRuntime, format, args...)
+}
+
+// newCodedError is newError for errors a program can tell apart once caught.
+func newCodedError(code, format string, args ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...), Code: code}
 }
 
 func isTruthy(obj object.Object) bool {
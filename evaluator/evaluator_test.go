@@ -1,8 +1,12 @@
 package evaluator
 
 import (
+	"bufio"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/ankush-web-eng/brolang/ast"
 	helper "github.com/ankush-web-eng/brolang/helpers"
 	"github.com/ankush-web-eng/brolang/lexer"
 	"github.com/ankush-web-eng/brolang/object"
@@ -129,3 +133,259 @@ func TestLoopControlStatements(t *testing.T) {
 		}
 	}
 }
+
+func TestInputExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected string
+	}{
+		{"bhai_sun n = suna_bhai();\nbol_bhai(n + 1);", "41\n", "42\n"},
+		{"bol_bhai(suna_bhai());\nbol_bhai(suna_bhai());", "bro\n 7 \n", "bro\n7\n"},
+		{"chal_bhai (bhai_sun i = 0; i < 2; i = i + 1) { bol_bhai(suna_bhai()); }", "a\nb", "a\nb\n"},
+		{"bol_bhai(suna_bhai());", "", "Input khatam ho gaya bhai, aur kuch nahi hai sunane ko!!"},
+	}
+
+	for i, tt := range tests {
+		env := object.NewEnvironment()
+		env.Input = bufio.NewReader(strings.NewReader(tt.stdin))
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		evaluated := Eval(program, env)
+
+		actual := env.OutputBuilder.String()
+		if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+			actual = evaluated.(*object.Error).Message
+		}
+		if actual != tt.expected {
+			t.Errorf("test %d - wrong output. expected=%q, got=%q", i, tt.expected, actual)
+		}
+	}
+
+	evaluated := testEval("suna_bhai();")
+	if err, ok := evaluated.(*object.Error); !ok || err.Message != "Kaun sunayega bhai? Input to diya hi nahi!!" {
+		t.Errorf("expected missing input error, got=%v", evaluated)
+	}
+}
+
+// recordingHook notes the line of every statement and stops at stopLine.
+type recordingHook struct {
+	lines    []int
+	stopLine int
+}
+
+func (h *recordingHook) BeforeStatement(stmt ast.Statement, env *object.Environment) error {
+	line := ast.StartToken(stmt).Line
+	h.lines = append(h.lines, line)
+	if line == h.stopLine {
+		return fmt.Errorf("ruk gaya line %d pe", line)
+	}
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	input := "bhai_sun x = 1;\nchal_bhai (bhai_sun i = 0; i < 2; i = i + 1) {\n    bol_bhai(i);\n}\nbol_bhai(x);\nbol_bhai(x);"
+	hook := &recordingHook{stopLine: 6}
+	env := object.NewEnvironment()
+	env.Hook = hook
+
+	evaluated := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+
+	if fmt.Sprint(hook.lines) != "[1 2 3 3 5 6]" {
+		t.Errorf("wrong statements seen. got=%v", hook.lines)
+	}
+	if err, ok := evaluated.(*object.Error); !ok || err.Message != "ruk gaya line 6 pe" {
+		t.Errorf("expected the hook's error, got=%v", evaluated)
+	}
+	if out := env.OutputBuilder.String(); out != "0\n1\n1\n" {
+		t.Errorf("wrong output. got=%q", out)
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"bol_bhai(1);\nbhai_sun y = x;", "[bhai_sun at 2:1]"},
+		{"chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {\n    agar (i == 2) {\n        bol_bhai(x);\n    }\n}",
+			"[bol_bhai at 3:9 agar at 2:5 chal_bhai at 1:1 (iteration 3)]"},
+		{"bhai_sun n = 0;\njaha_tak (n < 1) {\n    agar (jhuth) {\n    } nahi_to {\n        n = n + y;\n    }\n}",
+			"[assignment at 5:9 agar at 3:5 jaha_tak at 2:1 (iteration 1)]"},
+		// An error in the loop's own condition belongs to the loop statement.
+		{"chal_bhai (bhai_sun i = 0; i < z; i = i + 1) {\n    bol_bhai(i);\n}", "[chal_bhai at 1:1]"},
+	}
+
+	for i, tt := range tests {
+		evaluated := testEval(tt.input)
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("test %d - expected an error, got=%v", i, evaluated)
+			continue
+		}
+		if got := fmt.Sprint(err.Stack); got != tt.expected {
+			t.Errorf("test %d - wrong stack. expected=%s, got=%s", i, tt.expected, got)
+		}
+		if err.Line != err.Stack[0].Line || err.Column != err.Stack[0].Column {
+			t.Errorf("test %d - error at %d:%d, stack starts at %v", i, err.Line, err.Column, err.Stack[0])
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedCode string // the code of the error the program ends with, if any
+	}{
+		{"bhai_sun arr = [1, 2];\nkoshish_kar_bhai {\n    bol_bhai(arr[5]);\n} pakad_bhai (g) {\n    bol_bhai(g[\"code\"]);\n} aakhir_me {\n    bol_bhai(\"done\");\n}",
+			"index-out-of-range\ndone\n", ""},
+		{"koshish_kar_bhai {\n    fek_bhai \"nahi chalega\";\n} pakad_bhai (g) {\n    bol_bhai(g);\n    bol_bhai(g[\"code\"]);\n}",
+			"nahi chalega\nuser\n", ""},
+		// Throwing a caught error again keeps its code.
+		{"koshish_kar_bhai {\n    bol_bhai(x);\n} pakad_bhai (g) {\n    fek_bhai g;\n}", "", object.CodeUndefined},
+		// aakhir_me runs on the way out even when nothing catches the error.
+		{"koshish_kar_bhai {\n    fek_bhai \"bahar\";\n} aakhir_me {\n    bol_bhai(\"last\");\n}", "last\n", object.CodeUser},
+		{"koshish_kar_bhai {\n    bol_bhai(1 / 0);\n} pakad_bhai (g) {\n    bol_bhai(g[\"code\"]);\n}", "division-by-zero\n", ""},
+		{"bhai_sun n = 0;\nbol_bhai(7 % n);", "", object.CodeDivide},
+		{"koshish_kar_bhai {\n    fek_bhai 5;\n} pakad_bhai {\n    bol_bhai(\"pakda\");\n}", "pakda\n", ""},
+		{"chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {\n    koshish_kar_bhai {\n        bol_bhai(i);\n        fek_bhai \"ruk\";\n    } pakad_bhai {\n        bas_kar_bhai;\n    }\n}",
+			"0\n", ""},
+	}
+
+	for i, tt := range tests {
+		env := object.NewEnvironment()
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		if out := env.OutputBuilder.String(); out != tt.expected {
+			t.Errorf("test %d - wrong output. expected=%q, got=%q", i, tt.expected, out)
+		}
+		code := ""
+		if err, ok := evaluated.(*object.Error); ok {
+			code = err.Code
+		}
+		if code != tt.expectedCode {
+			t.Errorf("test %d - wrong error code. expected=%q, got=%q (%v)", i, tt.expectedCode, code, evaluated)
+		}
+	}
+
+	// An error from the hook stops the program; pakad_bhai and aakhir_me do not run.
+	env := object.NewEnvironment()
+	env.Hook = &recordingHook{stopLine: 2}
+	input := "koshish_kar_bhai {\n    bol_bhai(1);\n} pakad_bhai {\n    bol_bhai(2);\n} aakhir_me {\n    bol_bhai(3);\n}"
+	evaluated := Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	if err, ok := evaluated.(*object.Error); !ok || !err.Fatal {
+		t.Errorf("expected a fatal error, got=%v", evaluated)
+	}
+	if out := env.OutputBuilder.String(); out != "" {
+		t.Errorf("wrong output after a fatal error. got=%q", out)
+	}
+}
+
+func TestArrayMutation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"bhai_sun arr = [1, 2, 3];\narr[0] = 10;\narr[-1] = arr[-1] + 5;\nbol_bhai(arr);", "[10, 2, 8]"},
+		{"bhai_sun grid = [[1, 2], [3, 4]];\ngrid[1][0] = 30;\nbol_bhai(grid);", "[[1, 2], [30, 4]]"},
+		// Arrays are shared, so a change through one name shows through the other.
+		{"bhai_sun a = [1];\nbhai_sun b = a;\njod_bhai(b, 2, 3);\nbol_bhai(a);", "[1, 2, 3]"},
+		{"bhai_sun e = [];\nbol_bhai(jod_bhai(e, \"x\"));", "[x]"},
+		{"bhai_sun arr = [1, 2, 3, 4];\nbol_bhai(arr[1:3]);\nbol_bhai(arr[:-1]);\nbol_bhai(arr[2:]);", "[2, 3]\n[1, 2, 3]\n[3, 4]"},
+		{"bhai_sun arr = [1, 2];\nbhai_sun copy = arr[:];\ncopy[0] = 9;\nbol_bhai(arr);", "[1, 2]"},
+		{"bhai_sun s = \"namaste\";\nbol_bhai(s[0]);\nbol_bhai(s[-3:]);", "n\nste"},
+		{"bhai_sun arr = [1, 2];\narr[2] = 3;", "Aukaat m rehle aukaat m, 2 index pe kuch nahi hai! Bahar mat jaa array se!!"},
+		{"bhai_sun arr = [1, 2];\nbol_bhai(arr[-3]);", "Aukaat m rehle aukaat m, -3 index pe kuch nahi hai! Bahar mat jaa array se!!"},
+		{"bhai_sun arr = [1, 2];\narr[0] = \"ek\";", "Girgit mat ban, datatype mat badle array ke elements ka. INTEGER ko STRING se saath mix mat kar!!"},
+		{"bhai_sun arr = [1];\njod_bhai(arr, sach);", "Girgit mat ban, datatype mat badle array ke elements ka. INTEGER ko BOOLEAN se saath mix mat kar!!"},
+		{"bhai_sun s = \"ab\";\ns[0] = \"c\";", "String pathar ki lakeer h bhai, uska ek character nahi badal sakte!!"},
+		{"bhai_sun arr = [1, 2, 3];\nbol_bhai(arr[2:1]);", "Ulta slice nahi banta bhai, 2 se 1 tak kuch nahi hai!!"},
+		{"jod_bhai(5, 1);", "jod_bhai pehle array maangta h, INTEGER nahi!!"},
+	}
+
+	for i, tt := range tests {
+		env := object.NewEnvironment()
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		actual := strings.TrimSuffix(env.OutputBuilder.String(), "\n")
+		if err, ok := evaluated.(*object.Error); ok {
+			actual = err.Message
+		}
+		if actual != tt.expected {
+			t.Errorf("test %d - wrong result. expected=%q, got=%q", i, tt.expected, actual)
+		}
+	}
+}
+
+func TestBlockScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		legacy   bool
+		expected string
+	}{
+		// Assignment updates the nearest existing variable, even from inside a loop.
+		{"bhai_sun total = 0;\nchal_bhai (bhai_sun i = 0; i < 4; i = i + 1) {\ntotal = total + i;\n}\nbol_bhai(total);", false, "6"},
+		{"bhai_sun x = 1;\nagar (sach) {\nbhai_sun x = x + 10;\nbol_bhai(x);\n}\nbol_bhai(x);", false, "11\n1"},
+		{"bhai_sun x = 1;\nagar (sach) {\nx = 2;\n}\nbol_bhai(x);", false, "2"},
+		{"agar (sach) {\nbhai_sun andar = 1;\n}\nbol_bhai(andar);", false, "Abe hosh me rehle! andar kaha likha h tune bataiyo zara..."},
+		{"naya = 5;", false, "Pehle bhai_sun to likh! naya naam ka koi variable hi nahi h jisme daalu!!"},
+		// Each pass of a loop body starts with a fresh scope.
+		{"bhai_sun seen = 0;\nchal_bhai (bhai_sun n me 0..3) {\nbhai_sun count = 1;\nseen = seen + count;\n}\nbol_bhai(seen);", false, "3"},
+		{"koshish_kar_bhai {\nbhai_sun a = 1;\nfek_bhai \"x\";\n} pakad_bhai (g) {\nbhai_sun g = 2;\nbol_bhai(g);\n}\nbol_bhai(a);", false, "2\nAbe hosh me rehle! a kaha likha h tune bataiyo zara..."},
+		// Legacy scoping shares agar bodies with the enclosing scope and lets assignment declare.
+		{"agar (sach) {\nbhai_sun andar = 1;\n}\nbol_bhai(andar);", true, "1"},
+		{"naya = 5;\nbol_bhai(naya);", true, "5"},
+		{"bhai_sun total = 0;\njaha_tak (total < 3) {\ntotal = total + 1;\n}\nbol_bhai(total);", true, "3"},
+	}
+
+	for i, tt := range tests {
+		env := object.NewEnvironment()
+		env.LegacyScoping = tt.legacy
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		actual := strings.TrimSuffix(env.OutputBuilder.String(), "\n")
+		if err, ok := evaluated.(*object.Error); ok {
+			actual = strings.TrimPrefix(actual+"\n"+err.Message, "\n")
+		}
+		if actual != tt.expected {
+			t.Errorf("test %d - wrong result. expected=%q, got=%q", i, tt.expected, actual)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"chal_bhai (bhai_sun x me [1, 2, 3]) {\nbol_bhai(x);\n}", "1\n2\n3"},
+		{"chal_bhai (bhai_sun i, w me [\"ek\", \"do\"]) {\nbol_bhai(i);\nbol_bhai(w);\n}", "0\nek\n1\ndo"},
+		{"chal_bhai (bhai_sun c me \"héy\") {\nbol_bhai(c);\n}", "h\né\ny"},
+		{"chal_bhai (bhai_sun n me 2..5) {\nbol_bhai(n);\n}", "2\n3\n4"},
+		{"chal_bhai (bhai_sun n me 5..2) {\nbol_bhai(n);\n}", ""},
+		{"bhai_sun end = 3;\nchal_bhai (bhai_sun i, n me -1..end) {\nbol_bhai(i);\n}", "0\n1\n2\n3"},
+		{"chal_bhai (bhai_sun n me 0..10) {\nagar (n == 2) {\nbas_kar_bhai;\n}\nbol_bhai(n);\n}", "0\n1"},
+		// The number of passes is fixed when the loop starts, but elements are read as they are reached.
+		{"bhai_sun arr = [1, 2];\nchal_bhai (bhai_sun x me arr) {\njod_bhai(arr, 9);\narr[1] = 5;\nbol_bhai(x);\n}\nbol_bhai(arr);", "1\n5\n[1, 5, 9, 9]"},
+		{"chal_bhai (bhai_sun x me [1]) {\nbhai_sun y = x;\n}\nbol_bhai(x);", "Abe hosh me rehle! x kaha likha h tune bataiyo zara..."},
+		{"chal_bhai (bhai_sun n me 0..20000) {\n}", "Mere server ka bill tera BAAP bharega ? Itni badi loop chala raha h!!"},
+		// me is only a keyword in the loop header.
+		{"bhai_sun me = [4, 5];\nchal_bhai (bhai_sun me me me) {\nbol_bhai(me);\n}", "4\n5"},
+		{"chal_bhai (bhai_sun x me 5) {\n}", "chal_bhai me sirf array, string ya range pe chal sakte h, INTEGER pe nahi!!"},
+		{"chal_bhai (bhai_sun x me 1..\"das\") {\n}", "Range ke dono sire integer hone chahiye bhai, INTEGER..STRING nahi chalega!!"},
+	}
+
+	for i, tt := range tests {
+		env := object.NewEnvironment()
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		actual := strings.TrimSuffix(env.OutputBuilder.String(), "\n")
+		if err, ok := evaluated.(*object.Error); ok {
+			actual = err.Message
+		}
+		if actual != tt.expected {
+			t.Errorf("test %d - wrong result. expected=%q, got=%q", i, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/parser"
+)
+
+const indent = "    "
+
+// Source parses code and returns it in canonical layout. Code with syntax
+// errors is not formatted; the first error is returned instead.
+func Source(code string) (string, error) {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return "", errors.New(p.Diagnostics()[0].String())
+	}
+	return Program(program), nil
+}
+
+// Program prints a parsed program in canonical layout: one statement per
+// line, four-space indentation, spaces around operators and a semicolon
+// after every simple statement. A single blank line is kept wherever the
+// original source had one or more between two statements.
+func Program(program *ast.Program) string {
+	pr := &printer{}
+	pr.statements(program.Statements)
+	return pr.buf.String()
+}
+
+type printer struct {
+	buf   bytes.Buffer
+	depth int
+}
+
+func (pr *printer) write(s string) {
+	pr.buf.WriteString(s)
+}
+
+func (pr *printer) newline() {
+	pr.buf.WriteByte('\n')
+	pr.buf.WriteString(strings.Repeat(indent, pr.depth))
+}
+
+func (pr *printer) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		if i > 0 {
+			pr.newline()
+			if ast.StartToken(stmt).Line > lastLine(stmts[i-1])+1 {
+				// Avoid trailing spaces on the blank line.
+				pr.buf.Truncate(pr.buf.Len() - len(indent)*pr.depth)
+				pr.newline()
+			}
+		}
+		pr.statement(stmt)
+	}
+	if len(stmts) > 0 && pr.depth == 0 {
+		pr.write("\n")
+	}
+}
+
+func (pr *printer) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		pr.write("bhai_sun " + s.Name.Value)
+		if s.Type != nil {
+			pr.write(": " + s.Type.String())
+		}
+		pr.write(" = ")
+		pr.expression(s.Value)
+		pr.endSimple(s.Value)
+	case *ast.AssignStatement:
+		pr.write(s.Name.Value + " = ")
+		pr.expression(s.Value)
+		pr.endSimple(s.Value)
+	case *ast.IndexAssignStatement:
+		pr.expression(s.Target)
+		pr.write(" = ")
+		pr.expression(s.Value)
+		pr.endSimple(s.Value)
+	case *ast.PrintStatement:
+		pr.write("bol_bhai(")
+		pr.expression(s.Expression)
+		pr.write(");")
+	case *ast.BreakStatement:
+		pr.write("bas_kar_bhai;")
+	case *ast.ContinueStatement:
+		pr.write("aage_bhad_bhai;")
+	case *ast.ThrowStatement:
+		pr.write("fek_bhai ")
+		pr.expression(s.Value)
+		pr.write(";")
+	case *ast.TryStatement:
+		pr.write("koshish_kar_bhai ")
+		pr.block(s.Body)
+		if s.Catch != nil {
+			pr.write(" pakad_bhai ")
+			if s.CatchName != nil {
+				pr.write("(" + s.CatchName.Value + ") ")
+			}
+			pr.block(s.Catch)
+		}
+		if s.Finally != nil {
+			pr.write(" aakhir_me ")
+			pr.block(s.Finally)
+		}
+	case *ast.BlockStatement:
+		pr.block(s)
+	case *ast.ExpressionStatement:
+		if s.Expression == nil {
+			pr.write(";")
+			return
+		}
+		pr.expression(s.Expression)
+		pr.endSimple(s.Expression)
+	}
+}
+
+// endSimple terminates a statement with a semicolon unless it ends with a
+// block, which needs none.
+func (pr *printer) endSimple(value ast.Expression) {
+	switch value.(type) {
+	case *ast.IfExpression, *ast.WhileExpression, *ast.ForExpression, *ast.ForEachExpression:
+	default:
+		pr.write(";")
+	}
+}
+
+func (pr *printer) block(block *ast.BlockStatement) {
+	pr.write("{")
+	if block == nil || len(block.Statements) == 0 {
+		pr.write("}")
+		return
+	}
+	pr.depth++
+	pr.newline()
+	pr.statements(block.Statements)
+	pr.depth--
+	pr.newline()
+	pr.write("}")
+}
+
+// header prints a loop clause statement (chal_bhai init and update), which
+// takes no semicolon of its own.
+func (pr *printer) header(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		pr.write("bhai_sun " + s.Name.Value)
+		if s.Type != nil {
+			pr.write(": " + s.Type.String())
+		}
+		pr.write(" = ")
+		pr.expression(s.Value)
+	case *ast.AssignStatement:
+		pr.write(s.Name.Value + " = ")
+		pr.expression(s.Value)
+	case *ast.ExpressionStatement:
+		pr.expression(s.Expression)
+	case nil:
+	default:
+		pr.statement(stmt)
+	}
+}
+
+func (pr *printer) expression(exp ast.Expression) {
+	switch e := exp.(type) {
+	case *ast.IntegerLiteral:
+		pr.write(strconv.FormatInt(e.Value, 10))
+	case *ast.StringLiteral:
+		pr.write(`"` + e.Value + `"`)
+	case *ast.Boolean:
+		pr.write(e.Token.Literal)
+	case *ast.Identifier:
+		pr.write(e.Value)
+	case *ast.ArrayLiteral:
+		pr.write("[")
+		pr.list(e.Elements)
+		pr.write("]")
+	case *ast.IndexExpression:
+		pr.expression(e.Left)
+		pr.write("[")
+		pr.expression(e.Index)
+		pr.write("]")
+	case *ast.SliceExpression:
+		pr.expression(e.Left)
+		pr.write("[")
+		if e.Start != nil {
+			pr.expression(e.Start)
+		}
+		pr.write(":")
+		if e.End != nil {
+			pr.expression(e.End)
+		}
+		pr.write("]")
+	case *ast.PrefixExpression:
+		pr.write(e.Operator)
+		pr.expression(e.Right)
+	case *ast.InfixExpression:
+		pr.expression(e.Left)
+		pr.write(" " + e.Operator + " ")
+		pr.expression(e.Right)
+	case *ast.CallExpression:
+		pr.write(e.Function.TokenLiteral() + "(")
+		pr.list(e.Arguments)
+		pr.write(")")
+	case *ast.IfExpression:
+		pr.write("agar (")
+		pr.expression(e.Condition)
+		pr.write(") ")
+		pr.block(e.Consequence)
+		for _, elseIf := range e.ElseIf {
+			pr.write(" nahi_to_agar (")
+			pr.expression(elseIf.Condition)
+			pr.write(") ")
+			pr.block(elseIf.Consequence)
+		}
+		if e.Alternative != nil {
+			pr.write(" nahi_to ")
+			pr.block(e.Alternative)
+		}
+	case *ast.WhileExpression:
+		pr.write("jaha_tak (")
+		pr.expression(e.Condition)
+		pr.write(") ")
+		pr.block(e.Body)
+	case *ast.ForExpression:
+		pr.write("chal_bhai (")
+		pr.header(e.Init)
+		pr.write("; ")
+		pr.expression(e.Condition)
+		pr.write("; ")
+		pr.header(e.Update)
+		pr.write(") ")
+		pr.block(e.Body)
+	case *ast.ForEachExpression:
+		pr.write("chal_bhai (bhai_sun ")
+		if e.Index != nil {
+			pr.write(e.Index.Value + ", ")
+		}
+		pr.write(e.Value.Value + " me ")
+		pr.expression(e.Iterable)
+		pr.write(") ")
+		pr.block(e.Body)
+	case *ast.RangeExpression:
+		pr.expression(e.Start)
+		pr.write("..")
+		pr.expression(e.End)
+	}
+}
+
+func (pr *printer) list(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			pr.write(", ")
+		}
+		pr.expression(exp)
+	}
+}
+
+// lastLine estimates the line a statement ends on: the last line holding one
+// of its tokens, plus one for the closing brace of a trailing non-empty block.
+func lastLine(stmt ast.Statement) int {
+	last := 0
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if line := ast.StartToken(n).Line; line > last {
+			last = line
+		}
+		return true
+	})
+
+	var block *ast.BlockStatement
+	switch s := stmt.(type) {
+	case *ast.ExpressionStatement:
+		block = trailingBlock(s.Expression)
+	case *ast.LetStatement:
+		block = trailingBlock(s.Value)
+	case *ast.AssignStatement:
+		block = trailingBlock(s.Value)
+	case *ast.IndexAssignStatement:
+		block = trailingBlock(s.Value)
+	case *ast.TryStatement:
+		block = s.Finally
+		if block == nil {
+			block = s.Catch
+		}
+	}
+	if block != nil && len(block.Statements) > 0 {
+		last++
+	}
+	return last
+}
+
+// trailingBlock returns the block an expression ends with, if any.
+func trailingBlock(exp ast.Expression) *ast.BlockStatement {
+	switch e := exp.(type) {
+	case *ast.IfExpression:
+		if e.Alternative != nil {
+			return e.Alternative
+		}
+		if len(e.ElseIf) > 0 {
+			return e.ElseIf[len(e.ElseIf)-1].Consequence
+		}
+		return e.Consequence
+	case *ast.WhileExpression:
+		return e.Body
+	case *ast.ForExpression:
+		return e.Body
+	case *ast.ForEachExpression:
+		return e.Body
+	}
+	return nil
+}
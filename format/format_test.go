@@ -0,0 +1,174 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/evaluator"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/parser"
+)
+
+func TestSource(t *testing.T) {
+	input := `bhai_sun x:int=5
+bhai_sun  arr=[1,2,3];
+agar(x>3){bol_bhai("big");bhai_sun y = x*2;
+bol_bhai(y)} nahi_to_agar (x==3) {bol_bhai("three")}nahi_to{}
+
+
+chal_bhai(bhai_sun i=0;i<3;i=i+1){agar(i==1){aage_bhad_bhai;}
+bol_bhai(arr[i]);}
+bhai_sun n = suna_bhai()
+jaha_tak(n){n=n-1;bas_kar_bhai}`
+
+	expected := `bhai_sun x: int = 5;
+bhai_sun arr = [1, 2, 3];
+agar (x > 3) {
+    bol_bhai("big");
+    bhai_sun y = x * 2;
+    bol_bhai(y);
+} nahi_to_agar (x == 3) {
+    bol_bhai("three");
+} nahi_to {}
+
+chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {
+    agar (i == 1) {
+        aage_bhad_bhai;
+    }
+    bol_bhai(arr[i]);
+}
+bhai_sun n = suna_bhai();
+jaha_tak (n) {
+    n = n - 1;
+    bas_kar_bhai;
+}
+`
+
+	got, err := Source(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Fatalf("wrong output.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+
+	again, err := Source(got)
+	if err != nil || again != got {
+		t.Errorf("formatting is not idempotent.\nfirst=\n%s\nsecond=\n%s\nerr=%v", got, again, err)
+	}
+}
+
+func TestSourcePreservesBehaviour(t *testing.T) {
+	inputs := []string{
+		`bhai_sun total = 0; chal_bhai (bhai_sun i = 0; i < 4; i = i + 1) { total = total + i; bol_bhai(total); }`,
+		`bhai_sun size = agar (sach) { "big" } nahi_to { "small" }
+bol_bhai(size);`,
+		`bhai_sun x = 2 * 3 + 4; bol_bhai(x); bol_bhai([x, 1][0]);`,
+	}
+
+	for _, input := range inputs {
+		formatted, err := Source(input)
+		if err != nil {
+			t.Fatalf("%s - unexpected error: %v", input, err)
+		}
+		if before, after := run(input), run(formatted); before != after {
+			t.Errorf("output changed.\nbefore=%q\nafter=%q\nformatted=\n%s", before, after, formatted)
+		}
+	}
+}
+
+func TestSourceTryStatement(t *testing.T) {
+	input := `koshish_kar_bhai{bol_bhai([1][3])}pakad_bhai(galti){bol_bhai(galti["code"])}aakhir_me{fek_bhai "phir se"}
+koshish_kar_bhai {bol_bhai(1);} pakad_bhai {}
+bol_bhai(2)`
+
+	expected := `koshish_kar_bhai {
+    bol_bhai([1][3]);
+} pakad_bhai (galti) {
+    bol_bhai(galti["code"]);
+} aakhir_me {
+    fek_bhai "phir se";
+}
+koshish_kar_bhai {
+    bol_bhai(1);
+} pakad_bhai {}
+bol_bhai(2);
+`
+
+	got, err := Source(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Fatalf("wrong output.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+	if before, after := run(input), run(got); before != after {
+		t.Errorf("output changed.\nbefore=%q\nafter=%q", before, after)
+	}
+}
+
+func TestSourceArrayMutation(t *testing.T) {
+	input := `bhai_sun arr=[1,2,3]
+arr[ -1 ]=arr[0]+5
+jod_bhai(arr,4)
+bol_bhai(arr[1:]);bol_bhai(arr[:-2])`
+
+	expected := `bhai_sun arr = [1, 2, 3];
+arr[-1] = arr[0] + 5;
+jod_bhai(arr, 4);
+bol_bhai(arr[1:]);
+bol_bhai(arr[:-2]);
+`
+
+	got, err := Source(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Fatalf("wrong output.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+	if before, after := run(input), run(got); before != after {
+		t.Errorf("output changed.\nbefore=%q\nafter=%q", before, after)
+	}
+}
+
+func TestSourceForEach(t *testing.T) {
+	input := `chal_bhai(bhai_sun i,x me [4,5]){bol_bhai(i)
+bol_bhai(x)}
+chal_bhai (bhai_sun n me 0 .. 2) { bol_bhai(n); }`
+
+	expected := `chal_bhai (bhai_sun i, x me [4, 5]) {
+    bol_bhai(i);
+    bol_bhai(x);
+}
+chal_bhai (bhai_sun n me 0..2) {
+    bol_bhai(n);
+}
+`
+
+	got, err := Source(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Fatalf("wrong output.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+	if before, after := run(input), run(got); before != after {
+		t.Errorf("output changed.\nbefore=%q\nafter=%q", before, after)
+	}
+}
+
+func TestSourceSyntaxError(t *testing.T) {
+	if _, err := Source(`bol_bhai(5;`); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
+
+func run(code string) string {
+	env := object.NewEnvironment()
+	result := evaluator.Eval(parser.New(lexer.New(code)).ParseProgram(), env)
+	if result != nil && result.Type() == object.ERROR_OBJ {
+		return env.OutputBuilder.String() + result.Inspect()
+	}
+	return env.OutputBuilder.String()
+}
@@ -0,0 +1,54 @@
+package judge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadCases reads test cases from path, which is either a JSON file holding
+// an array of cases or a directory of NAME.out files holding the expected
+// output, each with an optional NAME.in holding the input.
+func LoadCases(path string) ([]Case, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var cases []Case
+		if err := json.Unmarshal(data, &cases); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return cases, nil
+	}
+
+	outs, err := filepath.Glob(filepath.Join(path, "*.out"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(outs)
+	cases := make([]Case, 0, len(outs))
+	for _, out := range outs {
+		expected, err := os.ReadFile(out)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(out), ".out")
+		stdin, err := os.ReadFile(filepath.Join(path, name+".in"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		cases = append(cases, Case{Name: name, Stdin: string(stdin), Expected: string(expected)})
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("%s: no .out files", path)
+	}
+	return cases, nil
+}
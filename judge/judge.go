@@ -0,0 +1,190 @@
+// Package judge runs a program against test cases, as an online judge does:
+// each case gives the program an input and says what it should print, and
+// every case gets a verdict.
+//
+// Outputs are compared line by line, ignoring spaces at the ends of lines and
+// blank lines at the end of the output.
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/runner"
+	"github.com/ankush-web-eng/brolang/sandbox"
+)
+
+// Verdicts.
+const (
+	Accepted     = "accepted"
+	WrongAnswer  = "wrong-answer"
+	CompileError = "compile-error" // the program did not parse or type check
+	RuntimeError = "runtime-error"
+	TimeLimit    = "time-limit-exceeded"
+	StepLimit    = "step-limit-exceeded" // a loop ran more times than the evaluator allows
+)
+
+// Case is one test of a program.
+type Case struct {
+	Name     string `json:"name,omitempty"`
+	Stdin    string `json:"stdin,omitempty"`
+	Expected string `json:"expected"` // what the program should print
+}
+
+// Result is the verdict on one case.
+type Result struct {
+	Name    string `json:"name,omitempty"`
+	Verdict string `json:"verdict"`
+	Output  string `json:"output"`
+	Diff    string `json:"diff,omitempty"`  // how the output differs from the expected one, for a wrong answer
+	Error   string `json:"error,omitempty"` // the error that stopped the program
+	Time    int64  `json:"timeNs"`          // nanoseconds the run took
+}
+
+// Report is the verdict on every case.
+type Report struct {
+	Cases  []Result `json:"cases"`
+	Passed int      `json:"passed"`
+	Total  int      `json:"total"`
+	Score  float64  `json:"score"` // the percentage of cases accepted
+}
+
+// RunFunc runs a program on one input. A *sandbox.CrashError counts against
+// the program; any other error means it could not be run at all.
+type RunFunc func(ctx context.Context, code, stdin string) (*runner.Result, error)
+
+// Run runs code once for every case, each in a fresh environment. It stops
+// early only if a run fails with an error that is not the program's fault,
+// or if the program does not compile, which is the verdict on every case.
+// A deadline on ctx bounds all the cases together: the one running when it
+// passes and those after it exceed the time limit.
+func Run(ctx context.Context, code string, cases []Case, run RunFunc) (*Report, error) {
+	report := &Report{Cases: make([]Result, 0, len(cases)), Total: len(cases)}
+	for i, c := range cases {
+		if outOfTime(ctx) {
+			for _, rest := range cases[i:] {
+				report.Cases = append(report.Cases, Result{Name: rest.Name, Verdict: TimeLimit, Error: errOutOfTime})
+			}
+			break
+		}
+
+		start := time.Now()
+		res, err := run(ctx, code, c.Stdin)
+		elapsed := time.Since(start)
+
+		var result Result
+		var crash *sandbox.CrashError
+		switch {
+		case (err != nil || res.Error != "") && outOfTime(ctx):
+			result = Result{Verdict: TimeLimit, Error: errOutOfTime}
+		case errors.As(err, &crash):
+			result = crashed(crash)
+		case err != nil:
+			return nil, err
+		default:
+			result = judge(res, c.Expected)
+		}
+		result.Name = c.Name
+		result.Time = elapsed.Nanoseconds()
+
+		if result.Verdict == CompileError {
+			// Every run would fail the same way.
+			for _, rest := range cases[i:] {
+				report.Cases = append(report.Cases, Result{Name: rest.Name, Verdict: CompileError, Error: result.Error})
+			}
+			break
+		}
+		report.Cases = append(report.Cases, result)
+		if result.Verdict == Accepted {
+			report.Passed++
+		}
+	}
+	if report.Total > 0 {
+		report.Score = float64(report.Passed) * 100 / float64(report.Total)
+	}
+	return report, nil
+}
+
+const errOutOfTime = "Saare test cases ka time khatam bhai!!"
+
+func outOfTime(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+func crashed(crash *sandbox.CrashError) Result {
+	switch crash.Reason {
+	case sandbox.ReasonTimeout, sandbox.ReasonCPULimit:
+		return Result{Verdict: TimeLimit, Error: crash.Error()}
+	}
+	return Result{Verdict: RuntimeError, Error: crash.Error()}
+}
+
+func judge(res *runner.Result, expected string) Result {
+	result := Result{Output: res.Output}
+	switch {
+	case len(res.Diagnostics) > 0:
+		result.Verdict = CompileError
+		result.Error = res.Error
+	case res.ErrorCode == object.CodeLoopLimit:
+		result.Verdict = StepLimit
+		result.Error = res.Error
+	case res.Error != "":
+		result.Verdict = RuntimeError
+		result.Error = res.Error
+	default:
+		if result.Diff = diff(expected, res.Output); result.Diff == "" {
+			result.Verdict = Accepted
+		} else {
+			result.Verdict = WrongAnswer
+		}
+	}
+	return result
+}
+
+// maxDiffLines is how many differing lines a diff shows.
+const maxDiffLines = 10
+
+// diff lists the lines where got differs from expected, or returns "" if it
+// does not.
+func diff(expected, got string) string {
+	want, have := lines(expected), lines(got)
+	var b strings.Builder
+	shown := 0
+	for i := 0; i < max(len(want), len(have)); i++ {
+		w, h := line(want, i), line(have, i)
+		if w == h {
+			continue
+		}
+		if shown == maxDiffLines {
+			b.WriteString("...\n")
+			break
+		}
+		fmt.Fprintf(&b, "line %d\n- %s\n+ %s\n", i+1, w, h)
+		shown++
+	}
+	return b.String()
+}
+
+// lines splits an output into lines without trailing spaces, dropping blank
+// lines at the end.
+func lines(output string) []string {
+	split := strings.Split(output, "\n")
+	for i := range split {
+		split[i] = strings.TrimRight(split[i], " \t\r")
+	}
+	for len(split) > 0 && split[len(split)-1] == "" {
+		split = split[:len(split)-1]
+	}
+	return split
+}
+
+func line(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return "(kuchh nahi)"
+}
@@ -0,0 +1,199 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/runner"
+	"github.com/ankush-web-eng/brolang/sandbox"
+)
+
+func runInProcess(ctx context.Context, code, stdin string) (*runner.Result, error) {
+	return runner.Run(code, runner.Options{Stdin: strings.NewReader(stdin)}), nil
+}
+
+func TestRun(t *testing.T) {
+	code := `bhai_sun n = suna_bhai();
+agar (n == 0) {
+    bhai_sun arr = [1];
+    bol_bhai(arr[5]);
+}
+agar (n < 0) {
+    jaha_tak (sach) {
+    }
+}
+bol_bhai(n * 2);`
+	cases := []Case{
+		{Name: "double", Stdin: "2\n", Expected: "4\n"},
+		{Name: "trailing space", Stdin: "3\n", Expected: "6  \n\n"},
+		{Name: "wrong", Stdin: "4\n", Expected: "9\n"},
+		{Name: "zero", Stdin: "0\n", Expected: "0\n"},
+		{Name: "forever", Stdin: "-1\n", Expected: "-2\n"},
+	}
+
+	report, err := Run(context.Background(), code, cases, runInProcess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{Accepted, Accepted, WrongAnswer, RuntimeError, StepLimit}
+	for i, verdict := range expected {
+		got := report.Cases[i]
+		if got.Verdict != verdict || got.Name != cases[i].Name {
+			t.Errorf("case %d - expected %s for %q, got=%s for %q (%s)", i, verdict, cases[i].Name, got.Verdict, got.Name, got.Error)
+		}
+	}
+	if diff := report.Cases[2].Diff; diff != "line 1\n- 9\n+ 8\n" {
+		t.Errorf("wrong diff. got=%q", diff)
+	}
+	if report.Passed != 2 || report.Total != 5 || report.Score != 40 {
+		t.Errorf("expected 2/5 passed and a score of 40, got=%d/%d and %v", report.Passed, report.Total, report.Score)
+	}
+}
+
+func TestRunCompileError(t *testing.T) {
+	cases := []Case{{Expected: "1\n"}, {Expected: "2\n"}}
+	runs := 0
+	run := func(ctx context.Context, code, stdin string) (*runner.Result, error) {
+		runs++
+		return runInProcess(ctx, code, stdin)
+	}
+
+	report, err := Run(context.Background(), "bhai_sun = ;", cases, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("expected a program that does not parse to run once, ran %d times", runs)
+	}
+	for i, c := range report.Cases {
+		if c.Verdict != CompileError || c.Error == "" {
+			t.Errorf("case %d - expected a compile error, got=%+v", i, c)
+		}
+	}
+	if report.Passed != 0 || report.Score != 0 {
+		t.Errorf("expected nothing passed, got=%d", report.Passed)
+	}
+}
+
+func TestRunCrash(t *testing.T) {
+	crashes := []*sandbox.CrashError{
+		{Reason: sandbox.ReasonTimeout},
+		{Reason: sandbox.ReasonMemoryLimit},
+	}
+	run := func(ctx context.Context, code, stdin string) (*runner.Result, error) {
+		crash := crashes[0]
+		crashes = crashes[1:]
+		return nil, crash
+	}
+
+	report, err := Run(context.Background(), "bol_bhai(1);", []Case{{}, {}}, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Cases[0].Verdict != TimeLimit || report.Cases[1].Verdict != RuntimeError {
+		t.Errorf("expected a time limit then a runtime error, got=%+v", report.Cases)
+	}
+
+	broken := errors.New("no workers")
+	_, err = Run(context.Background(), "bol_bhai(1);", []Case{{}}, func(context.Context, string, string) (*runner.Result, error) {
+		return nil, broken
+	})
+	if !errors.Is(err, broken) {
+		t.Errorf("expected the run error back, got=%v", err)
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	runs := 0
+	run := func(ctx context.Context, code, stdin string) (*runner.Result, error) {
+		runs++
+		if stdin == "fast" {
+			return &runner.Result{Output: "1\n"}, nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	report, err := Run(ctx, "bol_bhai(1);", []Case{{Stdin: "fast", Expected: "1\n"}, {Name: "slow"}, {Name: "later"}}, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verdicts := []string{report.Cases[0].Verdict, report.Cases[1].Verdict, report.Cases[2].Verdict}
+	if !reflect.DeepEqual(verdicts, []string{Accepted, TimeLimit, TimeLimit}) || report.Cases[2].Name != "later" {
+		t.Errorf("expected the cases from the slow one on to exceed the time limit, got=%+v", report.Cases)
+	}
+	if runs != 2 {
+		t.Errorf("expected no run after the deadline, got %d runs", runs)
+	}
+
+	// A cancelled context is not the program's fault.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, "bol_bhai(1);", []Case{{Name: "slow"}}, run); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got=%v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		expected, got string
+		diff          string
+	}{
+		{"1\n2\n", "1\n2", ""},
+		{"1\n2\n", "1\n", "line 2\n- 2\n+ (kuchh nahi)\n"},
+		{"a\n", "a\nb\n", "line 2\n- (kuchh nahi)\n+ b\n"},
+	}
+
+	for _, tt := range tests {
+		if got := diff(tt.expected, tt.got); got != tt.diff {
+			t.Errorf("diff(%q, %q) - expected %q, got=%q", tt.expected, tt.got, tt.diff, got)
+		}
+	}
+}
+
+func TestLoadCases(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"1.in":  "2\n",
+		"1.out": "4\n",
+		"2.out": "0\n",
+		"notes": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases, err := LoadCases(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Case{{Name: "1", Stdin: "2\n", Expected: "4\n"}, {Name: "2", Expected: "0\n"}}
+	if !reflect.DeepEqual(cases, expected) {
+		t.Errorf("expected %+v, got=%+v", expected, cases)
+	}
+
+	file := filepath.Join(dir, "cases.json")
+	os.WriteFile(file, []byte(`[{"name": "ek", "stdin": "1\n", "expected": "2\n"}]`), 0o644)
+	cases, err = LoadCases(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 1 || cases[0] != (Case{Name: "ek", Stdin: "1\n", Expected: "2\n"}) {
+		t.Errorf("wrong cases from JSON: %+v", cases)
+	}
+
+	if _, err := LoadCases(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory with no cases")
+	}
+}
@@ -46,3 +46,37 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `bhai_sun x = 5;
+  bol_bhai(x);`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"bhai_sun", 1, 1},
+		{"x", 1, 10},
+		{"=", 1, 12},
+		{"5", 1, 14},
+		{";", 1, 15},
+		{"bol_bhai", 2, 3},
+		{"(", 2, 11},
+		{"x", 2, 12},
+		{")", 2, 13},
+		{";", 2, 14},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
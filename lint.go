@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/lint"
+)
+
+// lintCommand implements `brolang lint [file...]`. With no files it reads the
+// program from stdin. It exits with status 1 if anything was reported.
+func lintCommand(args []string) int {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	status := 0
+	for _, name := range args {
+		code, err := readSource(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brolang lint: %v\n", err)
+			status = 1
+			continue
+		}
+
+		for _, d := range lint.Source(code) {
+			fmt.Printf("%s:%s\n", name, d)
+			status = 1
+		}
+	}
+	return status
+}
+
+// readSource reads a program from the named file, or from stdin for "-".
+func readSource(name string) (string, error) {
+	if name == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(name)
+	return string(data), err
+}
@@ -0,0 +1,89 @@
+package lint
+
+import (
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/object"
+)
+
+// literalType returns the runtime type a literal expression will evaluate to.
+func literalType(exp ast.Expression) (object.ObjectType, bool) {
+	switch exp.(type) {
+	case *ast.IntegerLiteral:
+		return object.INTEGER_OBJ, true
+	case *ast.StringLiteral:
+		return object.STRING_OBJ, true
+	case *ast.Boolean:
+		return object.BOOLEAN_OBJ, true
+	case *ast.ArrayLiteral:
+		return object.ARRAY_OBJ, true
+	}
+	return "", false
+}
+
+// constantTruth reports the truthiness of a condition whose value does not
+// depend on any variable, following the evaluator's isTruthy rules.
+func constantTruth(exp ast.Expression) (bool, bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.IntegerLiteral:
+		return exp.Value != 0, true
+	case *ast.StringLiteral, *ast.ArrayLiteral:
+		return true, true
+	case *ast.InfixExpression:
+		left, lok := constantInteger(exp.Left)
+		right, rok := constantInteger(exp.Right)
+		if !lok || !rok {
+			return false, false
+		}
+		switch exp.Operator {
+		case "<":
+			return left < right, true
+		case ">":
+			return left > right, true
+		case "==":
+			return left == right, true
+		case "!=":
+			return left != right, true
+		case "<=":
+			return left <= right, true
+		case ">=":
+			return left >= right, true
+		}
+		if value, ok := constantInteger(exp); ok {
+			return value != 0, true
+		}
+	}
+	return false, false
+}
+
+// constantInteger evaluates integer arithmetic made only of literals.
+func constantInteger(exp ast.Expression) (int64, bool) {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return exp.Value, true
+	case *ast.InfixExpression:
+		left, lok := constantInteger(exp.Left)
+		right, rok := constantInteger(exp.Right)
+		if !lok || !rok {
+			return 0, false
+		}
+		switch exp.Operator {
+		case "+":
+			return left + right, true
+		case "-":
+			return left - right, true
+		case "*":
+			return left * right, true
+		case "/":
+			if right != 0 {
+				return left / right, true
+			}
+		case "%":
+			if right != 0 {
+				return left % right, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,291 @@
+package lint
+
+import (
+	"sort"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+// Diagnostic codes reported by the linter.
+const (
+	UndefinedIdentifier  = "undefined-identifier"
+	UnusedVariable       = "unused-variable"
+	UndeclaredAssignment = "undeclared-assignment"
+	UnreachableCode      = "unreachable-code"
+	ConstantCondition    = "constant-condition"
+	MixedArray           = "mixed-array"
+)
+
+// binding is a variable declared in a scope, remembered until the scope ends
+// so we can tell whether it was ever read.
+type binding struct {
+	name *ast.Identifier
+	used bool
+}
+
+// scope mirrors object.Environment: every block, loop and pakad_bhai opens a
+// new one.
+type scope struct {
+	vars  map[string]*binding
+	order []*binding
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: make(map[string]*binding), outer: outer}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for cur := s; cur != nil; cur = cur.outer {
+		if b, ok := cur.vars[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) declare(name *ast.Identifier) *binding {
+	b := &binding{name: name}
+	s.vars[name.Value] = b
+	s.order = append(s.order, b)
+	return b
+}
+
+type linter struct {
+	scope *scope
+	diags []diagnostic.Diagnostic
+}
+
+// Lint checks a parsed program without running it and returns the problems
+// it finds, ordered by position.
+func Lint(program *ast.Program) []diagnostic.Diagnostic {
+	l := &linter{scope: newScope(nil)}
+	ast.Walk(l, program)
+	l.closeScope()
+
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		if l.diags[i].Line != l.diags[j].Line {
+			return l.diags[i].Line < l.diags[j].Line
+		}
+		return l.diags[i].Column < l.diags[j].Column
+	})
+	return l.diags
+}
+
+// Source parses and lints the given code. Syntax errors are returned on their
+// own, since linting a half-parsed program only produces noise.
+func Source(code string) []diagnostic.Diagnostic {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) > 0 {
+		return p.Diagnostics()
+	}
+	return Lint(program)
+}
+
+// Visit implements ast.Visitor. Nodes that bind names are walked by hand so
+// that a value is always checked before the name it initialises is declared.
+func (l *linter) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.Program:
+		l.checkUnreachable(n.Statements)
+
+	case *ast.BlockStatement:
+		l.checkUnreachable(n.Statements)
+		l.openScope()
+		for _, stmt := range n.Statements {
+			l.walk(stmt)
+		}
+		l.closeScope()
+		return nil
+
+	case *ast.LetStatement:
+		l.walk(n.Value)
+		if n.Name != nil {
+			l.scope.declare(n.Name)
+		}
+		return nil
+
+	case *ast.AssignStatement:
+		l.walk(n.Value)
+		if n.Name == nil {
+			return nil
+		}
+		if _, ok := l.scope.lookup(n.Name.Value); !ok {
+			l.report(n.Name.Token, diagnostic.Error, UndeclaredAssignment,
+				"%s is assigned before any bhai_sun declares it", n.Name.Value)
+		}
+		return nil
+
+	case *ast.Identifier:
+		if b, ok := l.scope.lookup(n.Value); ok {
+			b.used = true
+		} else {
+			l.report(n.Token, diagnostic.Error, UndefinedIdentifier, "%s is not defined", n.Value)
+		}
+
+	case *ast.CallExpression:
+		// The callee is a builtin name, not a variable.
+		for _, arg := range n.Arguments {
+			l.walk(arg)
+		}
+		return nil
+
+	case *ast.ArrayLiteral:
+		l.checkArrayLiteral(n)
+
+	case *ast.WhileExpression:
+		l.checkLoopCondition(n.Token.Literal, n.Condition)
+		l.openScope()
+		l.walk(n.Condition)
+		l.walk(n.Body)
+		l.closeScope()
+		return nil
+
+	case *ast.ForExpression:
+		l.checkLoopCondition(n.Token.Literal, n.Condition)
+		l.openScope()
+		l.walk(n.Init)
+		l.walk(n.Condition)
+		l.walk(n.Body)
+		l.walk(n.Update)
+		l.closeScope()
+		return nil
+
+	case *ast.ForEachExpression:
+		l.checkEmptyRange(n.Iterable)
+		l.openScope()
+		l.walk(n.Iterable)
+		// Loop variables have to be named even when only one of them is
+		// needed, so like the pakad_bhai name they are never reported as unused.
+		if n.Index != nil {
+			l.scope.declare(n.Index).used = true
+		}
+		if n.Value != nil {
+			l.scope.declare(n.Value).used = true
+		}
+		l.walk(n.Body)
+		l.closeScope()
+		return nil
+
+	case *ast.TryStatement:
+		l.walk(n.Body)
+		if n.Catch != nil {
+			// The caught error lives in a scope of its own; naming it without
+			// reading it is harmless, so it is never reported as unused.
+			l.openScope()
+			if n.CatchName != nil {
+				l.scope.declare(n.CatchName).used = true
+			}
+			l.walk(n.Catch)
+			l.closeScope()
+		}
+		l.walk(n.Finally)
+		return nil
+	}
+
+	return l
+}
+
+func (l *linter) walk(node ast.Node) {
+	if node != nil {
+		ast.Walk(l, node)
+	}
+}
+
+func (l *linter) openScope() {
+	l.scope = newScope(l.scope)
+}
+
+// closeScope reports the variables of the innermost scope that were never read.
+func (l *linter) closeScope() {
+	for _, b := range l.scope.order {
+		if !b.used {
+			l.report(b.name.Token, diagnostic.Warning, UnusedVariable,
+				"%s is declared with bhai_sun but never used", b.name.Value)
+		}
+	}
+	l.scope = l.scope.outer
+}
+
+// checkUnreachable flags the first statement following a break, continue or
+// fek_bhai in the same block.
+func (l *linter) checkUnreachable(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		switch stmt.(type) {
+		case *ast.BreakStatement, *ast.ContinueStatement, *ast.ThrowStatement:
+		default:
+			continue
+		}
+
+		for _, next := range stmts[i+1:] {
+			if es, ok := next.(*ast.ExpressionStatement); ok && es.Expression == nil {
+				continue // stray semicolon
+			}
+			l.report(ast.StartToken(next), diagnostic.Warning, UnreachableCode,
+				"code after %s will never run", stmt.TokenLiteral())
+			return
+		}
+		return
+	}
+}
+
+func (l *linter) checkLoopCondition(keyword string, cond ast.Expression) {
+	if cond == nil {
+		return
+	}
+	value, ok := constantTruth(cond)
+	if !ok {
+		return
+	}
+	if value {
+		l.report(ast.StartToken(cond), diagnostic.Warning, ConstantCondition,
+			"%s condition is always true; the loop only stops at bas_kar_bhai or the iteration limit", keyword)
+	} else {
+		l.report(ast.StartToken(cond), diagnostic.Warning, ConstantCondition,
+			"%s condition is always false; the loop body never runs", keyword)
+	}
+}
+
+// checkEmptyRange flags a for-each range between two literals that holds no
+// numbers at all.
+func (l *linter) checkEmptyRange(iterable ast.Expression) {
+	rng, ok := iterable.(*ast.RangeExpression)
+	if !ok {
+		return
+	}
+	start, sok := rng.Start.(*ast.IntegerLiteral)
+	end, eok := rng.End.(*ast.IntegerLiteral)
+	if sok && eok && start.Value >= end.Value {
+		l.report(ast.StartToken(rng), diagnostic.Warning, ConstantCondition,
+			"range %d..%d is empty; the loop body never runs", start.Value, end.Value)
+	}
+}
+
+func (l *linter) checkArrayLiteral(al *ast.ArrayLiteral) {
+	var first object.ObjectType
+	for _, el := range al.Elements {
+		t, ok := literalType(el)
+		if !ok {
+			continue
+		}
+		if first == "" {
+			first = t
+			continue
+		}
+		if t != first {
+			l.report(ast.StartToken(el), diagnostic.Error, MixedArray,
+				"array mixes %s and %s elements", first, t)
+			return
+		}
+	}
+}
+
+func (l *linter) report(tok token.Token, severity diagnostic.Severity, code, format string, args ...interface{}) {
+	l.diags = append(l.diags, diagnostic.New(tok, severity, code, format, args...))
+}
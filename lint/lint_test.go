@@ -0,0 +1,146 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/diagnostic"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []diagnostic.Diagnostic
+	}{
+		{
+			`bhai_sun x = 5;
+bol_bhai(x);`,
+			nil,
+		},
+		{
+			`bol_bhai(y);`,
+			[]diagnostic.Diagnostic{
+				{Line: 1, Column: 10, Severity: diagnostic.Error, Code: UndefinedIdentifier},
+			},
+		},
+		{
+			`bhai_sun x = 5;
+bhai_sun y = x;`,
+			[]diagnostic.Diagnostic{
+				{Line: 2, Column: 10, Severity: diagnostic.Warning, Code: UnusedVariable},
+			},
+		},
+		{
+			`x = 5;
+bol_bhai(x);`,
+			[]diagnostic.Diagnostic{
+				{Line: 1, Column: 1, Severity: diagnostic.Error, Code: UndeclaredAssignment},
+				{Line: 2, Column: 10, Severity: diagnostic.Error, Code: UndefinedIdentifier},
+			},
+		},
+		{
+			// Every block is a scope: the inner x shadows the outer one and
+			// ends with the agar body, taking y with it.
+			`bhai_sun x = 5;
+agar (x > 1) {
+    bhai_sun x = "paanch";
+    bhai_sun y = x;
+    x = y;
+}
+bol_bhai(x);
+bol_bhai(y);`,
+			[]diagnostic.Diagnostic{
+				{Line: 8, Column: 10, Severity: diagnostic.Error, Code: UndefinedIdentifier},
+			},
+		},
+		{
+			`bhai_sun x = 0;
+jaha_tak (x < 5) {
+    x = x + 1;
+    bas_kar_bhai;
+    bol_bhai(x);
+}`,
+			[]diagnostic.Diagnostic{
+				{Line: 5, Column: 5, Severity: diagnostic.Warning, Code: UnreachableCode},
+			},
+		},
+		{
+			`jaha_tak (sach) {
+    bas_kar_bhai;
+}
+chal_bhai (bhai_sun i = 0; 1 > 2; i = i + 1) {
+    bol_bhai(i);
+}`,
+			[]diagnostic.Diagnostic{
+				{Line: 1, Column: 11, Severity: diagnostic.Warning, Code: ConstantCondition},
+				{Line: 4, Column: 28, Severity: diagnostic.Warning, Code: ConstantCondition},
+			},
+		},
+		{
+			`bhai_sun arr = [1, "two", 3];
+bol_bhai(arr);`,
+			[]diagnostic.Diagnostic{
+				{Line: 1, Column: 20, Severity: diagnostic.Error, Code: MixedArray},
+			},
+		},
+		{
+			// Loop scopes end with the loop, so a variable declared inside is not visible after it.
+			`chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {
+    bhai_sun sq = i * i;
+    bol_bhai(sq);
+}
+bol_bhai(sq);`,
+			[]diagnostic.Diagnostic{
+				{Line: 5, Column: 10, Severity: diagnostic.Error, Code: UndefinedIdentifier},
+			},
+		},
+		{
+			`koshish_kar_bhai {
+    fek_bhai "ruk";
+    bol_bhai(1);
+} pakad_bhai (g) {
+    bol_bhai(g);
+}`,
+			[]diagnostic.Diagnostic{
+				{Line: 3, Column: 5, Severity: diagnostic.Warning, Code: UnreachableCode},
+			},
+		},
+		{
+			// Loop variables are not reported as unused and end with the loop.
+			`chal_bhai (bhai_sun i, x me [1, 2]) {
+    bol_bhai(1);
+}
+chal_bhai (bhai_sun n me 5..2) {
+    bol_bhai(n);
+}
+bol_bhai(x);`,
+			[]diagnostic.Diagnostic{
+				{Line: 4, Column: 26, Severity: diagnostic.Warning, Code: ConstantCondition},
+				{Line: 7, Column: 10, Severity: diagnostic.Error, Code: UndefinedIdentifier},
+			},
+		},
+		{
+			`bol_bhai(5;`,
+			[]diagnostic.Diagnostic{
+				{Line: 1, Column: 11, Severity: diagnostic.Error, Code: "syntax"},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		diags := Source(tt.input)
+		if len(diags) != len(tt.expected) {
+			t.Errorf("test %d - wrong number of diagnostics. expected=%d, got=%d (%v)",
+				i, len(tt.expected), len(diags), diags)
+			continue
+		}
+
+		for j, want := range tt.expected {
+			got := diags[j]
+			if got.Line != want.Line || got.Column != want.Column ||
+				got.Severity != want.Severity || got.Code != want.Code {
+				t.Errorf("test %d - diagnostic %d wrong. expected=%d:%d %s %s, got=%s",
+					i, j, want.Line, want.Column, want.Severity, want.Code, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/lsp"
+)
+
+// lspCommand implements `brolang lsp`, a language server speaking LSP over
+// stdin and stdout for editors.
+func lspCommand(args []string) int {
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "brolang lsp: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,194 @@
+package lsp
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/lint"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/resolve"
+	"github.com/ankush-web-eng/brolang/typecheck"
+)
+
+// document is an open file together with everything the server derived from it.
+type document struct {
+	uri   string
+	text  string
+	lines []string
+
+	program     *ast.Program
+	diagnostics []diagnostic.Diagnostic
+
+	// Only set when the document parses without errors.
+	info *typecheck.Info
+	defs map[*ast.Identifier]*ast.Identifier // identifier -> the name that declared it
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{uri: uri, text: text, lines: strings.Split(text, "\n")}
+
+	p := parser.New(lexer.New(text))
+	doc.program = p.ParseProgram()
+	if diags := p.Diagnostics(); len(diags) > 0 {
+		doc.diagnostics = diags
+		return doc
+	}
+
+	info, typeDiags := typecheck.Check(doc.program)
+	doc.info = info
+	doc.defs = resolve.Program(doc.program).Decls
+	doc.diagnostics = merge(lint.Lint(doc.program), typeDiags)
+	return doc
+}
+
+// merge combines linter and type checker diagnostics, dropping problems both
+// report at the same place, and orders them by position.
+func merge(lists ...[]diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	type key struct {
+		line, column int
+		code         string
+	}
+	seen := map[key]bool{}
+	var out []diagnostic.Diagnostic
+	for _, list := range lists {
+		for _, d := range list {
+			k := key{d.Line, d.Column, d.Code}
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, d)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
+
+// position converts a 1-based line and byte column to an LSP position.
+func (doc *document) position(line, column int) Position {
+	if line < 1 || line > len(doc.lines) {
+		return Position{Line: max(line-1, 0)}
+	}
+	text := doc.lines[line-1]
+	byteOffset := min(max(column-1, 0), len(text))
+	return Position{Line: line - 1, Character: utf16Len(text[:byteOffset])}
+}
+
+// offset converts an LSP position to a 1-based line and byte column.
+func (doc *document) offset(pos Position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(doc.lines) {
+		return pos.Line + 1, 1
+	}
+	text := doc.lines[pos.Line]
+	units := 0
+	for i, r := range text {
+		if units >= pos.Character {
+			return pos.Line + 1, i + 1
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return pos.Line + 1, len(text) + 1
+}
+
+// wordRange is the range of the word starting at a 1-based line and column,
+// used to underline diagnostics, which only record where they start.
+func (doc *document) wordRange(line, column int) Range {
+	start := doc.position(line, column)
+	end := start
+	if line >= 1 && line <= len(doc.lines) {
+		text := doc.lines[line-1]
+		i := column - 1
+		for i < len(text) && isWordByte(text[i]) {
+			i++
+		}
+		if i == column-1 && i < len(text) {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+		}
+		end = doc.position(line, i+1)
+	}
+	return Range{Start: start, End: end}
+}
+
+func (doc *document) identRange(ident *ast.Identifier) Range {
+	start := doc.position(ident.Token.Line, ident.Token.Column)
+	end := doc.position(ident.Token.Line, ident.Token.Column+len(ident.Value))
+	return Range{Start: start, End: end}
+}
+
+// fullRange covers the whole document.
+func (doc *document) fullRange() Range {
+	last := len(doc.lines) - 1
+	return Range{End: Position{Line: last, Character: utf16Len(doc.lines[last])}}
+}
+
+func (doc *document) lspDiagnostics() []Diagnostic {
+	out := make([]Diagnostic, 0, len(doc.diagnostics))
+	for _, d := range doc.diagnostics {
+		severity := SeverityError
+		if d.Severity == diagnostic.Warning {
+			severity = SeverityWarning
+		}
+		out = append(out, Diagnostic{
+			Range:    doc.wordRange(d.Line, d.Column),
+			Severity: severity,
+			Code:     d.Code,
+			Source:   "brolang",
+			Message:  d.Message,
+		})
+	}
+	return out
+}
+
+// identifierAt returns the variable name under the given position, if any.
+// Builtin names such as suna_bhai in a call are not variables and are skipped.
+func (doc *document) identifierAt(pos Position) *ast.Identifier {
+	line, column := doc.offset(pos)
+	var found *ast.Identifier
+	ast.Inspect(doc.program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression:
+			for _, arg := range n.Arguments {
+				ast.Inspect(arg, func(n ast.Node) bool {
+					if ident, ok := n.(*ast.Identifier); ok && covers(ident, line, column) {
+						found = ident
+					}
+					return found == nil
+				})
+			}
+			return false
+		case *ast.Identifier:
+			if covers(n, line, column) {
+				found = n
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+func covers(ident *ast.Identifier, line, column int) bool {
+	return ident.Token.Line == line &&
+		column >= ident.Token.Column && column <= ident.Token.Column+len(ident.Value)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
@@ -0,0 +1,243 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ankush-web-eng/brolang/format"
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+// ErrExitWithoutShutdown is returned by Run when the client sends exit
+// without asking the server to shut down first.
+var ErrExitWithoutShutdown = errors.New("exit received before shutdown")
+
+// Server is a Language Server Protocol server for Brolang. It handles one
+// message at a time, so documents need no locking.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+// NewServer returns a server reading requests from in and writing responses to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: make(map[string]*document),
+	}
+}
+
+// Run serves requests until the client sends exit or closes the input.
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if err == io.EOF {
+			if s.shutdown {
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			if msg == nil {
+				return err
+			}
+			// The body was read but is not valid JSON; the stream is still in sync.
+			s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		result, rerr := s.handle(msg)
+		if msg.ID != nil {
+			s.reply(msg.ID, result, rerr)
+		}
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rerr *responseError) {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &responseError{Code: codeInvalidParams, Message: err.Error()}
+		} else {
+			resp.Result = b
+		}
+	}
+	writeMessage(s.out, resp)
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle dispatches a request or notification. Unknown notifications are
+// ignored; unknown requests get a MethodNotFound error.
+func (s *Server) handle(msg *message) (interface{}, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(), nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.hover(params), nil
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.definition(params), nil
+
+	case "textDocument/completion":
+		return completions(), nil
+
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.formatting(params), nil
+	}
+
+	if msg.ID != nil {
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", msg.Method)}
+	}
+	return nil, nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func (s *Server) initialize() InitializeResult {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncKindFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "brolang"},
+	}
+}
+
+// update re-analyses a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.lspDiagnostics(),
+	})
+}
+
+// hover shows the inferred type of the variable under the cursor.
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok || doc.info == nil {
+		return nil
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return nil
+	}
+
+	r := doc.identRange(ident)
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("```brolang\n%s: %s\n```", ident.Value, doc.info.TypeOf(ident)),
+		},
+		Range: &r,
+	}
+}
+
+// definition jumps from a variable to the bhai_sun that declared it.
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok || doc.defs == nil {
+		return nil
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return nil
+	}
+	def, ok := doc.defs[ident]
+	if !ok {
+		return nil
+	}
+	return &Location{URI: doc.uri, Range: doc.identRange(def)}
+}
+
+func completions() []CompletionItem {
+	items := []CompletionItem{}
+	for _, kw := range token.Keywords() {
+		items = append(items, CompletionItem{Label: kw, Kind: CompletionItemKindKeyword, Detail: "keyword"})
+	}
+	return items
+}
+
+// formatting replaces the whole document with its canonical layout. Documents
+// with syntax errors are left alone.
+func (s *Server) formatting(params DocumentFormattingParams) []TextEdit {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok || doc.info == nil {
+		return nil
+	}
+	formatted := format.Program(doc.program)
+	if formatted == doc.text {
+		return []TextEdit{}
+	}
+	return []TextEdit{{Range: doc.fullRange(), NewText: formatted}}
+}
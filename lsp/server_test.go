@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+)
+
+const testURI = "file:///tmp/main.bro"
+
+const testSource = `bhai_sun naam = "bro";
+bhai_sun unused = 1;
+chal_bhai (bhai_sun i = 0; i < 2; i = i + 1) {
+    bol_bhai(naam);
+}
+bol_bhai(missing);`
+
+// session feeds the given client messages to a server and returns everything
+// it wrote, keyed by request id ("1", "2", ...) or notification method.
+func session(t *testing.T, msgs ...interface{}) (map[string]json.RawMessage, map[string][]json.RawMessage) {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, m := range msgs {
+		if err := writeMessage(&in, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatalf("server stopped with error: %v", err)
+	}
+
+	responses := map[string]json.RawMessage{}
+	notifications := map[string][]json.RawMessage{}
+	r := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			break
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		io.ReadFull(r, body)
+
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *responseError  `json:"error"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatalf("server wrote invalid JSON: %v", err)
+		}
+		switch {
+		case msg.Error != nil:
+			responses[string(msg.ID)] = json.RawMessage(fmt.Sprintf(`{"error":%d}`, msg.Error.Code))
+		case msg.ID != nil:
+			responses[string(msg.ID)] = msg.Result
+		default:
+			notifications[msg.Method] = append(notifications[msg.Method], msg.Params)
+		}
+	}
+	return responses, notifications
+}
+
+func request(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func position(id int, method string, line, character int) map[string]interface{} {
+	return request(id, method, TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: line, Character: character},
+	})
+}
+
+func open(text string) map[string]interface{} {
+	return notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "brolang", Version: 1, Text: text},
+	})
+}
+
+func shutdown(id int) []interface{} {
+	return []interface{}{request(id, "shutdown", nil), notify("exit", nil)}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	msgs := []interface{}{request(1, "initialize", map[string]interface{}{}), open(testSource)}
+	msgs = append(msgs, shutdown(2)...)
+	responses, notifications := session(t, msgs...)
+
+	var init InitializeResult
+	if err := json.Unmarshal(responses["1"], &init); err != nil {
+		t.Fatalf("bad initialize result: %v", err)
+	}
+	if !init.Capabilities.HoverProvider || init.Capabilities.TextDocumentSync != TextDocumentSyncKindFull {
+		t.Errorf("unexpected capabilities: %+v", init.Capabilities)
+	}
+
+	published := notifications["textDocument/publishDiagnostics"]
+	if len(published) != 1 {
+		t.Fatalf("expected one diagnostics notification, got=%d", len(published))
+	}
+	var params PublishDiagnosticsParams
+	json.Unmarshal(published[0], &params)
+
+	expected := []struct {
+		code  string
+		start Position
+		end   Position
+	}{
+		{"unused-variable", Position{1, 9}, Position{1, 15}},
+		{"undefined-identifier", Position{5, 9}, Position{5, 16}},
+	}
+	if len(params.Diagnostics) != len(expected) {
+		t.Fatalf("wrong number of diagnostics. expected=%d, got=%+v", len(expected), params.Diagnostics)
+	}
+	for i, want := range expected {
+		got := params.Diagnostics[i]
+		if got.Code != want.code || got.Range.Start != want.start || got.Range.End != want.end {
+			t.Errorf("diagnostic %d - expected %s at %v-%v, got=%+v", i, want.code, want.start, want.end, got)
+		}
+	}
+}
+
+func TestServerSyntaxError(t *testing.T) {
+	msgs := append([]interface{}{open("bol_bhai(5;")}, shutdown(1)...)
+	_, notifications := session(t, msgs...)
+
+	var params PublishDiagnosticsParams
+	json.Unmarshal(notifications["textDocument/publishDiagnostics"][0], &params)
+	if len(params.Diagnostics) != 1 || params.Diagnostics[0].Code != "syntax" {
+		t.Fatalf("expected a syntax diagnostic, got=%+v", params.Diagnostics)
+	}
+	if start := params.Diagnostics[0].Range.Start; start != (Position{0, 10}) {
+		t.Errorf("wrong position. expected=0:10, got=%v", start)
+	}
+}
+
+func TestServerHoverAndDefinition(t *testing.T) {
+	msgs := []interface{}{
+		open(testSource),
+		position(1, "textDocument/hover", 3, 14),      // naam inside bol_bhai
+		position(2, "textDocument/definition", 3, 14), // naam -> line 0
+		position(3, "textDocument/definition", 2, 27), // i in the condition
+		position(4, "textDocument/hover", 2, 3),       // the chal_bhai keyword
+	}
+	msgs = append(msgs, shutdown(5)...)
+	responses, _ := session(t, msgs...)
+
+	var hover Hover
+	json.Unmarshal(responses["1"], &hover)
+	if hover.Contents.Value != "```brolang\nnaam: string\n```" {
+		t.Errorf("wrong hover. got=%q", hover.Contents.Value)
+	}
+
+	var loc Location
+	json.Unmarshal(responses["2"], &loc)
+	if loc.URI != testURI || loc.Range.Start != (Position{0, 9}) || loc.Range.End != (Position{0, 13}) {
+		t.Errorf("wrong definition for naam. got=%+v", loc)
+	}
+
+	json.Unmarshal(responses["3"], &loc)
+	if loc.Range.Start != (Position{2, 20}) {
+		t.Errorf("wrong definition for i. got=%+v", loc)
+	}
+
+	if string(responses["4"]) != "null" {
+		t.Errorf("expected no hover on a keyword, got=%s", responses["4"])
+	}
+}
+
+func TestServerCompletionAndFormatting(t *testing.T) {
+	msgs := []interface{}{
+		open("bhai_sun x=1\nagar(x){bol_bhai(x)}"),
+		request(1, "textDocument/completion", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}}),
+		request(2, "textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}}),
+		request(3, "textDocument/unknown", nil),
+	}
+	msgs = append(msgs, shutdown(4)...)
+	responses, _ := session(t, msgs...)
+
+	var items []CompletionItem
+	json.Unmarshal(responses["1"], &items)
+	labels := map[string]bool{}
+	for _, item := range items {
+		labels[item.Label] = true
+	}
+	for _, kw := range []string{"bhai_sun", "bol_bhai", "chal_bhai", "nahi_to_agar"} {
+		if !labels[kw] {
+			t.Errorf("completion is missing %s", kw)
+		}
+	}
+
+	var edits []TextEdit
+	json.Unmarshal(responses["2"], &edits)
+	if len(edits) != 1 {
+		t.Fatalf("expected one edit, got=%+v", edits)
+	}
+	expected := "bhai_sun x = 1;\nagar (x) {\n    bol_bhai(x);\n}\n"
+	if edits[0].NewText != expected || edits[0].Range.End != (Position{1, 20}) {
+		t.Errorf("wrong edit. got=%+v", edits[0])
+	}
+
+	if string(responses["3"]) != fmt.Sprintf(`{"error":%d}`, codeMethodNotFound) {
+		t.Errorf("expected method not found, got=%s", responses["3"])
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	var in, out bytes.Buffer
+	writeMessage(&in, notify("exit", nil))
+	if err := NewServer(&in, &out).Run(); err != ErrExitWithoutShutdown {
+		t.Errorf("expected ErrExitWithoutShutdown, got=%v", err)
+	}
+}
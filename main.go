@@ -1,77 +1,37 @@
 package main
 
 import (
-	"net/http"
-
-	"github.com/ankush-web-eng/brolang/api/handler"
-	"github.com/ankush-web-eng/brolang/object"
+	"fmt"
+	"os"
 )
 
 func main() {
-	env := object.NewEnvironment()
-	handler.SetGlobalEnvironment(env)
-
-	http.HandleFunc("/compile", corsMiddleware(handler.CompilerHandler))
-	http.ListenAndServe(":8080", nil)
-}
-
-func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin == "http://localhost:3000" || origin == "https://brolang.ankushsingh.tech" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	if len(os.Args) < 2 {
+		os.Exit(serve(nil))
+	}
 
-		next.ServeHTTP(w, r)
+	switch os.Args[1] {
+	case "serve":
+		os.Exit(serve(os.Args[2:]))
+	case "run":
+		os.Exit(runCommand(os.Args[2:]))
+	case "lint":
+		os.Exit(lintCommand(os.Args[2:]))
+	case "check":
+		os.Exit(checkCommand(os.Args[2:]))
+	case "transpile":
+		os.Exit(transpileCommand(os.Args[2:]))
+	case "lsp":
+		os.Exit(lspCommand(os.Args[2:]))
+	case "debug":
+		os.Exit(debugCommand(os.Args[2:]))
+	case "test":
+		os.Exit(testCommand(os.Args[2:]))
+	case "worker":
+		os.Exit(workerCommand(os.Args[2:]))
+	default:
+		fmt.Fprintf(os.Stderr, "brolang: unknown command %q\n", os.Args[1])
+		fmt.Fprintln(os.Stderr, "usage: brolang [serve [-sandbox] | run [file] | lint [file...] | check [file...] | test program cases | transpile [-target=go|js] [file] | lsp | debug]")
+		os.Exit(2)
 	}
 }
-
-// package main
-
-// import (
-// 	"log"
-// 	"net/http"
-
-// 	"github.com/ankush-web-eng/brolang/api/handler"
-// 	"github.com/ankush-web-eng/brolang/object"
-// )
-
-// func main() {
-// 	env := object.NewEnvironment()
-// 	handler.SetGlobalEnvironment(env)
-
-// 	// Initialize Redis client for Pub/Sub
-// 	handler.InitializeRedisClient()
-
-// 	http.HandleFunc("/compile", corsMiddleware(handler.CompilerHandler))
-
-// 	log.Println("Server is starting on port 8080...")
-// 	if err := http.ListenAndServe(":8080", nil); err != nil {
-// 		log.Fatalf("Failed to start server: %v", err)
-// 	}
-// }
-
-// func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		origin := r.Header.Get("Origin")
-// 		if origin == "http://localhost:3000" || origin == "https://brolang.ankushsingh.tech" {
-// 			w.Header().Set("Access-Control-Allow-Origin", origin)
-// 		}
-// 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-// 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-// 		if r.Method == "OPTIONS" {
-// 			w.WriteHeader(http.StatusOK)
-// 			return
-// 		}
-
-// 		next.ServeHTTP(w, r)
-// 	}
-// }
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests served.", "route", "code")
+	latency := r.Histogram("latency_seconds", "How long requests took.", []float64{1, 0.1}, "route")
+	r.GaugeFunc("queue_depth", "Programs waiting.", func() float64 { return 3 })
+
+	requests.Inc("/compile", "200")
+	requests.Inc("/compile", "200")
+	requests.Inc(`/a"b`, "429")
+	latency.Observe(0.05, "/compile")
+	latency.Observe(0.5, "/compile")
+	latency.Observe(2, "/compile")
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/a\"b",code="429"} 1
+requests_total{route="/compile",code="200"} 2
+# HELP latency_seconds How long requests took.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/compile",le="0.1"} 1
+latency_seconds_bucket{route="/compile",le="1"} 2
+latency_seconds_bucket{route="/compile",le="+Inf"} 3
+latency_seconds_sum{route="/compile"} 2.55
+latency_seconds_count{route="/compile"} 3
+# HELP queue_depth Programs waiting.
+# TYPE queue_depth gauge
+queue_depth 3
+`
+	if b.String() != expected {
+		t.Errorf("wrong output.\nexpected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	if got := requests.Value("/compile", "200"); got != 2 {
+		t.Errorf("expected a value of 2, got=%v", got)
+	}
+	if got := latency.Count("/compile"); got != 3 {
+		t.Errorf("expected 3 observations, got=%d", got)
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a missing label value")
+		}
+	}()
+	NewRegistry().Counter("errors_total", "Errors.", "code").Inc()
+}
@@ -1,15 +1,40 @@
 package object
 
 import (
-	"fmt"
+	"bufio"
+	"sort"
 	"strings"
+
+	"github.com/ankush-web-eng/brolang/ast"
 )
 
+// Hook observes evaluation, for example to pause in a debugger. The evaluator
+// calls BeforeStatement before each statement of the program or of a block;
+// returning an error stops the program with that error.
+type Hook interface {
+	BeforeStatement(stmt ast.Statement, env *Environment) error
+}
+
 // Environment is a structure that holds variable mappings.
 type Environment struct {
 	store         map[string]Object
 	Outer         *Environment
 	OutputBuilder strings.Builder
+	// Input is where suna_bhai reads lines from; nil means there is no input.
+	// Enclosed environments share the input of their outer environment.
+	Input *bufio.Reader
+	// Hook, if set, is shared with enclosed environments like Input.
+	Hook Hook
+	// Tracer, if set, is shared the same way.
+	Tracer Tracer
+	// Depths, if set, gives for each identifier of the running program how
+	// many environments out from the one evaluating it its variable lives, as
+	// worked out by the resolve package. Shared the same way.
+	Depths map[*ast.Identifier]int
+	// LegacyScoping, if set, evaluates with the scoping of earlier releases:
+	// agar bodies and other blocks run in the enclosing environment, and
+	// assigning a name that was never declared declares it. Shared the same way.
+	LegacyScoping bool
 }
 
 // NewEnvironment creates a new Environment instance.
@@ -22,6 +47,11 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.Outer = outer
+	env.Input = outer.Input
+	env.Hook = outer.Hook
+	env.Tracer = outer.Tracer
+	env.Depths = outer.Depths
+	env.LegacyScoping = outer.LegacyScoping
 	return env
 }
 
@@ -29,7 +59,6 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 func (env *Environment) Get(name string) (Object, bool) {
 	obj, ok := env.store[name]
 	if !ok && env.Outer != nil {
-		fmt.Printf("Variable not found: %s\n", name)
 		return env.Outer.Get(name)
 	}
 	return obj, ok
@@ -37,15 +66,72 @@ func (env *Environment) Get(name string) (Object, bool) {
 
 // Set assigns a value to a variable in the environment.
 func (env *Environment) Set(name string, val Object) Object {
-	fmt.Printf("Setting variable: %s = %s\n", name, val.Inspect())
 	env.store[name] = val
 	return val
 }
 
+// Assign updates the variable in the nearest environment that already holds
+// name and reports whether there was one.
+func (env *Environment) Assign(name string, val Object) bool {
+	for cur := env; cur != nil; cur = cur.Outer {
+		if _, ok := cur.store[name]; ok {
+			cur.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+// GetAt looks name up only in the environment depth levels out from env.
+func (env *Environment) GetAt(depth int, name string) (Object, bool) {
+	if scope := env.at(depth); scope != nil {
+		obj, ok := scope.store[name]
+		return obj, ok
+	}
+	return nil, false
+}
+
+// AssignAt updates name in the environment depth levels out from env and
+// reports whether that environment holds it.
+func (env *Environment) AssignAt(depth int, name string, val Object) bool {
+	scope := env.at(depth)
+	if scope == nil {
+		return false
+	}
+	if _, ok := scope.store[name]; !ok {
+		return false
+	}
+	scope.store[name] = val
+	return true
+}
+
+func (env *Environment) at(depth int) *Environment {
+	for ; env != nil && depth > 0; depth-- {
+		env = env.Outer
+	}
+	return env
+}
+
 // Extend creates a new environment with the current environment as the outer environment.
 func (env *Environment) Extend() *Environment {
 	return &Environment{
-		store: make(map[string]Object),
-		Outer: env,
+		store:         make(map[string]Object),
+		Outer:         env,
+		Input:         env.Input,
+		Hook:          env.Hook,
+		Tracer:        env.Tracer,
+		Depths:        env.Depths,
+		LegacyScoping: env.LegacyScoping,
+	}
+}
+
+// Names returns the names of the variables stored directly in this
+// environment, without its outer environments, in alphabetical order.
+func (env *Environment) Names() []string {
+	names := make([]string, 0, len(env.store))
+	for name := range env.store {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
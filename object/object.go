@@ -16,6 +16,7 @@ const (
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
 	ARRAY_OBJ        = "ARRAY"
+	EXCEPTION_OBJ    = "EXCEPTION"
 )
 
 type Object interface {
@@ -98,8 +99,30 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Codes of runtime errors, which a program sees as galti["code"] after
+// catching one with pakad_bhai.
+const (
+	CodeRuntime   = "runtime"            // any error without a more specific code
+	CodeUser      = "user"               // raised with fek_bhai
+	CodeUndefined = "undefined-variable" // a name with no bhai_sun
+	CodeIndex     = "index-out-of-range"
+	CodeType      = "type-mismatch" // an operation on values of the wrong type
+	CodeInput     = "input"         // suna_bhai had nothing to read
+	CodeLoopLimit = "loop-limit"    // a loop ran too many times
+	CodeDivide    = "division-by-zero"
+)
+
 type Error struct {
 	Message string
+	Code    string
+	// Fatal errors cannot be caught, such as a debugger stopping the program.
+	Fatal bool
+	// Line and Column locate the statement that raised the error. They are
+	// zero until the evaluator's statement loop sees the error.
+	Line, Column int
+	// Stack lists the constructs the error passed through on its way out,
+	// innermost first: the failing statement, then any enclosing agar and loops.
+	Stack []Frame
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -107,6 +130,30 @@ func (e *Error) Inspect() string {
 	return "bhai galati kardi tune " + e.Message
 }
 
+// Exception is an error caught by pakad_bhai. Programs read it as
+// galti["message"] and galti["code"], or re-raise it with fek_bhai.
+type Exception struct {
+	Error *Error
+}
+
+func (e *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (e *Exception) Inspect() string  { return e.Error.Message }
+
+// Frame is one entry of an error's stack.
+type Frame struct {
+	Kind      string `json:"kind"` // the statement or construct, e.g. "bol_bhai" or "chal_bhai"
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	Iteration int    `json:"iteration,omitempty"` // loops only: the pass that failed, from 1
+}
+
+func (f Frame) String() string {
+	if f.Iteration > 0 {
+		return fmt.Sprintf("%s at %d:%d (iteration %d)", f.Kind, f.Line, f.Column, f.Iteration)
+	}
+	return fmt.Sprintf("%s at %d:%d", f.Kind, f.Line, f.Column)
+}
+
 type Array struct {
 	Elements []Object
 }
@@ -0,0 +1,28 @@
+package object
+
+// TraceKind says what happened in a TraceEvent.
+type TraceKind string
+
+const (
+	TraceStatement TraceKind = "statement" // a statement is about to run
+	TraceSet       TraceKind = "set"       // bhai_sun or an assignment stored a value
+	TraceIteration TraceKind = "iteration" // a loop is about to run its body again
+	TraceError     TraceKind = "error"     // a statement failed
+)
+
+// TraceEvent is one step of a running program. Line and Column locate the
+// statement or loop the event belongs to.
+type TraceEvent struct {
+	Kind      TraceKind
+	Line      int
+	Column    int
+	Name      string // TraceSet: the variable
+	Value     Object // TraceSet: the value stored; TraceError: the *Error
+	Iteration int    // TraceIteration: 1 for the first pass through the body
+}
+
+// Tracer receives the events of a running program. Like Hook it is optional
+// and shared by every environment of the program.
+type Tracer interface {
+	Trace(ev TraceEvent)
+}
@@ -0,0 +1,218 @@
+package optimize
+
+import (
+	"strconv"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+// Optimize simplifies a parsed program in place without changing what it
+// prints: constant arithmetic and comparisons are folded, agar branches whose
+// conditions are constant are pruned, and statements that can have no effect
+// are dropped. It returns the same program for convenience.
+func Optimize(program *ast.Program) *ast.Program {
+	ast.Rewrite(program, optimizeNode)
+	return program
+}
+
+// optimizeNode is called bottom-up by ast.Rewrite, so every node it sees
+// already has optimized children.
+func optimizeNode(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.InfixExpression:
+		return foldInfix(n)
+	case *ast.PrefixExpression:
+		return foldPrefix(n)
+	case *ast.IfExpression:
+		return pruneElseIfs(n)
+	case *ast.BlockStatement:
+		// The last statement of a block is its value (agar can be used as an
+		// expression), so it is never dropped.
+		n.Statements = dropNoOps(n.Statements, true)
+	case *ast.Program:
+		n.Statements = dropNoOps(n.Statements, false)
+	}
+	return node
+}
+
+// foldInfix evaluates arithmetic and comparisons between two integer literals.
+// Division by zero is left alone so the evaluator still reports its error.
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	left, lok := ie.Left.(*ast.IntegerLiteral)
+	right, rok := ie.Right.(*ast.IntegerLiteral)
+	if !lok || !rok {
+		return ie
+	}
+	tok := ast.StartToken(ie)
+
+	switch ie.Operator {
+	case "+":
+		return integerLiteral(tok, left.Value+right.Value)
+	case "-":
+		return integerLiteral(tok, left.Value-right.Value)
+	case "*":
+		return integerLiteral(tok, left.Value*right.Value)
+	case "/":
+		if right.Value != 0 {
+			return integerLiteral(tok, left.Value/right.Value)
+		}
+	case "%":
+		if right.Value != 0 {
+			return integerLiteral(tok, left.Value%right.Value)
+		}
+	case "<":
+		return booleanLiteral(tok, left.Value < right.Value)
+	case ">":
+		return booleanLiteral(tok, left.Value > right.Value)
+	case "==":
+		return booleanLiteral(tok, left.Value == right.Value)
+	case "!=":
+		return booleanLiteral(tok, left.Value != right.Value)
+	case "<=":
+		return booleanLiteral(tok, left.Value <= right.Value)
+	case ">=":
+		return booleanLiteral(tok, left.Value >= right.Value)
+	}
+	return ie
+}
+
+// foldPrefix evaluates negation of an integer literal and logical not of a constant.
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch pe.Operator {
+	case "-":
+		if il, ok := pe.Right.(*ast.IntegerLiteral); ok {
+			return integerLiteral(pe.Token, -il.Value)
+		}
+	case "!":
+		if value, ok := constantTruth(pe.Right); ok {
+			return booleanLiteral(pe.Token, !value)
+		}
+	}
+	return pe
+}
+
+// pruneElseIfs removes nahi_to_agar branches that can never run. A branch with
+// an always-false condition is dropped; a branch with an always-true condition
+// becomes the nahi_to block and everything after it is dropped. If the agar
+// condition itself is always false, the first remaining branch takes its place,
+// or the nahi_to block runs unconditionally; if it is always true, every other
+// branch is dropped.
+//
+// The agar itself always stays, even when only one block can run, so a runtime
+// error inside it still has an agar frame at the same position.
+func pruneElseIfs(ie *ast.IfExpression) ast.Expression {
+	elseIfs := ie.ElseIf[:0]
+	for _, elseIf := range ie.ElseIf {
+		value, ok := constantTruth(elseIf.Condition)
+		if !ok {
+			elseIfs = append(elseIfs, elseIf)
+			continue
+		}
+		if value {
+			ie.Alternative = elseIf.Consequence
+			break
+		}
+	}
+	ie.ElseIf = elseIfs
+
+	value, ok := constantTruth(ie.Condition)
+	switch {
+	case ok && value:
+		ie.ElseIf = nil
+		ie.Alternative = nil
+	case ok && !value && len(ie.ElseIf) > 0:
+		first := ie.ElseIf[0]
+		ie.Condition = first.Condition
+		ie.Consequence = first.Consequence
+		ie.ElseIf = ie.ElseIf[1:]
+	case ok && !value && ie.Alternative != nil:
+		ie.Condition = booleanLiteral(ast.StartToken(ie.Condition), true)
+		ie.Consequence = ie.Alternative
+		ie.Alternative = nil
+	}
+	return ie
+}
+
+// dropNoOps removes literal expression statements, which evaluate to a value
+// nobody reads, agar statements whose condition is always false and that have
+// no other branch, and statements after fek_bhai, which never run. In a block,
+// statements after bas_kar_bhai and aage_bhad_bhai never run either, and the
+// final statement always survives. A program carries on past a stray
+// bas_kar_bhai, so at the top level the statements after it are kept.
+//
+// Empty expression statements left behind by stray semicolons are kept: the
+// evaluator reports them as errors, and removing them would change output.
+func dropNoOps(stmts []ast.Statement, block bool) []ast.Statement {
+	out := stmts[:0]
+	for i, stmt := range stmts {
+		last := i == len(stmts)-1
+		if (isLiteralStatement(stmt) || isDeadIf(stmt)) && !(block && last) {
+			continue
+		}
+		out = append(out, stmt)
+
+		switch stmt.(type) {
+		case *ast.ThrowStatement:
+			return out
+		case *ast.BreakStatement, *ast.ContinueStatement:
+			if block {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+func isLiteralStatement(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	switch es.Expression.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+func isDeadIf(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	ie, ok := es.Expression.(*ast.IfExpression)
+	if !ok || len(ie.ElseIf) > 0 || ie.Alternative != nil {
+		return false
+	}
+	value, ok := constantTruth(ie.Condition)
+	return ok && !value
+}
+
+// constantTruth reports the truthiness of a literal, following the evaluator's isTruthy.
+func constantTruth(exp ast.Expression) (bool, bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.IntegerLiteral:
+		return exp.Value != 0, true
+	case *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+func integerLiteral(pos token.Token, value int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10), Line: pos.Line, Column: pos.Column},
+		Value: value,
+	}
+}
+
+func booleanLiteral(pos token.Token, value bool) *ast.Boolean {
+	tok := token.Token{Type: token.FALSE, Literal: "jhuth", Line: pos.Line, Column: pos.Column}
+	if value {
+		tok.Type, tok.Literal = token.TRUE, "sach"
+	}
+	return &ast.Boolean{Token: tok, Value: value}
+}
@@ -0,0 +1,165 @@
+package optimize
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/evaluator"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// run evaluates a program and returns its output followed by any error.
+func run(program *ast.Program) string {
+	env := object.NewEnvironment()
+	result := evaluator.Eval(program, env)
+	out := env.OutputBuilder.String()
+	if result != nil && result.Type() == object.ERROR_OBJ {
+		out += "error: " + result.Inspect()
+	}
+	return out
+}
+
+func TestOptimizePreservesOutput(t *testing.T) {
+	inputs := []string{
+		`bol_bhai(2 * 3 + 4);`,
+		`bhai_sun x = 10 - 2 * 3; bol_bhai(x);`,
+		`bhai_sun x = 7 % 3; bol_bhai(x / 1);`,
+		`agar (1 < 2) { bol_bhai("yes"); } nahi_to { bol_bhai("no"); }`,
+		`agar (2 < 1) { bol_bhai("yes"); } nahi_to { bol_bhai("no"); }`,
+		`agar (jhuth) { bol_bhai(1); } nahi_to_agar (sach) { bol_bhai(2); } nahi_to { bol_bhai(3); }`,
+		`bhai_sun x = 3;
+        agar (jhuth) { bol_bhai(1); } nahi_to_agar (x == 3) { bol_bhai(2); } nahi_to_agar (0) { bol_bhai(3); }`,
+		`agar (0) { bol_bhai("never"); }
+        bol_bhai("after");`,
+		`5; "noop"; sach; bol_bhai(1);`,
+		`bhai_sun x = agar (sach) { 5 };
+        bol_bhai(x);`,
+		`bhai_sun x = agar (sach) { 5; agar (jhuth) { 6 } };
+        bol_bhai(x);`,
+		`chal_bhai (bhai_sun i = 0; i < 2 + 1; i = i + 1) {
+            bol_bhai(i * 10);
+            agar (i == 1) { bas_kar_bhai; bol_bhai("unreachable"); }
+        }`,
+		`bhai_sun x = 0;
+        jaha_tak (1 == 1) {
+            x = x + 1;
+            agar (x > 3) { bas_kar_bhai; }
+        }
+        bol_bhai(x);`,
+		`bol_bhai(1); bas_kar_bhai; bol_bhai(2);`,
+		`bol_bhai(1); aage_bhad_bhai; bol_bhai(2);`,
+		`bol_bhai(1); fek_bhai "ruk"; bol_bhai(2);`,
+		`bol_bhai("a" + 1);`,
+		`bhai_sun arr = [1 + 1, 2 * 2]; bol_bhai(arr[3 - 2]);`,
+		`bhai_sun arr = [1, 2]; bol_bhai(arr[1 + 1]);`,
+	}
+
+	for i, input := range inputs {
+		plain := run(parse(t, input))
+		optimized := run(Optimize(parse(t, input)))
+		if plain != optimized {
+			t.Errorf("input %d - output changed.\nunoptimized=%q\noptimized=%q", i, plain, optimized)
+		}
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`bhai_sun x = 1 + 2;`, int64(3)},
+		{`bhai_sun x = 2 * 3 + 4;`, int64(14)},
+		{`bhai_sun x = 10 % 4;`, int64(2)},
+		{`bhai_sun x = 1 / 0;`, "(1 / 0)"},
+		{`bhai_sun x = 1 + y;`, "(1 + y)"},
+	}
+
+	for _, tt := range tests {
+		program := Optimize(parse(t, tt.input))
+		value := program.Statements[0].(*ast.LetStatement).Value
+
+		switch expected := tt.expected.(type) {
+		case int64:
+			lit, ok := value.(*ast.IntegerLiteral)
+			if !ok || lit.Value != expected {
+				t.Errorf("%s - not folded to %d. got=%T (%+v)", tt.input, expected, value, value)
+			}
+		case string:
+			if _, ok := value.(*ast.InfixExpression); !ok {
+				t.Errorf("%s - should not be folded. got=%T (%+v)", tt.input, value, value)
+			}
+		}
+	}
+}
+
+func TestFoldPrefix(t *testing.T) {
+	five := &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "5"}, Value: 5}
+
+	neg := foldPrefix(&ast.PrefixExpression{Operator: "-", Right: five})
+	if lit, ok := neg.(*ast.IntegerLiteral); !ok || lit.Value != -5 {
+		t.Errorf("-5 not folded. got=%T (%+v)", neg, neg)
+	}
+
+	not := foldPrefix(&ast.PrefixExpression{Operator: "!", Right: five})
+	if b, ok := not.(*ast.Boolean); !ok || b.Value {
+		t.Errorf("!5 not folded to jhuth. got=%T (%+v)", not, not)
+	}
+}
+
+func TestDeadBranchPruning(t *testing.T) {
+	program := Optimize(parse(t, `
+    agar (1 > 2) { bol_bhai(1); }
+    agar (2 > 1) { bol_bhai(2); } nahi_to { bol_bhai(3); }
+    5;
+    `))
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected a single statement, got=%d", len(program.Statements))
+	}
+	es, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("agar (2 > 1) not kept. got=%T", program.Statements[0])
+	}
+	ie, ok := es.Expression.(*ast.IfExpression)
+	if !ok || ie.Alternative != nil || len(ie.Consequence.Statements) != 1 {
+		t.Errorf("agar (2 > 1) not pruned to its block. got=%+v", ie)
+	}
+}
+
+func TestOptimizePreservesStack(t *testing.T) {
+	inputs := []string{
+		"agar (sach) {\n    bhai_sun a = [1];\n    bol_bhai(a[3]);\n}",
+		"agar (1 > 2) {\n    bol_bhai(1);\n} nahi_to {\n    bol_bhai(x);\n}",
+		"agar (jhuth) {\n    bol_bhai(1);\n} nahi_to_agar (sach) {\n    bol_bhai(\"a\" + 1);\n}",
+		"chal_bhai (bhai_sun i me 0..3) {\n    agar (2 > 1) {\n        agar (i == 2) { fek_bhai \"ruk\"; }\n    }\n}",
+	}
+
+	stack := func(program *ast.Program) []object.Frame {
+		err, ok := evaluator.Eval(program, object.NewEnvironment()).(*object.Error)
+		if !ok {
+			t.Fatal("expected an error")
+		}
+		return err.Stack
+	}
+	for i, input := range inputs {
+		plain := stack(parse(t, input))
+		optimized := stack(Optimize(parse(t, input)))
+		if !reflect.DeepEqual(plain, optimized) {
+			t.Errorf("input %d - stack changed.\nunoptimized=%+v\noptimized=%+v", i, plain, optimized)
+		}
+	}
+}
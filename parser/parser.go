@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/diagnostic"
 	"github.com/ankush-web-eng/brolang/lexer"
 	"github.com/ankush-web-eng/brolang/token"
 )
@@ -14,6 +15,7 @@ type Parser struct {
 	curToken  token.Token // Current token being parsed
 	peekToken token.Token // Next token to be parsed
 	errors    []string
+	diags     []diagnostic.Diagnostic
 }
 
 func New(l *lexer.Lexer) *Parser {
@@ -65,6 +67,9 @@ func (p *Parser) parseStatement() ast.Statement {
 		if p.peekTokenIs(token.ASSIGN) {
 			return p.parseAssignStatement()
 		}
+		if p.peekTokenIs(token.LBRACKET) {
+			return p.parseIndexAssignOrExpression()
+		}
 		return p.parseExpressionStatement()
 	case token.PRINT:
 		return p.parsePrintStatement()
@@ -86,6 +91,10 @@ func (p *Parser) parseStatement() ast.Statement {
 			p.nextToken()
 		}
 		return stmt
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -114,6 +123,34 @@ func (p *Parser) parseAssignStatement() *ast.AssignStatement {
 	return stmt
 }
 
+// parseIndexAssignOrExpression parses a statement starting with `name[`,
+// which is an index assignment if an '=' follows the brackets and an
+// expression statement otherwise.
+func (p *Parser) parseIndexAssignOrExpression() ast.Statement {
+	start := p.curToken
+	exp := p.parseExpression()
+	if !p.peekTokenIs(token.ASSIGN) {
+		return &ast.ExpressionStatement{Token: start, Expression: exp}
+	}
+
+	target, ok := exp.(*ast.IndexExpression)
+	if !ok {
+		p.addError(p.peekToken, "Bhai assign sirf ek index pe hota h, arr[i] = value likh!!")
+		return nil
+	}
+	stmt := &ast.IndexAssignStatement{Token: start, Target: target}
+
+	p.nextToken() // move to '='
+	p.nextToken()
+	stmt.Value = p.parseExpression()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // parsePrintStatement parses a print statement
 func (p *Parser) parsePrintStatement() *ast.PrintStatement {
 	stmt := &ast.PrintStatement{Token: p.curToken}
@@ -146,9 +183,27 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 // parseExpression parses an expression
 func (p *Parser) parseExpression() ast.Expression {
+	leftExp := p.parseOperand()
+	if leftExp == nil {
+		return nil
+	}
+
+	// If the next token is an infix operator, parse it as an infix expression
+	for p.peekTokenIs(token.PLUS) || p.peekTokenIs(token.MINUS) ||
+		p.peekTokenIs(token.ASTERISK) || p.peekTokenIs(token.SLASH) || p.peekTokenIs(token.MOD) {
+		p.nextToken() // Move to the operator
+		leftExp = p.parseInfixExpression(leftExp)
+	}
+
+	return leftExp
+}
+
+// parseOperand parses a value together with any prefix operator in front of
+// it and any indexes or slices after it, so that -arr[0] and arr[0] + 1 mean
+// what they look like.
+func (p *Parser) parseOperand() ast.Expression {
 	var leftExp ast.Expression
 
-	// Parse left-hand side of the expression
 	switch p.curToken.Type {
 	case token.INT:
 		leftExp = p.parseIntegerLiteral()
@@ -172,19 +227,16 @@ func (p *Parser) parseExpression() ast.Expression {
 		} else {
 			leftExp = p.parseIdentifier()
 		}
+	case token.INPUT, token.APPEND:
+		leftExp = p.parseCallExpression(p.parseIdentifier())
+	case token.MINUS, token.BANG:
+		return p.parsePrefixExpression()
 
 	default:
 		return nil
 	}
 
-	// If the next token is an infix operator, parse it as an infix expression
-	for p.peekTokenIs(token.PLUS) || p.peekTokenIs(token.MINUS) ||
-		p.peekTokenIs(token.ASTERISK) || p.peekTokenIs(token.SLASH) || p.peekTokenIs(token.MOD) {
-		p.nextToken() // Move to the operator
-		leftExp = p.parseInfixExpression(leftExp)
-	}
-
-	if p.peekTokenIs(token.LBRACKET) {
+	for leftExp != nil && p.peekTokenIs(token.LBRACKET) {
 		p.nextToken()
 		leftExp = p.parseIndexExpression(leftExp)
 	}
@@ -200,11 +252,26 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		return nil
 	}
 
+	return p.parseLetValue(stmt)
+}
+
+// parseLetValue parses the rest of a let statement, starting at its name
+func (p *Parser) parseLetValue(stmt *ast.LetStatement) *ast.LetStatement {
 	stmt.Name = &ast.Identifier{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
 
+	// Optional type annotation (bhai_sun x: int = 5)
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Type = p.parseTypeAnnotation()
+		if stmt.Type == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -215,14 +282,37 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parseTypeAnnotation parses a type name (int) or an array type ([]int)
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	ta := &ast.TypeAnnotation{Token: p.curToken}
+
+	switch p.curToken.Type {
+	case token.IDENT:
+		ta.Name = p.curToken.Literal
+	case token.LBRACKET:
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		p.nextToken()
+		ta.Elem = p.parseTypeAnnotation()
+		if ta.Elem == nil {
+			return nil
+		}
+	default:
+		p.addError(p.curToken, fmt.Sprintf("Ye konsa type h bhai?? %s", p.curToken.Literal))
+		return nil
+	}
+
+	return ta
+}
+
 // parseIntegerLiteral parses an integer literal (123)
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, fmt.Sprintf("could not parse %q as integer", p.curToken.Literal))
 		return nil
 	}
 
@@ -396,7 +486,8 @@ func (p *Parser) parseWhileExpression() ast.Expression {
 	return expression
 }
 
-// parseForExpression parses a for-expression
+// parseForExpression parses a for-expression, or a for-each expression when
+// the loop variable is followed by "me" or a comma
 func (p *Parser) parseForExpression() ast.Expression {
 	expression := &ast.ForExpression{Token: p.curToken}
 
@@ -406,7 +497,19 @@ func (p *Parser) parseForExpression() ast.Expression {
 
 	// Parse initialization
 	p.nextToken() // Move past '('
-	if !p.curTokenIs(token.SEMICOLON) {
+	if p.curTokenIs(token.LET) {
+		let := &ast.LetStatement{Token: p.curToken}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		if token.IsContextual(p.peekToken, token.IN) || p.peekTokenIs(token.COMMA) {
+			return p.parseForEachExpression(expression.Token)
+		}
+		expression.Init = p.parseLetValue(let)
+		if expression.Init == nil {
+			return nil
+		}
+	} else if !p.curTokenIs(token.SEMICOLON) {
 		expression.Init = p.parseStatement()
 		if expression.Init == nil {
 			return nil
@@ -452,6 +555,58 @@ func (p *Parser) parseForExpression() ast.Expression {
 	return expression
 }
 
+// parseForEachExpression parses the rest of `chal_bhai (bhai_sun [i,] x me
+// iterable) { ... }`, starting at the first loop variable
+func (p *Parser) parseForEachExpression(tok token.Token) ast.Expression {
+	expression := &ast.ForEachExpression{Token: tok}
+
+	expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Index = expression.Value
+		expression.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !token.IsContextual(p.peekToken, token.IN) {
+		p.peekError(token.IN)
+		return nil
+	}
+	p.nextToken()
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression()
+	if expression.Iterable == nil {
+		p.addError(p.curToken, "me ke baad kis pe chalna h wo bhi to bata!!")
+		return nil
+	}
+	if p.peekTokenIs(token.DOTDOT) {
+		p.nextToken()
+		rng := &ast.RangeExpression{Token: p.curToken, Start: expression.Iterable}
+		p.nextToken()
+		rng.End = p.parseExpression()
+		if rng.End == nil {
+			p.addError(p.curToken, "Range kaha tak chalegi? .. ke baad bhi kuch likh!!")
+			return nil
+		}
+		expression.Iterable = rng
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
 // parseBlockStatement parses a block statement
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
@@ -464,12 +619,93 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+
+		// Like ParseProgram, skip the semicolon that ends a bhai_sun or an
+		// expression statement.
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
 		p.nextToken()
 	}
 
 	return block
 }
 
+// parseTryStatement parses koshish_kar_bhai { ... } followed by
+// pakad_bhai (name) { ... }, aakhir_me { ... } or both
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		// The name for the caught error is optional
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.CatchName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addError(p.peekToken, "Koshish ke baad pakad_bhai ya aakhir_me bhi likh de bhai!!")
+		return nil
+	}
+
+	return stmt
+}
+
+// parseThrowStatement parses fek_bhai followed by the value to raise
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression()
+	if stmt.Value == nil {
+		p.addError(p.curToken, "fek_bhai ke baad kya fekna h wo bhi to likh!!")
+		return nil
+	}
+
+	return stmt
+}
+
+// parsePrefixExpression parses a negation or logical not (-1, !done). It binds
+// tighter than any infix operator.
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
+
+	p.nextToken()
+	expression.Right = p.parseOperand()
+	if expression.Right == nil {
+		p.addError(p.curToken, fmt.Sprintf("%s ke baad kuch to likh bhai!!", expression.Operator))
+		return nil
+	}
+	return expression
+}
+
 // parseInfixExpression parses an infix expressionn (1 + 2)
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
@@ -483,7 +719,7 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
-// parseCallExpression parses a function call expression, specifically for bol_bhai()
+// parseCallExpression parses a function call expression, specifically for bol_bhai() and suna_bhai()
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{
 		Token:    p.curToken,
@@ -498,7 +734,6 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp.Arguments = []ast.Expression{}
 
 	if p.curTokenIs(token.RPAREN) {
-		p.nextToken()
 		return exp
 	}
 
@@ -519,18 +754,34 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 
 // ------- More helper methods -------
 
-// parseIndexExpression parses an index expression (array[1])
+// parseIndexExpression parses an index expression (array[1]) or a slice
+// (array[1:3], where either bound may be left out)
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
 
 	p.nextToken() // Move past '['
-	exp.Index = p.parseExpression()
+	var index ast.Expression
+	if !p.curTokenIs(token.COLON) {
+		index = p.parseExpression()
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: index}
+		}
+		p.nextToken() // Move to ':'
+	}
 
+	slice := &ast.SliceExpression{Token: tok, Left: left, Start: index}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.End = p.parseExpression()
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 
-	return exp
+	return slice
 }
 
 // curTokenIs checks if the current token is of a certain type
@@ -557,10 +808,21 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("Sahi se code likhna bhi nahi aa raha tere se! %s kaha se aa gaya %s se pehle!!!!",
 		p.peekToken.Literal, t)
+	p.addError(p.peekToken, msg)
+}
+
+// addError records an error message along with the position of the offending token
+func (p *Parser) addError(tok token.Token, msg string) {
 	p.errors = append(p.errors, msg)
+	p.diags = append(p.diags, diagnostic.New(tok, diagnostic.Error, "syntax", "%s", msg))
 }
 
 // Errors returns the list of errors encountered during parsing
 func (p *Parser) Errors() []string {
 	return p.errors
 }
+
+// Diagnostics returns the errors encountered during parsing together with their positions
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
+	return p.diags
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/ankush-web-eng/brolang/ast"
@@ -74,3 +75,208 @@ func TestLetStatements(t *testing.T) {
 		}
 	}
 }
+
+func TestParserDiagnostics(t *testing.T) {
+	input := `bhai_sun x = 5;
+bol_bhai(x;`
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	diags := p.Diagnostics()
+	if len(diags) != len(p.Errors()) {
+		t.Fatalf("diagnostics and errors out of sync. diagnostics=%d, errors=%d", len(diags), len(p.Errors()))
+	}
+	if len(diags) == 0 {
+		t.Fatalf("expected a syntax error")
+	}
+	if diags[0].Line != 2 || diags[0].Column != 11 {
+		t.Errorf("wrong position. expected=2:11, got=%d:%d", diags[0].Line, diags[0].Column)
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`bhai_sun x: int = 5;`, "int"},
+		{`bhai_sun xs: []string = ["a"];`, "[]string"},
+		{`bhai_sun grid: [][]int = [[1]];`, "[][]int"},
+		{`bhai_sun y = 5;`, ""},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.LetStatement)
+		got := ""
+		if stmt.Type != nil {
+			got = stmt.Type.String()
+		}
+		if got != tt.expected {
+			t.Errorf("wrong annotation. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestBlockSemicolons(t *testing.T) {
+	input := `agar (sach) {
+    bhai_sun label = "m";
+    label;
+    bol_bhai(label);
+}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	block := stmt.Expression.(*ast.IfExpression).Consequence
+	if len(block.Statements) != 3 {
+		t.Fatalf("expected 3 statements in block, got=%d", len(block.Statements))
+	}
+	for i, s := range block.Statements {
+		if es, ok := s.(*ast.ExpressionStatement); ok && es.Expression == nil {
+			t.Errorf("statement %d is empty", i)
+		}
+	}
+}
+
+func TestTryStatements(t *testing.T) {
+	input := `koshish_kar_bhai {
+    fek_bhai "galat";
+} pakad_bhai (g) {
+    bol_bhai(g);
+} aakhir_me {
+    bol_bhai("done");
+}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("expected *ast.TryStatement, got=%T", program.Statements[0])
+	}
+	if stmt.CatchName == nil || stmt.CatchName.Value != "g" {
+		t.Errorf("wrong catch name. got=%v", stmt.CatchName)
+	}
+	if stmt.Catch == nil || stmt.Finally == nil {
+		t.Fatalf("expected both pakad_bhai and aakhir_me blocks")
+	}
+	if _, ok := stmt.Body.Statements[0].(*ast.ThrowStatement); !ok {
+		t.Errorf("expected *ast.ThrowStatement, got=%T", stmt.Body.Statements[0])
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`koshish_kar_bhai { bol_bhai(1); }`, "Koshish ke baad pakad_bhai ya aakhir_me bhi likh de bhai!!"},
+		{`fek_bhai;`, "fek_bhai ke baad kya fekna h wo bhi to likh!!"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || !strings.Contains(errors[0], tt.expected) {
+			t.Errorf("expected error %q, got=%v", tt.expected, errors)
+		}
+	}
+}
+
+func TestIndexAssignAndSlices(t *testing.T) {
+	input := `grid[0][-1] = 5;
+bol_bhai(arr[1:]);
+bol_bhai(arr[:2]);
+bol_bhai(arr[0] + 1);`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assign, ok := program.Statements[0].(*ast.IndexAssignStatement)
+	if !ok {
+		t.Fatalf("expected *ast.IndexAssignStatement, got=%T", program.Statements[0])
+	}
+	if _, ok := assign.Target.Left.(*ast.IndexExpression); !ok {
+		t.Errorf("expected a nested index target, got=%T", assign.Target.Left)
+	}
+	if prefix, ok := assign.Target.Index.(*ast.PrefixExpression); !ok || prefix.Operator != "-" {
+		t.Errorf("expected a negative index, got=%T", assign.Target.Index)
+	}
+
+	from := program.Statements[1].(*ast.PrintStatement).Expression.(*ast.SliceExpression)
+	if from.Start == nil || from.End != nil {
+		t.Errorf("arr[1:] should have only a start. got start=%v end=%v", from.Start, from.End)
+	}
+	to := program.Statements[2].(*ast.PrintStatement).Expression.(*ast.SliceExpression)
+	if to.Start != nil || to.End == nil {
+		t.Errorf("arr[:2] should have only an end. got start=%v end=%v", to.Start, to.End)
+	}
+	if _, ok := program.Statements[3].(*ast.PrintStatement).Expression.(*ast.InfixExpression); !ok {
+		t.Errorf("arr[0] + 1 should be an infix expression")
+	}
+
+	p = New(lexer.New(`arr[1:2] = 5;`))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) == 0 || !strings.Contains(errors[0], "arr[i] = value") {
+		t.Errorf("expected an error assigning to a slice, got=%v", errors)
+	}
+}
+
+func TestForEachExpressions(t *testing.T) {
+	input := `chal_bhai (bhai_sun x me arr) { bol_bhai(x); }
+chal_bhai (bhai_sun i, n me 1..len) { bol_bhai(n); }
+chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) { bol_bhai(i); }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	each := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ForEachExpression)
+	if each.Index != nil || each.Value.Value != "x" {
+		t.Errorf("expected only a value variable x, got index=%v value=%v", each.Index, each.Value)
+	}
+	if _, ok := each.Iterable.(*ast.Identifier); !ok {
+		t.Errorf("expected an identifier to loop over, got=%T", each.Iterable)
+	}
+
+	ranged := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.ForEachExpression)
+	if ranged.Index == nil || ranged.Index.Value != "i" || ranged.Value.Value != "n" {
+		t.Errorf("expected variables i and n, got index=%v value=%v", ranged.Index, ranged.Value)
+	}
+	rng, ok := ranged.Iterable.(*ast.RangeExpression)
+	if !ok {
+		t.Fatalf("expected *ast.RangeExpression, got=%T", ranged.Iterable)
+	}
+	if _, ok := rng.Start.(*ast.IntegerLiteral); !ok {
+		t.Errorf("expected the range to start at a literal, got=%T", rng.Start)
+	}
+	if end, ok := rng.End.(*ast.Identifier); !ok || end.Value != "len" {
+		t.Errorf("expected the range to end at len, got=%v", rng.End)
+	}
+
+	if _, ok := program.Statements[2].(*ast.ExpressionStatement).Expression.(*ast.ForExpression); !ok {
+		t.Errorf("a three-clause chal_bhai should still parse as *ast.ForExpression")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`chal_bhai (bhai_sun x me) {}`, "me ke baad kis pe chalna h"},
+		{`chal_bhai (bhai_sun x me 1..) {}`, "Range kaha tak chalegi?"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if errors := p.Errors(); len(errors) == 0 || !strings.Contains(errors[0], tt.expected) {
+			t.Errorf("%s - expected error containing %q, got=%v", tt.input, tt.expected, errors)
+		}
+	}
+}
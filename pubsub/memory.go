@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bufferSize is how many messages a subscriber may fall behind by.
+const bufferSize = 16
+
+// Memory is a Broker within one process.
+type Memory struct {
+	keep time.Duration
+
+	mu   sync.Mutex
+	last map[string]kept
+	subs map[string]map[*memorySubscription]bool
+}
+
+type kept struct {
+	msg     []byte
+	expires time.Time
+}
+
+// NewMemory returns a broker that keeps each channel's last message for keep.
+func NewMemory(keep time.Duration) *Memory {
+	return &Memory{
+		keep: keep,
+		last: map[string]kept{},
+		subs: map[string]map[*memorySubscription]bool{},
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, channel string, msg []byte) error {
+	msg = append([]byte(nil), msg...)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, k := range m.last {
+		if now.After(k.expires) {
+			delete(m.last, name)
+		}
+	}
+	m.last[channel] = kept{msg: msg, expires: now.Add(m.keep)}
+	for sub := range m.subs[channel] {
+		select {
+		case sub.ch <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Last(ctx context.Context, channel string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.last[channel]
+	if !ok || time.Now().After(k.expires) {
+		return nil, false, nil
+	}
+	return k.msg, true, nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	sub := &memorySubscription{m: m, channel: channel, ch: make(chan []byte, bufferSize)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.subs[channel] == nil {
+		m.subs[channel] = map[*memorySubscription]bool{}
+	}
+	m.subs[channel][sub] = true
+	return sub, nil
+}
+
+// Close ends every subscription.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, subs := range m.subs {
+		for sub := range subs {
+			close(sub.ch)
+		}
+	}
+	m.subs = map[string]map[*memorySubscription]bool{}
+	return nil
+}
+
+type memorySubscription struct {
+	m       *Memory
+	channel string
+	ch      chan []byte
+}
+
+func (s *memorySubscription) Messages() <-chan []byte { return s.ch }
+
+func (s *memorySubscription) Close() error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if s.m.subs[s.channel][s] {
+		delete(s.m.subs[s.channel], s)
+		if len(s.m.subs[s.channel]) == 0 {
+			delete(s.m.subs, s.channel)
+		}
+		close(s.ch)
+	}
+	return nil
+}
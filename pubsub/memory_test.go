@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryPublishSubscribe(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(time.Minute)
+	defer m.Close()
+
+	if _, ok, _ := m.Last(ctx, "job"); ok {
+		t.Fatal("expected no last message before anything was published")
+	}
+
+	sub, err := m.Subscribe(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := m.Subscribe(ctx, "other")
+
+	for _, msg := range []string{"queued", "done"} {
+		if err := m.Publish(ctx, "job", []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, expected := range []string{"queued", "done"} {
+		select {
+		case got := <-sub.Messages():
+			if string(got) != expected {
+				t.Errorf("expected %q, got=%q", expected, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no message, expected %q", expected)
+		}
+	}
+	select {
+	case got := <-other.Messages():
+		t.Errorf("expected nothing on another channel, got=%q", got)
+	default:
+	}
+
+	if last, ok, _ := m.Last(ctx, "job"); !ok || string(last) != "done" {
+		t.Errorf("expected last message %q, got=%q (%v)", "done", last, ok)
+	}
+
+	sub.Close()
+	if _, open := <-sub.Messages(); open {
+		t.Error("expected Messages to be closed with the subscription")
+	}
+	sub.Close()
+	if err := m.Publish(ctx, "job", []byte("again")); err != nil {
+		t.Errorf("publishing after a subscriber left: %v", err)
+	}
+}
+
+func TestMemoryKeep(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10 * time.Millisecond)
+	defer m.Close()
+
+	m.Publish(ctx, "old", []byte("1"))
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := m.Last(ctx, "old"); ok {
+		t.Error("expected the last message to be gone after the keep time")
+	}
+
+	m.Publish(ctx, "new", []byte("2"))
+	m.mu.Lock()
+	_, stale := m.last["old"]
+	m.mu.Unlock()
+	if stale {
+		t.Error("expected publishing to drop expired messages")
+	}
+}
@@ -0,0 +1,33 @@
+// Package pubsub carries the results of asynchronous jobs from the process
+// that ran them to the clients waiting for them. A Broker delivers each
+// message to the current subscribers of its channel and also keeps the last
+// one for a while, so a client that polls, or subscribes late, still sees it.
+//
+// Memory is a broker for a single server; Redis lets several servers share
+// jobs.
+package pubsub
+
+import "context"
+
+// Broker publishes messages on named channels.
+type Broker interface {
+	// Publish sends msg to everyone subscribed to channel and keeps it as
+	// the channel's last message.
+	Publish(ctx context.Context, channel string, msg []byte) error
+	// Last returns the last message published on channel, if it is still
+	// kept.
+	Last(ctx context.Context, channel string) (msg []byte, ok bool, err error)
+	// Subscribe starts receiving the messages published on channel from now
+	// on. To not miss one, subscribe before checking Last.
+	Subscribe(ctx context.Context, channel string) (Subscription, error)
+	// Close releases the broker's resources.
+	Close() error
+}
+
+// Subscription receives the messages of one channel.
+type Subscription interface {
+	// Messages delivers the messages, and is closed when the subscription
+	// is. A subscriber that falls far behind misses messages.
+	Messages() <-chan []byte
+	Close() error
+}
@@ -0,0 +1,81 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis is a Broker on a Redis server: messages go out with PUBLISH and the
+// last one of each channel is kept under the key "<channel>:last".
+type Redis struct {
+	client *redis.Client
+	keep   time.Duration
+}
+
+// NewRedis returns a broker on client that keeps each channel's last message
+// for keep. Closing the broker closes the client.
+func NewRedis(client *redis.Client, keep time.Duration) *Redis {
+	return &Redis{client: client, keep: keep}
+}
+
+func lastKey(channel string) string {
+	return channel + ":last"
+}
+
+func (r *Redis) Publish(ctx context.Context, channel string, msg []byte) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, lastKey(channel), msg, r.keep)
+		pipe.Publish(ctx, channel, msg)
+		return nil
+	})
+	return err
+}
+
+func (r *Redis) Last(ctx context.Context, channel string) ([]byte, bool, error) {
+	msg, err := r.client.Get(ctx, lastKey(channel)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return msg, true, nil
+}
+
+func (r *Redis) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	ps := r.client.Subscribe(ctx, channel)
+	// Wait for the server to confirm, so nothing published after we return
+	// is missed.
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	sub := &redisSubscription{ps: ps, ch: make(chan []byte, bufferSize)}
+	go func() {
+		defer close(sub.ch)
+		for m := range ps.Channel() {
+			select {
+			case sub.ch <- []byte(m.Payload):
+			default:
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+type redisSubscription struct {
+	ps *redis.PubSub
+	ch chan []byte
+}
+
+func (s *redisSubscription) Messages() <-chan []byte { return s.ch }
+
+func (s *redisSubscription) Close() error {
+	return s.ps.Close()
+}
@@ -0,0 +1,226 @@
+// Package queue limits how many jobs run at once and how many may wait for
+// their turn. Waiting jobs are grouped by client and the clients take turns,
+// so one client with many jobs waiting does not hold up everyone else.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Config describes a queue.
+type Config struct {
+	// Concurrency is the number of jobs running at once. It defaults to 1.
+	Concurrency int
+	// Depth is the number of jobs that may wait for a slot; 0 means a job
+	// that cannot start at once is turned away.
+	Depth int
+	// PerClient is the number of jobs one client may have waiting; 0 means
+	// the whole Depth.
+	PerClient int
+}
+
+// FullError is returned for a job that found the queue, or its client's share
+// of it, full.
+type FullError struct {
+	RetryAfter time.Duration // a guess at when a slot will be free
+}
+
+func (e *FullError) Error() string {
+	return fmt.Sprintf("queue is full, retry after %v", e.RetryAfter)
+}
+
+// Queue hands out slots to run jobs in.
+type Queue struct {
+	cfg Config
+
+	mu      sync.Mutex
+	running int
+	waiting int
+	clients map[string]*client // clients with jobs waiting
+	turns   []*client          // the same clients, in the order they are served
+	average time.Duration      // how long a job holds its slot, as a moving average
+}
+
+type client struct {
+	id      string
+	waiters []*waiter
+}
+
+type waiter struct {
+	ready   chan struct{} // closed once the job has been given a slot
+	granted bool
+}
+
+// New returns a queue with no jobs running.
+func New(cfg Config) *Queue {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PerClient < 1 || cfg.PerClient > cfg.Depth {
+		cfg.PerClient = cfg.Depth
+	}
+	return &Queue{cfg: cfg, clients: map[string]*client{}}
+}
+
+// Acquire waits for a slot for a job from the named client. It returns how
+// long the job waited and a function to call when it is done, which frees the
+// slot for the next client in turn. A job the queue has no room for gets a
+// *FullError straight away.
+func (q *Queue) Acquire(ctx context.Context, clientID string) (release func(), waited time.Duration, err error) {
+	t, err := q.Enqueue(clientID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t.Wait(ctx)
+}
+
+// Ticket is a job's place in the queue.
+type Ticket struct {
+	q      *Queue
+	client *client // nil if the job got a slot at once
+	w      *waiter
+	start  time.Time
+}
+
+// Enqueue takes a place in the queue for a job from the named client, or
+// returns a *FullError if there is no room. The job must then Wait for its
+// turn or Cancel.
+func (q *Queue) Enqueue(clientID string) (*Ticket, error) {
+	t := &Ticket{q: q, w: &waiter{ready: make(chan struct{})}, start: time.Now()}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running < q.cfg.Concurrency && q.waiting == 0 {
+		q.running++
+		t.w.granted = true
+		close(t.w.ready)
+		return t, nil
+	}
+	c := q.clients[clientID]
+	if q.waiting >= q.cfg.Depth || (c != nil && len(c.waiters) >= q.cfg.PerClient) {
+		return nil, &FullError{RetryAfter: q.retryAfter()}
+	}
+	if c == nil {
+		c = &client{id: clientID}
+		q.clients[clientID] = c
+		q.turns = append(q.turns, c)
+	}
+	c.waiters = append(c.waiters, t.w)
+	q.waiting++
+	t.client = c
+	return t, nil
+}
+
+// Wait waits for the job's turn, returning how long it waited since Enqueue
+// and a function to call when it is done. If ctx ends first the job's place
+// is given up.
+func (t *Ticket) Wait(ctx context.Context) (release func(), waited time.Duration, err error) {
+	q := t.q
+	select {
+	case <-t.w.ready:
+		if t.client == nil {
+			return q.releaser(), 0, nil
+		}
+		return q.releaser(), time.Since(t.start), nil
+	case <-ctx.Done():
+		t.Cancel()
+		return nil, time.Since(t.start), ctx.Err()
+	}
+}
+
+// Cancel gives up the job's place, or its slot if it already has one, to the
+// next job in turn. It is for a job that will not Wait.
+func (t *Ticket) Cancel() {
+	q := t.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if t.w.granted {
+		// The slot came just as the job gave up; pass it on.
+		q.running--
+		q.dispatch()
+	} else {
+		q.remove(t.client, t.w)
+	}
+}
+
+// Len reports the number of jobs running and waiting.
+func (q *Queue) Len() (running, waiting int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running, q.waiting
+}
+
+func (q *Queue) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.running--
+			q.record(time.Since(start))
+			q.dispatch()
+		})
+	}
+}
+
+// dispatch gives free slots to waiting jobs, one client at a time.
+func (q *Queue) dispatch() {
+	for q.running < q.cfg.Concurrency && len(q.turns) > 0 {
+		c := q.turns[0]
+		q.turns = q.turns[1:]
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		if len(c.waiters) > 0 {
+			q.turns = append(q.turns, c)
+		} else {
+			delete(q.clients, c.id)
+		}
+		q.waiting--
+		q.running++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// remove takes a job that gave up off its client's list.
+func (q *Queue) remove(c *client, w *waiter) {
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			q.waiting--
+			break
+		}
+	}
+	if len(c.waiters) > 0 {
+		return
+	}
+	delete(q.clients, c.id)
+	for i, other := range q.turns {
+		if other == c {
+			q.turns = append(q.turns[:i], q.turns[i+1:]...)
+			break
+		}
+	}
+}
+
+func (q *Queue) record(d time.Duration) {
+	if q.average == 0 {
+		q.average = d
+		return
+	}
+	q.average += (d - q.average) / 8
+}
+
+// retryAfter guesses how long it will take for everything now waiting, and
+// one more job, to get a slot.
+func (q *Queue) retryAfter() time.Duration {
+	wait := q.average * time.Duration(q.waiting+1) / time.Duration(q.cfg.Concurrency)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// acquireAsync starts waiting for a slot and reports the client's name once
+// it has one.
+func acquireAsync(t *testing.T, q *Queue, client string, order chan<- string) {
+	t.Helper()
+	go func() {
+		release, _, err := q.Acquire(context.Background(), client)
+		if err != nil {
+			t.Errorf("%s: %v", client, err)
+			return
+		}
+		order <- client
+		release()
+	}()
+}
+
+// waitFor waits until n jobs are waiting.
+func waitFor(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, waiting := q.Len(); waiting == n {
+			return
+		}
+	}
+	t.Fatalf("expected %d jobs waiting", n)
+}
+
+func TestQueueFull(t *testing.T) {
+	q := New(Config{Concurrency: 1, Depth: 2, PerClient: 1})
+
+	release, waited, err := q.Acquire(context.Background(), "a")
+	if err != nil || waited != 0 {
+		t.Fatalf("first job - expected to start at once, got waited=%v err=%v", waited, err)
+	}
+	order := make(chan string, 2)
+	acquireAsync(t, q, "a", order)
+	waitFor(t, q, 1)
+
+	var full *FullError
+	if _, _, err := q.Acquire(context.Background(), "a"); !errors.As(err, &full) {
+		t.Errorf("second job waiting for a - expected a *FullError, got=%v", err)
+	} else if full.RetryAfter < time.Second {
+		t.Errorf("expected RetryAfter of at least a second, got=%v", full.RetryAfter)
+	}
+
+	acquireAsync(t, q, "b", order)
+	waitFor(t, q, 2)
+	if _, _, err := q.Acquire(context.Background(), "c"); !errors.As(err, &full) {
+		t.Errorf("job beyond the depth - expected a *FullError, got=%v", err)
+	}
+
+	release()
+	for _, expected := range []string{"a", "b"} {
+		if got := <-order; got != expected {
+			t.Errorf("expected %s to run next, got=%s", expected, got)
+		}
+	}
+}
+
+func TestQueueTakesTurns(t *testing.T) {
+	q := New(Config{Concurrency: 1, Depth: 10})
+
+	release, _, err := q.Acquire(context.Background(), "busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan string, 6)
+	for i, client := range []string{"busy", "busy", "busy", "quiet", "other", "quiet"} {
+		acquireAsync(t, q, client, order)
+		waitFor(t, q, i+1)
+	}
+	release()
+
+	expected := []string{"busy", "quiet", "other", "busy", "quiet", "busy"}
+	for i, want := range expected {
+		if got := <-order; got != want {
+			t.Errorf("turn %d - expected %s, got=%s", i, want, got)
+		}
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	q := New(Config{Concurrency: 1, Depth: 1})
+
+	release, _, err := q.Acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, waited, err := q.Acquire(ctx, "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got=%v", err)
+	} else if waited < 20*time.Millisecond {
+		t.Errorf("expected to have waited at least 20ms, got=%v", waited)
+	}
+	if running, waiting := q.Len(); running != 1 || waiting != 0 {
+		t.Errorf("expected 1 running and 0 waiting, got=%d and %d", running, waiting)
+	}
+
+	release()
+	release() // a second call does nothing
+	if running, _ := q.Len(); running != 0 {
+		t.Errorf("expected nothing running, got=%d", running)
+	}
+}
@@ -0,0 +1,149 @@
+// Package ratelimit throttles clients with token buckets: every client has
+// a bucket of Burst tokens that refills at a steady rate, and each request
+// takes a token or is turned away.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is how many requests a client may make: Events per Per on average,
+// and up to Burst at once.
+type Limit struct {
+	Events int
+	Per    time.Duration
+	Burst  int
+}
+
+// units are the periods a Limit may be written with.
+var units = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+
+// ParseLimit parses a limit written as EVENTS/UNIT[:BURST], where the unit is
+// s, m or h, such as 60/m or 2/s:10. The burst defaults to EVENTS.
+func ParseLimit(s string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(s, ":")
+	events, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: %q is not EVENTS/UNIT[:BURST]", s)
+	}
+	var l Limit
+	var err error
+	if l.Events, err = strconv.Atoi(events); err != nil || l.Events <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: %q: events must be a positive number", s)
+	}
+	if l.Per, ok = units[unit]; !ok {
+		return Limit{}, fmt.Errorf("ratelimit: %q: unit must be s, m or h", s)
+	}
+	l.Burst = l.Events
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst <= 0 {
+			return Limit{}, fmt.Errorf("ratelimit: %q: burst must be a positive number", s)
+		}
+	}
+	return l, nil
+}
+
+func (l Limit) String() string {
+	unit := "s"
+	for name, d := range units {
+		if d == l.Per {
+			unit = name
+		}
+	}
+	return fmt.Sprintf("%d/%s:%d", l.Events, unit, l.Burst)
+}
+
+// rate is the tokens added to a bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Events) / l.Per.Seconds()
+}
+
+// Decision is the answer to one request, with what a client needs to know to
+// pace itself.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // tokens in a full bucket
+	Remaining  int           // tokens left after this request
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, if the request was turned away
+}
+
+// Limiter keeps a bucket for every client seen lately.
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter giving every client limit.
+func New(limit Limit) *Limiter {
+	return &Limiter{limit: limit, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// Allow takes a token from the bucket of the client named by key, reporting
+// whether there was one.
+func (l *Limiter) Allow(key string) Decision {
+	now := l.now()
+	rate, burst := l.limit.rate(), float64(l.limit.Burst)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	d := Decision{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((burst - b.tokens) / rate)
+	return d
+}
+
+// Len returns the number of clients with a bucket.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep forgets, at most once per refill time, the buckets that have filled
+// up since they were last used, as a new bucket would be the same.
+func (l *Limiter) sweep(now time.Time) {
+	fill := seconds(float64(l.limit.Burst) / l.limit.rate())
+	if now.Sub(l.swept) < fill {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
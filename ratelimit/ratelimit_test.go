@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Limit
+	}{
+		{"60/m", Limit{Events: 60, Per: time.Minute, Burst: 60}},
+		{"2/s:10", Limit{Events: 2, Per: time.Second, Burst: 10}},
+		{"100/h:5", Limit{Events: 100, Per: time.Hour, Burst: 5}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.input)
+		if err != nil {
+			t.Errorf("ParseLimit(%q) - unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseLimit(%q) - expected %+v, got=%+v", tt.input, tt.expected, got)
+		}
+		if again, _ := ParseLimit(got.String()); again != got {
+			t.Errorf("ParseLimit(%q) - %q does not parse back", tt.input, got.String())
+		}
+	}
+
+	for _, input := range []string{"", "60", "0/s", "5/d", "5/s:0", "bahut/s"} {
+		if _, err := ParseLimit(input); err == nil {
+			t.Errorf("ParseLimit(%q) - expected an error", input)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(Limit{Events: 1, Per: time.Second, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if d := l.Allow("a"); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("request %d - expected to be allowed with %d left, got=%+v", i, 2-i, d)
+		}
+	}
+	d := l.Allow("a")
+	if d.Allowed || d.RetryAfter != time.Second || d.Reset != 3*time.Second {
+		t.Errorf("expected to be turned away for a second, got=%+v", d)
+	}
+	if d := l.Allow("b"); !d.Allowed {
+		t.Error("expected another client to have its own bucket")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if d := l.Allow("a"); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("expected one token back after 1.5s, got=%+v", d)
+	}
+
+	now = now.Add(time.Minute)
+	l.Allow("c")
+	if n := l.Len(); n != 1 {
+		t.Errorf("expected idle buckets to be forgotten, %d left", n)
+	}
+}
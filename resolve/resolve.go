@@ -0,0 +1,140 @@
+// Package resolve works out, before a program runs, which declaration every
+// variable refers to. It follows the evaluator's block scoping: the program,
+// every block, every loop header and every pakad_bhai open a scope of their
+// own, and bhai_sun declares a name in the innermost one.
+package resolve
+
+import "github.com/ankush-web-eng/brolang/ast"
+
+// Info is what the resolver found out about a program.
+type Info struct {
+	// Decls maps every identifier naming a variable, including the names in
+	// bhai_sun statements, loop headers and pakad_bhai, to the name that
+	// declared it. Identifiers with no declaration in scope are left out.
+	Decls map[*ast.Identifier]*ast.Identifier
+	// Depths maps every identifier reading or assigning a variable to the
+	// number of scopes between it and the declaration, 0 meaning the
+	// innermost scope.
+	Depths map[*ast.Identifier]int
+}
+
+type resolver struct {
+	scopes []map[string]*ast.Identifier
+	info   *Info
+}
+
+// Program resolves every identifier in program.
+func Program(program *ast.Program) *Info {
+	r := &resolver{info: &Info{
+		Decls:  map[*ast.Identifier]*ast.Identifier{},
+		Depths: map[*ast.Identifier]int{},
+	}}
+	r.open()
+	ast.Walk(r, program)
+	return r.info
+}
+
+func (r *resolver) open() {
+	r.scopes = append(r.scopes, map[string]*ast.Identifier{})
+}
+
+func (r *resolver) close() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *resolver) declare(name *ast.Identifier) {
+	if name != nil {
+		r.scopes[len(r.scopes)-1][name.Value] = name
+		r.info.Decls[name] = name
+	}
+}
+
+// use records the declaration ident refers to, if one is in scope.
+func (r *resolver) use(ident *ast.Identifier) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if decl, ok := r.scopes[i][ident.Value]; ok {
+			r.info.Decls[ident] = decl
+			r.info.Depths[ident] = len(r.scopes) - 1 - i
+			return
+		}
+	}
+}
+
+func (r *resolver) walk(node ast.Node) {
+	if node != nil {
+		ast.Walk(r, node)
+	}
+}
+
+// Visit implements ast.Visitor, walking binding constructs by hand so a
+// value is resolved before the name it initialises is declared.
+func (r *resolver) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.LetStatement:
+		r.walk(n.Value)
+		r.declare(n.Name)
+		return nil
+
+	case *ast.AssignStatement:
+		r.walk(n.Value)
+		if n.Name != nil {
+			r.use(n.Name)
+		}
+		return nil
+
+	case *ast.Identifier:
+		r.use(n)
+
+	case *ast.CallExpression:
+		// The callee is a builtin name, not a variable.
+		for _, arg := range n.Arguments {
+			r.walk(arg)
+		}
+		return nil
+
+	case *ast.BlockStatement:
+		r.open()
+		for _, stmt := range n.Statements {
+			r.walk(stmt)
+		}
+		r.close()
+		return nil
+
+	case *ast.WhileExpression:
+		r.open()
+		r.walk(n.Condition)
+		r.walk(n.Body)
+		r.close()
+		return nil
+
+	case *ast.ForExpression:
+		r.open()
+		r.walk(n.Init)
+		r.walk(n.Condition)
+		r.walk(n.Body)
+		r.walk(n.Update)
+		r.close()
+		return nil
+
+	case *ast.ForEachExpression:
+		r.open()
+		r.walk(n.Iterable)
+		r.declare(n.Index)
+		r.declare(n.Value)
+		r.walk(n.Body)
+		r.close()
+		return nil
+
+	case *ast.TryStatement:
+		r.walk(n.Body)
+		if n.Catch != nil {
+			r.open()
+			r.declare(n.CatchName)
+			r.walk(n.Catch)
+			r.close()
+		}
+		r.walk(n.Finally)
+		return nil
+	}
+	return r
+}
@@ -0,0 +1,73 @@
+package resolve
+
+import (
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/parser"
+)
+
+func TestProgram(t *testing.T) {
+	input := `bhai_sun x = 1;
+chal_bhai (bhai_sun i = 0; i < 2; i = i + 1) {
+    bhai_sun x = x + i;
+    agar (x > 1) {
+        x = i;
+    }
+}
+bol_bhai(x);
+bol_bhai(missing);`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	info := Program(program)
+
+	type use struct {
+		line, column int // position of the identifier
+		declLine     int // line of the declaration, 0 if unresolved
+		depth        int
+	}
+	tests := []use{
+		{2, 28, 2, 0}, // i < 2, in the loop's scope
+		{3, 18, 1, 2}, // x + i reads the outer x: body, loop, program
+		{3, 22, 2, 1},
+		{4, 11, 3, 0}, // the x declared in the body
+		{5, 9, 3, 1},  // x = i inside agar
+		{5, 13, 2, 2},
+		{8, 10, 1, 0},
+		{9, 10, 0, 0},
+	}
+
+	idents := map[[2]int]*ast.Identifier{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok {
+			idents[[2]int{ident.Token.Line, ident.Token.Column}] = ident
+		}
+		return true
+	})
+
+	for _, tt := range tests {
+		ident, ok := idents[[2]int{tt.line, tt.column}]
+		if !ok {
+			t.Fatalf("no identifier at %d:%d", tt.line, tt.column)
+		}
+		decl, resolved := info.Decls[ident]
+		if tt.declLine == 0 {
+			if resolved {
+				t.Errorf("%s at %d:%d - expected no declaration, got line %d", ident.Value, tt.line, tt.column, decl.Token.Line)
+			}
+			continue
+		}
+		if !resolved || decl.Token.Line != tt.declLine {
+			t.Errorf("%s at %d:%d - expected the declaration on line %d, got=%v", ident.Value, tt.line, tt.column, tt.declLine, decl)
+			continue
+		}
+		if depth := info.Depths[ident]; depth != tt.depth {
+			t.Errorf("%s at %d:%d - expected depth %d, got=%d", ident.Value, tt.line, tt.column, tt.depth, depth)
+		}
+	}
+}
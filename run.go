@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/runner"
+	"github.com/ankush-web-eng/brolang/trace"
+)
+
+// runCommand implements `brolang run [flags] [file]`, evaluating a program
+// from a file (or stdin) and printing its output.
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	typeCheck := fs.Bool("typecheck", false, "run the static type checker before evaluating")
+	noOptimize := fs.Bool("no-optimize", false, "evaluate the program exactly as parsed")
+	traceEvents := fs.Bool("trace", false, "log every statement, assignment, loop iteration and error to stderr")
+	profile := fs.Bool("profile", false, "print hits and time per line to stderr")
+	legacyScoping := fs.Bool("legacy-scoping", false, "let agar bodies share the outer scope and assignment declare missing variables, as before")
+	fs.Parse(args)
+
+	name := "-"
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+
+	code, err := readSource(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang run: %v\n", err)
+		return 1
+	}
+
+	opts := runner.Options{
+		TypeCheck:        *typeCheck,
+		DisableOptimizer: *noOptimize,
+		Profile:          *profile,
+		LegacyScoping:    *legacyScoping,
+	}
+	if *traceEvents {
+		opts.Tracer = trace.NewPrinter(os.Stderr)
+	}
+	if name != "-" {
+		// suna_bhai reads from stdin unless the program itself came from there.
+		opts.Stdin = os.Stdin
+	}
+	res := runner.Run(code, opts)
+
+	fmt.Print(res.Output)
+	if *profile {
+		trace.WriteReport(os.Stderr, res.Profile)
+	}
+	if res.Error != "" {
+		fmt.Fprintln(os.Stderr, res.Error)
+		for _, frame := range res.Stack {
+			fmt.Fprintf(os.Stderr, "\t%s\n", frame)
+		}
+		return 1
+	}
+	return 0
+}
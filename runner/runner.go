@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/evaluator"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/optimize"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/trace"
+	"github.com/ankush-web-eng/brolang/typecheck"
+)
+
+// Options selects the passes that run between parsing and evaluation.
+type Options struct {
+	TypeCheck        bool            // reject programs with static type errors before running them
+	DisableOptimizer bool            // evaluate the program exactly as it was parsed; implied by Hook, Tracer and Profile
+	Stdin            io.Reader       // lines read by suna_bhai; nil means no input
+	Hook             object.Hook     // called before every statement, e.g. by a debugger
+	Tracer           object.Tracer   // receives every step of the program
+	Profile          bool            // count hits and time per line into Result.Profile
+	LegacyScoping    bool            // agar bodies share the outer scope and assignment can declare
+	Context          context.Context // once it ends the program stops with an error; nil means never
+}
+
+// Result is the outcome of running a program.
+type Result struct {
+	Output      string                  // everything the program printed
+	Error       string                  // the parse, type or runtime error, if any
+	ErrorCode   string                  // the code of a runtime error, one of object's Code constants
+	Diagnostics []diagnostic.Diagnostic // positioned parse or type errors
+	Profile     []trace.LineProfile     // per-line hits and time, if Options.Profile was set
+	Stack       []object.Frame          // where a runtime error happened, innermost first
+}
+
+// Run lexes, parses, optionally checks and optimizes, and evaluates a program
+// in a fresh environment.
+func Run(code string, opts Options) *Result {
+	l := lexer.New(code)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	// if there are any errors while parsing the code, return the error
+	if len(p.Errors()) > 0 {
+		return &Result{
+			Error:       strings.Join(p.Errors(), " ") + " ",
+			Diagnostics: p.Diagnostics(),
+		}
+	}
+
+	if opts.TypeCheck {
+		if _, diags := typecheck.Check(program); len(diags) > 0 {
+			var msg strings.Builder
+			for _, d := range diags {
+				msg.WriteString(d.String())
+				msg.WriteString(" ")
+			}
+			return &Result{Error: msg.String(), Diagnostics: diags}
+		}
+	}
+
+	// The optimizer drops and merges statements, which a hook, tracer or
+	// profiler would see as different line hits.
+	if !opts.DisableOptimizer && opts.Hook == nil && opts.Tracer == nil && !opts.Profile {
+		optimize.Optimize(program)
+	}
+
+	env := object.NewEnvironment()
+	if opts.Stdin != nil {
+		env.Input = bufio.NewReader(opts.Stdin)
+	}
+	env.Hook = opts.Hook
+	if opts.Context != nil {
+		env.Hook = stopHook{ctx: opts.Context, next: opts.Hook}
+	}
+	env.Tracer = opts.Tracer
+	env.LegacyScoping = opts.LegacyScoping
+	var profiler *trace.Profiler
+	if opts.Profile {
+		profiler = trace.NewProfiler()
+		if opts.Tracer != nil {
+			env.Tracer = trace.Multi(opts.Tracer, profiler)
+		} else {
+			env.Tracer = profiler
+		}
+	}
+	result := evaluator.Eval(program, env)
+
+	res := &Result{Output: env.OutputBuilder.String()}
+	if profiler != nil {
+		res.Profile = profiler.Report()
+	}
+	if err, ok := result.(*object.Error); ok {
+		res.Error = err.Inspect()
+		res.ErrorCode = err.Code
+		res.Stack = err.Stack
+	}
+	return res
+}
+
+// stopHook stops a program when its context ends, before handing each
+// statement to the next hook, if any.
+type stopHook struct {
+	ctx  context.Context
+	next object.Hook
+}
+
+func (h stopHook) BeforeStatement(stmt ast.Statement, env *object.Environment) error {
+	if h.ctx.Err() != nil {
+		return errStopped
+	}
+	if h.next != nil {
+		return h.next.BeforeStatement(stmt, env)
+	}
+	return nil
+}
+
+var errStopped = errors.New("Program beech me hi rok diya bhai, server band ho raha h ya tu chala gaya")
@@ -0,0 +1,64 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// unlimited is RLIM_INFINITY, which package syscall declares as -1.
+const unlimited = ^uint64(0)
+
+// applyLimits sets the worker's resource limits for its next job. RLIMIT_CPU
+// counts the whole life of the process, so the job's budget is added to what
+// the worker has used so far. RLIMIT_DATA rather than RLIMIT_AS bounds memory,
+// since the Go runtime reserves far more address space than it ever uses.
+func applyLimits(l Limits) error {
+	cpu := unlimited
+	if l.CPUTime > 0 {
+		var usage syscall.Rusage
+		if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+			return err
+		}
+		used := time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+		// The limit is in whole seconds; round up so a job gets at least its budget.
+		cpu = uint64((used + l.CPUTime + time.Second - 1) / time.Second)
+	}
+	if err := setSoftLimit(syscall.RLIMIT_CPU, cpu); err != nil {
+		return fmt.Errorf("cpu: %v", err)
+	}
+
+	memory := unlimited
+	if l.Memory > 0 {
+		memory = uint64(l.Memory)
+	}
+	if err := setSoftLimit(syscall.RLIMIT_DATA, memory); err != nil {
+		return fmt.Errorf("memory: %v", err)
+	}
+	return nil
+}
+
+// setSoftLimit changes only the soft limit, which an unprivileged process can
+// raise again for the next job as long as it stays under the hard limit.
+func setSoftLimit(resource int, value uint64) error {
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(resource, &lim); err != nil {
+		return err
+	}
+	lim.Cur = min(value, lim.Max)
+	return syscall.Setrlimit(resource, &lim)
+}
+
+// watchCPU makes the worker exit with exitCPULimit when the kernel reports
+// that it has used up its CPU time. Go ignores SIGXCPU unless asked for it.
+func watchCPU() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGXCPU)
+	go func() {
+		<-ch
+		fmt.Fprintln(os.Stderr, "brolang worker: cpu time limit exceeded")
+		os.Exit(exitCPULimit)
+	}()
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sandbox
+
+// applyLimits does nothing outside Linux; jobs are bounded only by the pool's
+// timeout and the output limit.
+func applyLimits(l Limits) error { return nil }
+
+func watchCPU() {}
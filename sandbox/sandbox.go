@@ -0,0 +1,367 @@
+// Package sandbox runs programs in separate worker processes, so a program
+// that exhausts memory, overflows the Go stack or spins forever takes down
+// only its worker, not the server. Workers are started ahead of time and kept
+// in a pool; each runs one job at a time under CPU, memory and output limits.
+//
+// A worker is the brolang binary started as `brolang worker` (see Serve). The
+// server writes each Job to its stdin as a line of JSON and reads the
+// runner.Result back from its stdout the same way.
+package sandbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/runner"
+)
+
+// Limits bound what one job may use. Zero values mean no limit. CPU and
+// memory are enforced by the operating system where it supports it (Linux).
+type Limits struct {
+	CPUTime time.Duration `json:"cpuTime,omitempty"` // processor time for the job
+	Memory  int64         `json:"memory,omitempty"`  // bytes of data the worker may hold
+	Output  int           `json:"output,omitempty"`  // bytes of program output kept
+}
+
+// DefaultLimits are used by NewPool when Config.Limits is left empty.
+var DefaultLimits = Limits{
+	CPUTime: 5 * time.Second,
+	Memory:  512 << 20,
+	Output:  1 << 20,
+}
+
+// Job is a program and how to run it, as sent to a worker.
+type Job struct {
+	Code             string `json:"code"`
+	Stdin            string `json:"stdin,omitempty"`
+	TypeCheck        bool   `json:"typeCheck,omitempty"`
+	DisableOptimizer bool   `json:"disableOptimizer,omitempty"`
+	Profile          bool   `json:"profile,omitempty"`
+	LegacyScoping    bool   `json:"legacyScoping,omitempty"`
+	Limits           Limits `json:"limits"`
+}
+
+// Reasons a worker can die while running a job.
+const (
+	ReasonTimeout       = "timeout"
+	ReasonCPULimit      = "cpu-limit"
+	ReasonMemoryLimit   = "memory-limit"
+	ReasonStackOverflow = "stack-overflow"
+	ReasonCrashed       = "crashed"
+)
+
+// CrashError reports a job whose worker died or had to be killed. The worker
+// is replaced; the rest of the pool is unaffected.
+type CrashError struct {
+	Reason string `json:"reason"`           // one of the Reason constants
+	Detail string `json:"detail,omitempty"` // what the worker said as it died, if anything
+}
+
+func (e *CrashError) Error() string {
+	switch e.Reason {
+	case ReasonTimeout:
+		return "Bhai itna time kaun deta h? Program ne waqt khatam kar diya!!"
+	case ReasonCPULimit:
+		return "CPU ka bill bhi main bharu? Program ne CPU ki had paar kar di!!"
+	case ReasonMemoryLimit:
+		return "Itni memory kaha se laun bhai? Program ne memory ki had paar kar di!!"
+	case ReasonStackOverflow:
+		return "Itna gehra kaun likhta h bhai? Program ka stack phat gaya!!"
+	}
+	return "Program ne worker hi uda diya bhai!!"
+}
+
+// Config describes a pool.
+type Config struct {
+	// Command starts a worker. It defaults to this executable run as
+	// `worker`, which is right for the brolang binary itself.
+	Command []string
+	// Env is added to the environment of every worker, which otherwise
+	// inherits only a few harmless variables from this process.
+	Env []string
+	// Size is the number of workers, and so of jobs running at once. It
+	// defaults to 1.
+	Size int
+	// Timeout bounds the wall-clock time of a job, including time spent
+	// waiting for input or sleeping; 0 means no limit beyond Limits.CPUTime.
+	Timeout time.Duration
+	// Limits applies to every job that does not set its own.
+	Limits Limits
+}
+
+// passEnv lists the variables a worker inherits from the server. Everything
+// else, passwords and API keys included, stays out of reach of the programs
+// it runs.
+var passEnv = []string{"PATH", "TMPDIR", "TZ", "LANG", "GOMAXPROCS", "GOGC", "GODEBUG"}
+
+func workerEnv() []string {
+	var env []string
+	for _, name := range passEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// Pool runs jobs on a fixed number of worker processes.
+type Pool struct {
+	cfg   Config
+	slots chan *worker // idle workers; nil stands for one that must be started
+
+	mu     sync.Mutex
+	closed bool
+	busy   map[*worker]bool
+}
+
+// NewPool starts cfg.Size workers.
+func NewPool(cfg Config) (*Pool, error) {
+	if len(cfg.Command) == 0 {
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("sandbox: finding the worker executable: %v", err)
+		}
+		cfg.Command = []string{exe, "worker"}
+	}
+	if cfg.Size < 1 {
+		cfg.Size = 1
+	}
+	if cfg.Limits == (Limits{}) {
+		cfg.Limits = DefaultLimits
+	}
+
+	p := &Pool{cfg: cfg, slots: make(chan *worker, cfg.Size), busy: map[*worker]bool{}}
+	for i := 0; i < cfg.Size; i++ {
+		w, err := p.start()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.slots <- w
+	}
+	return p, nil
+}
+
+// Run runs a job on the next free worker, waiting for one if all are busy.
+// A job that kills its worker gets a *CrashError; other errors mean the job
+// could not be run at all.
+func (p *Pool) Run(ctx context.Context, job Job) (*runner.Result, error) {
+	if job.Limits == (Limits{}) {
+		job.Limits = p.cfg.Limits
+	}
+
+	var w *worker
+	select {
+	case w = <-p.slots:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if w == nil {
+		var err error
+		if w, err = p.start(); err != nil {
+			p.slots <- nil
+			return nil, err
+		}
+	}
+
+	if !p.acquire(w) {
+		w.kill()
+		return nil, errors.New("sandbox: pool is closed")
+	}
+	res, err := w.run(ctx, &job, p.cfg.Timeout)
+	p.release(w)
+
+	if err != nil {
+		// The worker is gone or in an unknown state; start afresh next time.
+		w.kill()
+		p.slots <- nil
+		return nil, err
+	}
+	p.slots <- w
+	return res, nil
+}
+
+// Close stops every worker. Jobs still running fail.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	for w := range p.busy {
+		w.kill()
+	}
+	p.mu.Unlock()
+
+	for {
+		select {
+		case w := <-p.slots:
+			if w != nil {
+				w.kill()
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *Pool) acquire(w *worker) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.busy[w] = true
+	return true
+}
+
+func (p *Pool) release(w *worker) {
+	p.mu.Lock()
+	delete(p.busy, w)
+	p.mu.Unlock()
+}
+
+func (p *Pool) start() (*worker, error) {
+	cmd := exec.Command(p.cfg.Command[0], p.cfg.Command[1:]...)
+	cmd.Env = append(workerEnv(), p.cfg.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	w := &worker{
+		cmd:    cmd,
+		stdin:  stdin,
+		enc:    json.NewEncoder(stdin),
+		dec:    json.NewDecoder(bufio.NewReader(stdout)),
+		stderr: &head{max: 4096},
+		exited: make(chan struct{}),
+	}
+	cmd.Stderr = w.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("sandbox: starting worker: %v", err)
+	}
+	go func() {
+		cmd.Wait()
+		close(w.exited)
+	}()
+	return w, nil
+}
+
+// worker is one running worker process.
+type worker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	enc    *json.Encoder
+	dec    *json.Decoder
+	stderr *head
+	exited chan struct{} // closed once the process has exited and been reaped
+}
+
+// run sends a job and waits for its result, killing the worker if it takes
+// longer than timeout or ctx is cancelled.
+func (w *worker) run(ctx context.Context, job *Job, timeout time.Duration) (*runner.Result, error) {
+	if err := w.enc.Encode(job); err != nil {
+		return nil, w.crash()
+	}
+
+	done := make(chan error, 1)
+	var res runner.Result
+	go func() { done <- w.dec.Decode(&res) }()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, w.crash()
+		}
+		return &res, nil
+	case <-expired:
+		w.kill()
+		<-done
+		return nil, &CrashError{Reason: ReasonTimeout}
+	case <-ctx.Done():
+		w.kill()
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+// crash waits for a worker that stopped answering to exit and works out why
+// it died.
+func (w *worker) crash() *CrashError {
+	w.stdin.Close()
+	select {
+	case <-w.exited:
+	case <-time.After(time.Second):
+		w.kill()
+	}
+
+	detail := firstLine(w.stderr.String())
+	reason := ReasonCrashed
+	switch {
+	case w.cmd.ProcessState != nil && w.cmd.ProcessState.ExitCode() == exitCPULimit:
+		reason = ReasonCPULimit
+	case strings.Contains(detail, "out of memory") || strings.Contains(detail, "cannot allocate memory"):
+		reason = ReasonMemoryLimit
+	case strings.Contains(detail, "stack overflow"):
+		reason = ReasonStackOverflow
+	}
+	return &CrashError{Reason: reason, Detail: detail}
+}
+
+// firstLine picks the line of a worker's stderr that says why it died: the Go
+// runtime's "fatal error" or "panic" line if there is one, otherwise the first.
+func firstLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, "fatal error:") || strings.HasPrefix(line, "panic:") {
+			return line
+		}
+	}
+	return lines[0]
+}
+
+func (w *worker) kill() {
+	w.stdin.Close()
+	w.cmd.Process.Kill()
+	<-w.exited
+}
+
+// head keeps the first max bytes written to it. A dying Go program says why
+// before dumping its goroutines.
+type head struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (h *head) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if room := h.max - len(h.buf); room > 0 {
+		h.buf = append(h.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func (h *head) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return string(h.buf)
+}
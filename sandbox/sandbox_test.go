@@ -0,0 +1,178 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary double as a worker, so the tests do not need
+// a built brolang binary.
+func TestMain(m *testing.M) {
+	if os.Getenv("BROLANG_SANDBOX_WORKER") == "1" {
+		if err := Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "worker: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// spin runs for a long time without printing or allocating; the evaluator
+// caps a single loop at 10000 iterations, so it nests two.
+const spin = "chal_bhai (bhai_sun i me 0..9000) { chal_bhai (bhai_sun j me 0..9000) { } }"
+
+func newTestPool(t *testing.T, cfg Config) *Pool {
+	t.Helper()
+	cfg.Command = []string{os.Args[0]}
+	cfg.Env = []string{"BROLANG_SANDBOX_WORKER=1"}
+	pool, err := NewPool(cfg)
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestPoolRun(t *testing.T) {
+	pool := newTestPool(t, Config{Size: 2})
+
+	tests := []struct {
+		job            Job
+		expectedOutput string
+		expectedError  string
+	}{
+		{Job{Code: "bhai_sun x = 5; bol_bhai(x * 2);"}, "10\n", ""},
+		{Job{Code: "bhai_sun naam = suna_bhai(); bol_bhai(naam);", Stdin: "Ankush\n"}, "Ankush\n", ""},
+		{Job{Code: "bol_bhai(1); bol_bhai(y);"}, "1\n", "bhai galati kardi tune"},
+		{
+			Job{Code: "chal_bhai (bhai_sun i = 0; i < 100; i = i + 1) { bol_bhai(i); }", Limits: Limits{Output: 8}},
+			"0\n1\n2\n3\n",
+			"8 bytes se zyada nahi chhapunga",
+		},
+	}
+
+	for i, tt := range tests {
+		res, err := pool.Run(context.Background(), tt.job)
+		if err != nil {
+			t.Fatalf("test[%d] - Run: %v", i, err)
+		}
+		if res.Output != tt.expectedOutput {
+			t.Errorf("test[%d] - expected output %q, got=%q", i, tt.expectedOutput, res.Output)
+		}
+		if tt.expectedError == "" && res.Error != "" {
+			t.Errorf("test[%d] - unexpected error %q", i, res.Error)
+		}
+		if !strings.Contains(res.Error, tt.expectedError) {
+			t.Errorf("test[%d] - expected error containing %q, got=%q", i, tt.expectedError, res.Error)
+		}
+	}
+}
+
+func TestPoolCrash(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            Config
+		code           string
+		expectedReason string
+		linuxOnly      bool
+	}{
+		{
+			"timeout",
+			Config{Timeout: 200 * time.Millisecond},
+			spin,
+			ReasonTimeout,
+			false,
+		},
+		{
+			"cpu",
+			Config{Limits: Limits{CPUTime: time.Second}},
+			spin,
+			ReasonCPULimit,
+			true,
+		},
+		{
+			"memory",
+			Config{Limits: Limits{Memory: 128 << 20}},
+			"bhai_sun a = [1]; chal_bhai (bhai_sun i me 0..9000) { chal_bhai (bhai_sun j me 0..9000) { jod_bhai(a, j); } }",
+			ReasonMemoryLimit,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.linuxOnly && runtime.GOOS != "linux" {
+				t.Skip("limits are only enforced on linux")
+			}
+			pool := newTestPool(t, tt.cfg)
+
+			_, err := pool.Run(context.Background(), Job{Code: tt.code})
+			var crash *CrashError
+			if !errors.As(err, &crash) {
+				t.Fatalf("expected a *CrashError, got=%v", err)
+			}
+			if crash.Reason != tt.expectedReason {
+				t.Errorf("expected reason %q, got=%q (%s)", tt.expectedReason, crash.Reason, crash.Detail)
+			}
+
+			// The crashed worker is replaced and the pool keeps working.
+			res, err := pool.Run(context.Background(), Job{Code: "bol_bhai(42);"})
+			if err != nil {
+				t.Fatalf("Run after crash: %v", err)
+			}
+			if res.Output != "42\n" {
+				t.Errorf("expected output %q after crash, got=%q", "42\n", res.Output)
+			}
+		})
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	pool := newTestPool(t, Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Run(ctx, Job{Code: spin}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got=%v", err)
+	}
+}
+
+func TestWorkerEnv(t *testing.T) {
+	t.Setenv("BROLANG_REDIS_PASSWORD", "hunter2")
+	t.Setenv("TZ", "Asia/Kolkata")
+
+	env := strings.Join(workerEnv(), "\n")
+	if strings.Contains(env, "hunter2") {
+		t.Errorf("worker environment leaks the server's secrets: %q", env)
+	}
+	if !strings.Contains(env, "TZ=Asia/Kolkata") {
+		t.Errorf("worker environment lost TZ: %q", env)
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	tests := []struct {
+		stderr   string
+		expected string
+	}{
+		{"", ""},
+		{"brolang worker: cpu time limit exceeded\n", "brolang worker: cpu time limit exceeded"},
+		{
+			"runtime: goroutine stack exceeds 67108864-byte limit\nruntime: sp=0xc0200e0390\nfatal error: stack overflow\n\ngoroutine 1 [running]:\n",
+			"fatal error: stack overflow",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := firstLine(tt.stderr); got != tt.expected {
+			t.Errorf("firstLine(%q) - expected %q, got=%q", tt.stderr, tt.expected, got)
+		}
+	}
+}
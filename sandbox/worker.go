@@ -0,0 +1,64 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+
+	"github.com/ankush-web-eng/brolang/runner"
+)
+
+// exitCPULimit is the status a worker exits with when it runs out of CPU time.
+const exitCPULimit = 3
+
+// CodeOutputLimit is the error code of a result cut off at the output limit.
+const CodeOutputLimit = "output-limit"
+
+// maxStack bounds the Go stack of a worker, so deeply nested programs fail
+// quickly instead of growing towards the runtime's default of a gigabyte.
+const maxStack = 64 << 20
+
+// Serve is the worker side of the protocol: it reads jobs from r, runs each
+// one and writes its result to w, until r is closed. Output beyond the job's
+// limit is cut off and reported as an error.
+func Serve(r io.Reader, w io.Writer) error {
+	debug.SetMaxStack(maxStack)
+	watchCPU()
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	enc := json.NewEncoder(w)
+	for {
+		var job Job
+		if err := dec.Decode(&job); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading job: %v", err)
+		}
+		if err := applyLimits(job.Limits); err != nil {
+			return fmt.Errorf("applying limits: %v", err)
+		}
+
+		opts := runner.Options{
+			TypeCheck:        job.TypeCheck,
+			DisableOptimizer: job.DisableOptimizer,
+			Profile:          job.Profile,
+			LegacyScoping:    job.LegacyScoping,
+		}
+		if job.Stdin != "" {
+			opts.Stdin = strings.NewReader(job.Stdin)
+		}
+		res := runner.Run(job.Code, opts)
+		if limit := job.Limits.Output; limit > 0 && len(res.Output) > limit {
+			res.Output = res.Output[:limit]
+			res.Error = fmt.Sprintf("bhai galati kardi tune Itna output kaun padhega? %d bytes se zyada nahi chhapunga!!", limit)
+			res.ErrorCode = CodeOutputLimit
+			res.Stack = nil
+		}
+		if err := enc.Encode(res); err != nil {
+			return fmt.Errorf("writing result: %v", err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/api/handler"
+	"github.com/ankush-web-eng/brolang/cache"
+	"github.com/ankush-web-eng/brolang/config"
+	"github.com/ankush-web-eng/brolang/pubsub"
+	"github.com/ankush-web-eng/brolang/queue"
+	"github.com/ankush-web-eng/brolang/ratelimit"
+	"github.com/ankush-web-eng/brolang/sandbox"
+	"github.com/ankush-web-eng/brolang/snippet"
+	"github.com/go-redis/redis/v8"
+)
+
+// serve implements `brolang serve [flags]`, the HTTP API. See package config
+// for its settings.
+func serve(args []string) int {
+	cfg, err := config.Load("serve", args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang serve: %v\n", err)
+		return 2
+	}
+	slog.SetDefault(newLogger(cfg))
+
+	handler.SetConfig(cfg)
+	handler.SetQueue(queue.New(queue.Config{Concurrency: cfg.Concurrency, Depth: cfg.Queue, PerClient: cfg.PerClient}))
+	handler.TrustProxy = cfg.TrustProxy
+	handler.JudgeTimeout = judgeTimeouts * cfg.Timeout
+	handler.SetAPIKeys(cfg.APIKeys)
+	if cfg.CacheSize > 0 {
+		handler.SetCache(cache.NewLRU(cache.Config{MaxEntries: cfg.CacheEntries, MaxBytes: cfg.CacheSize, TTL: cfg.CacheTTL}))
+	}
+
+	if cfg.Sandbox {
+		workers := cfg.Workers
+		if workers == 0 {
+			workers = cfg.Concurrency
+		}
+		pool, err := sandbox.NewPool(sandbox.Config{Size: workers, Timeout: cfg.Timeout, Limits: cfg.Limits})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brolang serve: %v\n", err)
+			return 1
+		}
+		defer pool.Close()
+		handler.SetSandbox(pool)
+	}
+
+	snippets, err := snippet.NewDir(cfg.Snippets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang serve: %v\n", err)
+		return 1
+	}
+	handler.SetSnippetStore(snippets)
+
+	if cfg.Broker == "redis" {
+		client := redis.NewClient(&redis.Options{Addr: cfg.Redis, Password: cfg.RedisPassword})
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "brolang serve: connecting to Redis: %v\n", err)
+			return 1
+		}
+		handler.SetBroker(pubsub.NewRedis(client, cfg.Keep))
+	} else {
+		handler.SetBroker(pubsub.NewMemory(cfg.Keep))
+	}
+	defer handler.Broker.Close()
+
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang serve: %v\n", err)
+		return 1
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	slog.Info("listening", "addr", ln.Addr().String())
+	if err := run(ctx, newServer(cfg), ln, cfg.Grace); err != nil {
+		fmt.Fprintf(os.Stderr, "brolang serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// stopWait is how long programs get to stop once cancelled, after the grace
+// period, before their connections are closed under them.
+const stopWait = 5 * time.Second
+
+// judgeTimeouts is how many -timeouts all the cases of one /judge request
+// get together, so a request of many slow cases cannot hold its turn in the
+// queue for long.
+const judgeTimeouts = 3
+
+// newServer returns the API server, with every route.
+func newServer(cfg *config.Config) *http.Server {
+	mux := http.NewServeMux()
+	// Probes are neither logged nor counted.
+	mux.HandleFunc("/healthz", handler.HealthHandler)
+	mux.HandleFunc("/readyz", handler.ReadyHandler)
+
+	route := func(pattern string, h http.HandlerFunc) {
+		h = handler.LimitBody(cfg.MaxBody, h)
+		if limit, ok := cfg.RateLimits[pattern]; ok {
+			h = handler.RateLimit(pattern, ratelimit.New(limit), h)
+		}
+		mux.HandleFunc(pattern, handler.Instrument(pattern, corsMiddleware(cfg, h)))
+	}
+	route("/compile", handler.CompilerHandler)
+	route("/lint", handler.LintHandler)
+	route("/judge", handler.JudgeHandler)
+	route("/jobs", handler.SubmitJobHandler)
+	route("/jobs/{id}", handler.JobHandler)
+	route("/jobs/{id}/events", handler.JobEventsHandler)
+	route("/snippets", handler.SaveSnippetHandler)
+	route("/snippets/{id}", handler.SnippetHandler)
+	route("/config", handler.ConfigHandler)
+	route("/metrics", handler.MetricsHandler)
+
+	return &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       2 * time.Minute,
+		BaseContext:       handler.BaseContext,
+	}
+}
+
+// run serves srv on ln until ctx ends, then shuts down: the server turns
+// unready and stops taking programs, and the ones running get grace to
+// finish before they are stopped.
+func run(ctx context.Context, srv *http.Server, ln net.Listener, grace time.Duration) error {
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "grace", grace)
+	handler.Drain()
+	graceCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	err := srv.Shutdown(graceCtx)
+	if err == nil {
+		err = handler.WaitJobs(graceCtx)
+	}
+	if err == nil {
+		return nil
+	}
+
+	slog.Warn("grace period over, stopping running programs")
+	handler.Stop()
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopWait)
+	defer cancel()
+	if err := srv.Shutdown(stopCtx); err != nil {
+		srv.Close()
+	}
+	handler.WaitJobs(stopCtx)
+	return nil
+}
+
+// newLogger returns a logger writing to stderr as cfg says.
+func newLogger(cfg *config.Config) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+func corsMiddleware(cfg *config.Config, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && cfg.AllowOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After, X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
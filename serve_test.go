@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/api/handler"
+	"github.com/ankush-web-eng/brolang/config"
+	"github.com/ankush-web-eng/brolang/pubsub"
+)
+
+// forever runs far longer than any test waits.
+const forever = `bhai_sun n = 0;
+chal_bhai (bhai_sun i me 0..9000) {
+    chal_bhai (bhai_sun j me 0..9000) {
+        n = n + 1;
+    }
+}`
+
+func TestShutdown(t *testing.T) {
+	handler.SetBroker(pubsub.NewMemory(time.Minute))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, newServer(config.Default()), ln, 100*time.Millisecond) }()
+
+	resp, err := http.Get(base + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the server to be ready, got status %d", resp.StatusCode)
+	}
+
+	// One program runs in a request, another as a background job.
+	body, _ := json.Marshal(handler.JobRequest{CompileRequest: handler.CompileRequest{Code: forever}, RequestID: "forever"})
+	resp, err = http.Post(base+"/jobs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	compiled := make(chan handler.CompileResponse, 1)
+	go func() {
+		body, _ := json.Marshal(handler.CompileRequest{Code: forever})
+		resp, err := http.Post(base+"/compile", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			close(compiled)
+			return
+		}
+		defer resp.Body.Close()
+		var response handler.CompileResponse
+		json.NewDecoder(resp.Body).Decode(&response)
+		compiled <- response
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(stopWait):
+		t.Fatal("the server did not shut down")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected running programs to get the grace period, shut down after %v", elapsed)
+	}
+
+	if response := <-compiled; !strings.Contains(response.Error, "rok diya") {
+		t.Errorf("expected the running program to be stopped, got=%+v", response)
+	}
+	msg, _, _ := handler.Broker.Last(context.Background(), "brolang:job:forever")
+	var status handler.JobStatus
+	json.Unmarshal(msg, &status)
+	if status.Status != handler.JobDone || status.Result == nil || !strings.Contains(status.Result.Error, "rok diya") {
+		t.Errorf("expected the background job to be stopped and done, got=%s", msg)
+	}
+
+	if _, err := http.Get(base + "/healthz"); err == nil {
+		t.Error("expected the server to stop listening")
+	}
+	w := httptest.NewRecorder()
+	handler.ReadyHandler(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a draining server to be unready, got status %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.CompilerHandler(w, httptest.NewRequest("POST", "/compile", strings.NewReader(`{"code": "bol_bhai(1);"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected a draining server to turn programs away, got status %d", w.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package snippet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Dir is a Store keeping each snippet as a JSON file in a directory, so the
+// server needs no database.
+type Dir struct {
+	path string
+}
+
+// NewDir returns a store in the directory at path, creating it if needed.
+func NewDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir{path: path}, nil
+}
+
+func (d *Dir) file(id string) string {
+	return filepath.Join(d.path, id+".json")
+}
+
+func (d *Dir) Save(ctx context.Context, s *Snippet) error {
+	if !ValidID(s.ID) {
+		return fmt.Errorf("snippet: invalid ID %q", s.ID)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	// Write the whole file under a temporary name, then link it into place:
+	// readers never see half a snippet and an existing one is never replaced.
+	tmp, err := os.CreateTemp(d.path, ".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Link(tmp.Name(), d.file(s.ID)); errors.Is(err, fs.ErrExist) {
+		return ErrExists
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *Dir) Get(ctx context.Context, id string) (*Snippet, error) {
+	if !ValidID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(d.file(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var s Snippet
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,72 @@
+// Package snippet stores programs so they can be shared by a short ID.
+//
+// IDs are derived from the program and its input, so sharing the same
+// program twice gives the same link.
+package snippet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// Snippet is a shared program.
+type Snippet struct {
+	ID      string    `json:"id"`
+	Code    string    `json:"code"`
+	Stdin   string    `json:"stdin,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Store keeps snippets.
+type Store interface {
+	// Save stores s under s.ID, or returns ErrExists if the ID is taken.
+	Save(ctx context.Context, s *Snippet) error
+	// Get returns the snippet with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Snippet, error)
+}
+
+var (
+	ErrExists   = errors.New("snippet: ID already taken")
+	ErrNotFound = errors.New("snippet: not found")
+)
+
+// idLength is the length of a new ID; a longer one is used only if two
+// snippets would otherwise share it.
+const idLength = 8
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidID reports whether id could name a snippet.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Create saves a program and its input in store and returns the snippet. If
+// the same program and input were saved before, that snippet is returned.
+func Create(ctx context.Context, store Store, code, stdin string) (*Snippet, error) {
+	sum := sha256.Sum256([]byte(code + "\x00" + stdin))
+	hash := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	for n := idLength; n <= len(hash); n++ {
+		s := &Snippet{ID: hash[:n], Code: code, Stdin: stdin, Created: time.Now().UTC()}
+		err := store.Save(ctx, s)
+		if err == nil {
+			return s, nil
+		} else if !errors.Is(err, ErrExists) {
+			return nil, err
+		}
+
+		existing, err := store.Get(ctx, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing.Code == code && existing.Stdin == stdin {
+			return existing, nil
+		}
+	}
+	return nil, errors.New("snippet: no free ID")
+}
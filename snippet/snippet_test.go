@@ -0,0 +1,86 @@
+package snippet
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDir(filepath.Join(t.TempDir(), "snippets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := Create(ctx, store, "bol_bhai(1);", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.ID) != idLength {
+		t.Errorf("expected an ID of %d characters, got=%q", idLength, first.ID)
+	}
+
+	again, err := Create(ctx, store, "bol_bhai(1);", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || !again.Created.Equal(first.Created) {
+		t.Errorf("expected the same program to give the same snippet, got=%+v and %+v", first, again)
+	}
+
+	withInput, err := Create(ctx, store, "bol_bhai(1);", "2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withInput.ID == first.ID {
+		t.Error("expected a different input to give a different ID")
+	}
+
+	got, err := store.Get(ctx, withInput.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != "bol_bhai(1);" || got.Stdin != "2\n" {
+		t.Errorf("wrong snippet read back: %+v", got)
+	}
+}
+
+func TestCreateCollision(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Take the short ID of a program with a different one.
+	short, _ := Create(ctx, store, "bol_bhai(2);", "")
+	os.Remove(store.file(short.ID))
+	if err := store.Save(ctx, &Snippet{ID: short.ID, Code: "bol_bhai(3);"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Create(ctx, store, "bol_bhai(2);", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.ID) != idLength+1 || s.ID[:idLength] != short.ID {
+		t.Errorf("expected a longer ID starting with %q, got=%q", short.ID, s.ID)
+	}
+}
+
+func TestDirGet(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"missing", "../secret", ""} {
+		if _, err := store.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) - expected ErrNotFound, got=%v", id, err)
+		}
+	}
+}
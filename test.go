@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/judge"
+	"github.com/ankush-web-eng/brolang/runner"
+	"github.com/ankush-web-eng/brolang/sandbox"
+)
+
+// testCommand implements `brolang test [flags] program cases`, judging a
+// program against the test cases in a JSON file or a directory of .in/.out
+// files. It exits with status 1 unless every case is accepted.
+func testCommand(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	typeCheck := fs.Bool("typecheck", false, "run the static type checker before evaluating")
+	legacyScoping := fs.Bool("legacy-scoping", false, "let agar bodies share the outer scope and assignment declare missing variables, as before")
+	isolate := fs.Bool("sandbox", true, "run each case in a worker process under the limits below")
+	timeout := fs.Duration("timeout", 10*time.Second, "wall-clock limit for each case")
+	cpu := fs.Duration("cpu", sandbox.DefaultLimits.CPUTime, "CPU time limit for each case")
+	memory := fs.Int64("memory", sandbox.DefaultLimits.Memory, "memory limit in bytes for each case")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: brolang test [flags] program.bro cases.json|cases-dir")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	code, err := readSource(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang test: %v\n", err)
+		return 1
+	}
+	cases, err := judge.LoadCases(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang test: %v\n", err)
+		return 1
+	}
+
+	opts := runner.Options{TypeCheck: *typeCheck, LegacyScoping: *legacyScoping}
+	run := func(ctx context.Context, code, stdin string) (*runner.Result, error) {
+		opts := opts
+		opts.Stdin = strings.NewReader(stdin)
+		return runner.Run(code, opts), nil
+	}
+	if *isolate {
+		pool, err := sandbox.NewPool(sandbox.Config{
+			Timeout: *timeout,
+			Limits:  sandbox.Limits{CPUTime: *cpu, Memory: *memory, Output: sandbox.DefaultLimits.Output},
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brolang test: %v\n", err)
+			return 1
+		}
+		defer pool.Close()
+		run = func(ctx context.Context, code, stdin string) (*runner.Result, error) {
+			return pool.Run(ctx, sandbox.Job{
+				Code:          code,
+				Stdin:         stdin,
+				TypeCheck:     *typeCheck,
+				LegacyScoping: *legacyScoping,
+			})
+		}
+	}
+
+	report, err := judge.Run(context.Background(), code, cases, run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang test: %v\n", err)
+		return 1
+	}
+
+	for i, c := range report.Cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprint(i + 1)
+		}
+		fmt.Printf("%s: %s (%v)\n", name, c.Verdict, time.Duration(c.Time).Round(time.Millisecond))
+		if c.Diff != "" {
+			fmt.Print(indent(c.Diff))
+		}
+		if c.Error != "" {
+			fmt.Print(indent(c.Error + "\n"))
+		}
+	}
+	fmt.Printf("passed %d/%d (%.0f%%)\n", report.Passed, report.Total, report.Score)
+	if report.Passed != report.Total {
+		return 1
+	}
+	return 0
+}
+
+// indent puts a tab before every line of text.
+func indent(text string) string {
+	return "\t" + strings.ReplaceAll(strings.TrimSuffix(text, "\n"), "\n", "\n\t") + "\n"
+}
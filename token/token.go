@@ -1,10 +1,14 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 1-based line of the first character
+	Column  int // 1-based column of the first character
 }
 
 const (
@@ -36,6 +40,8 @@ const (
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	DOTDOT    = ".."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
@@ -47,30 +53,54 @@ const (
 	LET      = "bhai_sun"
 	PRINT    = "bol_bhai"
 	INPUT    = "suna_bhai"
+	APPEND   = "jod_bhai"
 	IF       = "agar"
 	ELSE     = "nahi_to"
 	ELSE_IF  = "nahi_to_agar"
 	WHILE    = "jaha_tak"
 	FOR      = "chal_bhai"
+	IN       = "me"
 	TRUE     = "sach"
 	FALSE    = "jhuth"
 	BREAK    = "bas_kar_bhai"
 	CONTINUE = "aage_bhad_bhai"
+	TRY      = "koshish_kar_bhai"
+	CATCH    = "pakad_bhai"
+	FINALLY  = "aakhir_me"
+	THROW    = "fek_bhai"
 )
 
 var keywords = map[string]TokenType{
-	"bhai_sun":       LET,
-	"bol_bhai":       PRINT,
-	"suna_bhai":      INPUT,
-	"agar":           IF,
-	"nahi_to":        ELSE,
-	"nahi_to_agar":   ELSE_IF,
-	"jaha_tak":       WHILE,
-	"chal_bhai":      FOR,
-	"sach":           TRUE,
-	"jhuth":          FALSE,
-	"bas_kar_bhai":   BREAK,
-	"aage_bhad_bhai": CONTINUE,
+	"bhai_sun":         LET,
+	"bol_bhai":         PRINT,
+	"suna_bhai":        INPUT,
+	"jod_bhai":         APPEND,
+	"agar":             IF,
+	"nahi_to":          ELSE,
+	"nahi_to_agar":     ELSE_IF,
+	"jaha_tak":         WHILE,
+	"chal_bhai":        FOR,
+	"sach":             TRUE,
+	"jhuth":            FALSE,
+	"bas_kar_bhai":     BREAK,
+	"aage_bhad_bhai":   CONTINUE,
+	"koshish_kar_bhai": TRY,
+	"pakad_bhai":       CATCH,
+	"aakhir_me":        FINALLY,
+	"fek_bhai":         THROW,
+}
+
+// contextual keywords are only keywords where the parser expects them, so
+// they stay free for use as names everywhere else: `me` in
+// `chal_bhai (bhai_sun x me arr)`, say, and `bhai_sun me = 3;`.
+var contextual = map[string]TokenType{
+	"me": IN,
+}
+
+// IsContextual reports whether tok is the contextual keyword t, which the
+// lexer reads as an identifier.
+func IsContextual(tok Token, t TokenType) bool {
+	return tok.Type == IDENT && contextual[tok.Literal] == t
 }
 
 // LookupIdent checks if the given identifier is a keyword or not
@@ -80,3 +110,17 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Keywords returns every keyword of the language, contextual ones included,
+// in alphabetical order.
+func Keywords() []string {
+	names := make([]string, 0, len(keywords)+len(contextual))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	for name := range contextual {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
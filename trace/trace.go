@@ -0,0 +1,128 @@
+// Package trace provides object.Tracer implementations: a printer that logs
+// every event and a profiler that adds up hits and time per line.
+package trace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/object"
+)
+
+// Printer writes one line per event, such as "3:5 set x = 2".
+type Printer struct {
+	w io.Writer
+}
+
+// NewPrinter returns a tracer writing events to w.
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w}
+}
+
+// Trace implements object.Tracer.
+func (p *Printer) Trace(ev object.TraceEvent) {
+	fmt.Fprintln(p.w, Format(ev))
+}
+
+// Format renders an event the way Printer writes it.
+func Format(ev object.TraceEvent) string {
+	pos := fmt.Sprintf("%d:%d %s", ev.Line, ev.Column, ev.Kind)
+	switch ev.Kind {
+	case object.TraceSet:
+		value := ev.Value.Inspect()
+		if s, ok := ev.Value.(*object.String); ok {
+			value = strconv.Quote(s.Value)
+		}
+		return fmt.Sprintf("%s %s = %s", pos, ev.Name, value)
+	case object.TraceIteration:
+		return fmt.Sprintf("%s %d", pos, ev.Iteration)
+	case object.TraceError:
+		return fmt.Sprintf("%s %s", pos, ev.Value.Inspect())
+	}
+	return pos
+}
+
+// Multi sends every event to each of the tracers in turn.
+func Multi(tracers ...object.Tracer) object.Tracer {
+	return multi(tracers)
+}
+
+type multi []object.Tracer
+
+func (m multi) Trace(ev object.TraceEvent) {
+	for _, t := range m {
+		t.Trace(ev)
+	}
+}
+
+// LineProfile is what a program spent on one line.
+type LineProfile struct {
+	Line int   `json:"line"`
+	Hits int   `json:"hits"`   // statements started on the line
+	Time int64 `json:"timeNs"` // nanoseconds from those statements to the next statement
+}
+
+// Profiler counts the statements run on each line and charges each line the
+// time until the next statement starts, so a loop's line pays for checking
+// its condition but not for its body.
+type Profiler struct {
+	now   func() time.Time
+	lines map[int]*LineProfile
+
+	current *LineProfile // the line that is running
+	since   time.Time
+}
+
+// NewProfiler returns an empty profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{now: time.Now, lines: map[int]*LineProfile{}}
+}
+
+// Trace implements object.Tracer.
+func (p *Profiler) Trace(ev object.TraceEvent) {
+	if ev.Kind != object.TraceStatement {
+		return
+	}
+	now := p.now()
+	p.charge(now)
+
+	line, ok := p.lines[ev.Line]
+	if !ok {
+		line = &LineProfile{Line: ev.Line}
+		p.lines[ev.Line] = line
+	}
+	line.Hits++
+	p.current, p.since = line, now
+}
+
+func (p *Profiler) charge(now time.Time) {
+	if p.current != nil {
+		p.current.Time += int64(now.Sub(p.since))
+	}
+}
+
+// Report returns the profile ordered by line. The last statement is charged
+// up to the first call, so call it once the program has finished.
+func (p *Profiler) Report() []LineProfile {
+	if p.current != nil {
+		p.charge(p.now())
+		p.current = nil
+	}
+	out := make([]LineProfile, 0, len(p.lines))
+	for _, line := range p.lines {
+		out = append(out, *line)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Line < out[j].Line })
+	return out
+}
+
+// WriteReport prints a profile as a table.
+func WriteReport(w io.Writer, profile []LineProfile) {
+	fmt.Fprintf(w, "%6s %8s %12s\n", "line", "hits", "time")
+	for _, line := range profile {
+		fmt.Fprintf(w, "%6d %8d %12s\n", line.Line, line.Hits, time.Duration(line.Time))
+	}
+}
@@ -0,0 +1,104 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankush-web-eng/brolang/evaluator"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	"github.com/ankush-web-eng/brolang/parser"
+)
+
+func run(t *testing.T, input string, tracer object.Tracer) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+	env := object.NewEnvironment()
+	env.Tracer = tracer
+	return evaluator.Eval(program, env)
+}
+
+func TestPrinter(t *testing.T) {
+	input := `bhai_sun naam = "bro";
+chal_bhai (bhai_sun i = 0; i < 2; i = i + 1) {
+    bol_bhai(naam);
+}
+bol_bhai(x);`
+
+	var out bytes.Buffer
+	run(t, input, NewPrinter(&out))
+
+	expected := `1:1 statement
+1:1 set naam = "bro"
+2:1 statement
+2:12 set i = 0
+2:1 iteration 1
+3:5 statement
+2:35 set i = 1
+2:1 iteration 2
+3:5 statement
+2:35 set i = 2
+5:1 statement
+5:1 error bhai galati kardi tune Abe hosh me rehle! x kaha likha h tune bataiyo zara...
+`
+	if out.String() != expected {
+		t.Errorf("wrong trace.\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestErrorIsTracedOnce(t *testing.T) {
+	input := "chal_bhai (bhai_sun i = 0; i < 1; i = i + 1) {\n    agar (sach) {\n        bol_bhai(x);\n    }\n}"
+	var out bytes.Buffer
+	evaluated := run(t, input, NewPrinter(&out))
+
+	if strings.Count(out.String(), "error") != 1 || !strings.Contains(out.String(), "3:9 error") {
+		t.Errorf("expected one error at 3:9, got:\n%s", out.String())
+	}
+	if err, ok := evaluated.(*object.Error); !ok || err.Line != 3 || err.Column != 9 {
+		t.Errorf("expected the error to point at 3:9, got=%+v", evaluated)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	input := "bhai_sun total = 0;\nchal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {\n    total = total + i;\n}\nbol_bhai(total);"
+
+	clock := time.Unix(0, 0)
+	p := NewProfiler()
+	p.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	var out bytes.Buffer
+	run(t, input, Multi(p, NewPrinter(&out)))
+
+	report := p.Report()
+	expected := []LineProfile{
+		{Line: 1, Hits: 1, Time: int64(time.Millisecond)},
+		{Line: 2, Hits: 1, Time: int64(time.Millisecond)},
+		{Line: 3, Hits: 3, Time: int64(3 * time.Millisecond)},
+		{Line: 5, Hits: 1, Time: int64(time.Millisecond)},
+	}
+	if len(report) != len(expected) {
+		t.Fatalf("wrong number of lines. expected=%d, got=%+v", len(expected), report)
+	}
+	for i, want := range expected {
+		if report[i] != want {
+			t.Errorf("line %d - expected=%+v, got=%+v", want.Line, want, report[i])
+		}
+	}
+	if strings.Count(out.String(), "statement") != 6 {
+		t.Errorf("Multi did not forward every event, got:\n%s", out.String())
+	}
+
+	var table bytes.Buffer
+	WriteReport(&table, report)
+	if !strings.Contains(table.String(), "     3        3          3ms") {
+		t.Errorf("wrong table:\n%s", table.String())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/transpile"
+)
+
+// transpileCommand implements `brolang transpile --target=go|js [file]`,
+// printing the program converted to another language.
+func transpileCommand(args []string) int {
+	fs := flag.NewFlagSet("transpile", flag.ExitOnError)
+	target := fs.String("target", "go", "language to generate (go or js)")
+	fs.Parse(args)
+
+	name := "-"
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+
+	code, err := readSource(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang transpile: %v\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) > 0 {
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+		return 1
+	}
+
+	var out string
+	switch *target {
+	case "go":
+		out, err = transpile.ToGo(program)
+	case "js":
+		out, err = transpile.ToJS(program)
+	default:
+		err = fmt.Errorf("unknown target %q", *target)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brolang transpile: %s: %v\n", name, err)
+		return 1
+	}
+
+	fmt.Print(out)
+	return 0
+}
@@ -0,0 +1,501 @@
+package transpile
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/typecheck"
+)
+
+// goReserved holds Go keywords and the predeclared or imported names the
+// generated file relies on. Brolang variables with these names get a trailing
+// underscore.
+var goReserved = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+	"append": true, "bool": true, "cap": true, "false": true, "int64": true,
+	"len": true, "main": true, "nil": true, "string": true, "true": true,
+	"fmt": true, "reflect": true, "strings": true, "inspect": true, "at": true,
+}
+
+// atHelper turns an index that may count from the end, as a negative index
+// does in Brolang, into a Go one.
+const atHelper = `
+func at(n int, i int64) int64 {
+	if i < 0 {
+		return i + int64(n)
+	}
+	return i
+}
+`
+
+// inspectHelper prints arrays the way object.Array.Inspect does ("[1, 2]"),
+// which fmt.Println would print as "[1 2]".
+const inspectHelper = `
+func inspect(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Sprint(v)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = inspect(rv.Index(i).Interface())
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+`
+
+// ToGo converts a program into the source of a runnable Go file. bhai_sun
+// becomes a variable declaration, chal_bhai a three-clause or range for
+// loop, jaha_tak a condition-only for loop, arrays slices and bol_bhai
+// fmt.Println.
+//
+// Go needs a static type for every variable, so the program must pass the
+// type checker and every variable must keep one type. The interpreter's
+// iteration limit is not reproduced, and a bad index panics instead of
+// failing with the interpreter's error.
+func ToGo(program *ast.Program) (string, error) {
+	info, diags := typecheck.Check(program)
+	if len(diags) > 0 {
+		return "", fmt.Errorf("type error: %s", diags[0])
+	}
+
+	g := &goGen{info: info}
+	g.indent = 1
+	g.scope(program.Statements)
+	if g.err != nil {
+		return "", g.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by brolang transpile. DO NOT EDIT.\n\npackage main\n\n")
+	var imports []string
+	if g.needsFmt || g.needsInspect {
+		imports = append(imports, "fmt")
+	}
+	if g.needsInspect {
+		imports = append(imports, "reflect", "strings")
+	}
+	if len(imports) > 0 {
+		out.WriteString("import (\n")
+		for _, path := range imports {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		out.WriteString(")\n\n")
+	}
+	out.WriteString("func main() {\n")
+	out.Write(g.buf.Bytes())
+	out.WriteString("}\n")
+	if g.needsInspect {
+		out.WriteString(inspectHelper)
+	}
+	if g.needsAt {
+		out.WriteString(atHelper)
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("generated invalid Go: %v", err)
+	}
+	return string(src), nil
+}
+
+type goGen struct {
+	info         *typecheck.Info
+	buf          bytes.Buffer
+	indent       int
+	vars         []map[string]*typecheck.Type // Go-visible variables, innermost last
+	needsFmt     bool
+	needsInspect bool
+	needsAt      bool
+	err          error
+}
+
+func (g *goGen) fail(node ast.Node, format string, args ...interface{}) {
+	if g.err == nil {
+		tok := ast.StartToken(node)
+		g.err = fmt.Errorf("%d:%d: cannot transpile: %s", tok.Line, tok.Column, fmt.Sprintf(format, args...))
+	}
+}
+
+func (g *goGen) line(format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("\t", g.indent))
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func goName(name string) string {
+	if goReserved[name] {
+		return name + "_"
+	}
+	return name
+}
+
+func goType(t *typecheck.Type) (string, bool) {
+	switch t.Kind {
+	case typecheck.Int:
+		return "int64", true
+	case typecheck.String:
+		return "string", true
+	case typecheck.Bool:
+		return "bool", true
+	case typecheck.Array:
+		elem, ok := goType(t.Elem)
+		return "[]" + elem, ok
+	}
+	return "", false
+}
+
+// scope emits the statements of a Brolang scope. Brolang and Go both open a
+// scope for every block, so a bhai_sun becomes a Go declaration where it
+// stands, and a second bhai_sun of the same name in the same scope becomes a
+// plain assignment.
+func (g *goGen) scope(stmts []ast.Statement) {
+	g.vars = append(g.vars, map[string]*typecheck.Type{})
+	for _, stmt := range stmts {
+		g.statement(stmt)
+	}
+	g.vars = g.vars[:len(g.vars)-1]
+}
+
+func (g *goGen) lookup(name string) (*typecheck.Type, bool) {
+	for i := len(g.vars) - 1; i >= 0; i-- {
+		if t, ok := g.vars[i][name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// let declares a bhai_sun variable in the innermost scope. The value is
+// rendered first, so a name it shares with the new variable still means the
+// outer one, as it does in Go.
+func (g *goGen) let(ls *ast.LetStatement) {
+	name := ls.Name.Value
+	t := g.info.Defs[ls.Name]
+	declared := g.vars[len(g.vars)-1]
+	if prev, ok := declared[name]; ok {
+		if !typecheck.Identical(prev, t) {
+			g.fail(ls, "%s is redeclared as %s (was %s)", name, t, prev)
+			return
+		}
+		g.line("%s = %s", goName(name), g.expr(ls.Value, prev))
+		return
+	}
+	gt, ok := goType(t)
+	if !ok {
+		g.fail(ls, "type of %s (%s) is not known statically", name, t)
+		return
+	}
+	value := g.expr(ls.Value, t)
+	declared[name] = t
+	g.line("var %s %s = %s", goName(name), gt, value)
+	g.line("_ = %s", goName(name))
+}
+
+// declare declares a loop variable in the innermost scope, to be set on every pass.
+func (g *goGen) declare(name *ast.Identifier) {
+	t := g.info.Defs[name]
+	gt, ok := goType(t)
+	if !ok {
+		g.fail(name, "type of %s (%s) is not known statically", name.Value, t)
+		return
+	}
+	g.vars[len(g.vars)-1][name.Value] = t
+	g.line("var %s %s", goName(name.Value), gt)
+	g.line("_ = %s", goName(name.Value))
+}
+
+func (g *goGen) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		g.let(s)
+	case *ast.AssignStatement:
+		g.assign(s, s.Name.Value, s.Value)
+	case *ast.IndexAssignStatement:
+		g.line("%s = %s", g.expr(s.Target, nil), g.expr(s.Value, g.info.TypeOf(s.Target)))
+	case *ast.PrintStatement:
+		g.needsFmt = true
+		g.line("fmt.Println(%s)", g.printArg(s.Expression))
+	case *ast.BreakStatement:
+		g.line("break")
+	case *ast.ContinueStatement:
+		g.line("continue")
+	case *ast.BlockStatement:
+		g.line("{")
+		g.block(s.Statements)
+		g.line("}")
+	case *ast.ExpressionStatement:
+		g.expressionStatement(s)
+	case *ast.TryStatement, *ast.ThrowStatement:
+		g.fail(stmt, "%s is not supported yet", stmt.TokenLiteral())
+	default:
+		g.fail(stmt, "unsupported statement %T", stmt)
+	}
+}
+
+func (g *goGen) block(stmts []ast.Statement) {
+	g.indent++
+	g.scope(stmts)
+	g.indent--
+}
+
+func (g *goGen) assign(node ast.Node, name string, value ast.Expression) {
+	target, ok := g.lookup(name)
+	if !ok {
+		g.fail(node, "%s is not declared", name)
+		return
+	}
+	if vt := g.info.TypeOf(value); vt.Kind != typecheck.Unknown && !assignableTo(vt, target) {
+		g.fail(node, "%s changes type from %s to %s", name, target, vt)
+		return
+	}
+	g.line("%s = %s", goName(name), g.expr(value, target))
+}
+
+// assignableTo mirrors the type checker's rule: an array whose element type is
+// unknown (an empty literal) fits any array type.
+func assignableTo(from, to *typecheck.Type) bool {
+	if from.Kind != to.Kind {
+		return false
+	}
+	if from.Kind == typecheck.Array && from.Elem.Kind != typecheck.Unknown {
+		return assignableTo(from.Elem, to.Elem)
+	}
+	return true
+}
+
+func (g *goGen) expressionStatement(es *ast.ExpressionStatement) {
+	switch e := es.Expression.(type) {
+	case nil:
+		g.fail(es, "empty statement")
+	case *ast.IfExpression:
+		g.ifStatement(e)
+	case *ast.WhileExpression:
+		g.whileStatement(e)
+	case *ast.ForExpression:
+		g.forStatement(e)
+	case *ast.ForEachExpression:
+		g.forEachStatement(e)
+	default:
+		g.line("_ = %s", g.expr(e, nil))
+	}
+}
+
+func (g *goGen) ifStatement(ie *ast.IfExpression) {
+	g.line("if %s {", g.condition(ie.Condition))
+	g.block(ie.Consequence.Statements)
+	for _, elseIf := range ie.ElseIf {
+		g.line("} else if %s {", g.condition(elseIf.Condition))
+		g.block(elseIf.Consequence.Statements)
+	}
+	if ie.Alternative != nil {
+		g.line("} else {")
+		g.block(ie.Alternative.Statements)
+	}
+	g.line("}")
+}
+
+// forStatement and whileStatement wrap the loop in a block holding the loop's
+// scope, so that the init variable lives as long as the loop. The body is a
+// Go block of its own, fresh on every pass as in the evaluator.
+func (g *goGen) forStatement(fe *ast.ForExpression) {
+	g.openLoopScope()
+	if fe.Init != nil {
+		g.statement(fe.Init)
+	}
+	cond := ""
+	if fe.Condition != nil {
+		cond = g.condition(fe.Condition)
+	}
+	update := ""
+	if fe.Update != nil {
+		update = g.simpleStatement(fe.Update)
+	}
+	g.line("for ; %s; %s {", cond, update)
+	g.block(fe.Body.Statements)
+	g.line("}")
+	g.closeLoopScope()
+}
+
+// forEachStatement declares the loop variables in the loop's scope and copies
+// each pass's values into them from Go loop variables whose names start with
+// an underscore, which no Brolang identifier can.
+func (g *goGen) forEachStatement(fe *ast.ForEachExpression) {
+	g.openLoopScope()
+	for _, name := range []*ast.Identifier{fe.Index, fe.Value} {
+		if name != nil {
+			g.declare(name)
+		}
+	}
+
+	value := "_v"
+	if rng, ok := fe.Iterable.(*ast.RangeExpression); ok {
+		value = "_n"
+		start, end := g.expr(rng.Start, nil), g.expr(rng.End, nil)
+		if fe.Index != nil {
+			g.line("for _i, _n, _end := int64(0), int64(%s), int64(%s); _n < _end; _i, _n = _i+1, _n+1 {", start, end)
+		} else {
+			g.line("for _n, _end := int64(%s), int64(%s); _n < _end; _n++ {", start, end)
+		}
+	} else {
+		if g.info.TypeOf(fe.Iterable).Kind == typecheck.String {
+			g.fail(fe.Iterable, "looping over strings is not supported yet")
+		}
+		index := "_"
+		if fe.Index != nil {
+			index = "_i"
+		}
+		g.line("for %s, _v := range %s {", index, g.expr(fe.Iterable, nil))
+	}
+	g.indent++
+	if fe.Index != nil {
+		g.line("%s = int64(_i)", goName(fe.Index.Value))
+	}
+	g.line("%s = %s", goName(fe.Value.Value), value)
+	g.indent--
+	g.block(fe.Body.Statements)
+	g.line("}")
+	g.closeLoopScope()
+}
+
+func (g *goGen) whileStatement(we *ast.WhileExpression) {
+	g.openLoopScope()
+	g.line("for %s {", g.condition(we.Condition))
+	g.block(we.Body.Statements)
+	g.line("}")
+	g.closeLoopScope()
+}
+
+func (g *goGen) openLoopScope() {
+	g.line("{")
+	g.indent++
+	g.vars = append(g.vars, map[string]*typecheck.Type{})
+}
+
+func (g *goGen) closeLoopScope() {
+	g.vars = g.vars[:len(g.vars)-1]
+	g.indent--
+	g.line("}")
+}
+
+// simpleStatement renders a chal_bhai update clause, which must be an assignment in Go.
+func (g *goGen) simpleStatement(stmt ast.Statement) string {
+	switch s := stmt.(type) {
+	case *ast.AssignStatement:
+		target, _ := g.lookup(s.Name.Value)
+		return fmt.Sprintf("%s = %s", goName(s.Name.Value), g.expr(s.Value, target))
+	case *ast.LetStatement:
+		// Declaring again in the loop's scope is assigning the init variable.
+		target, ok := g.vars[len(g.vars)-1][s.Name.Value]
+		if !ok {
+			g.fail(stmt, "chal_bhai update must assign a variable declared in the loop's init")
+			return ""
+		}
+		return fmt.Sprintf("%s = %s", goName(s.Name.Value), g.expr(s.Value, target))
+	}
+	g.fail(stmt, "unsupported chal_bhai update %T", stmt)
+	return ""
+}
+
+// condition converts a Brolang condition to a Go bool, following isTruthy.
+func (g *goGen) condition(exp ast.Expression) string {
+	switch g.info.TypeOf(exp).Kind {
+	case typecheck.Bool:
+		return g.expr(exp, nil)
+	case typecheck.Int:
+		return fmt.Sprintf("(%s) != 0", g.expr(exp, nil))
+	case typecheck.String, typecheck.Array:
+		return "true"
+	}
+	g.fail(exp, "type of condition is not known statically")
+	return "false"
+}
+
+func (g *goGen) printArg(exp ast.Expression) string {
+	t := g.info.TypeOf(exp)
+	switch t.Kind {
+	case typecheck.Array:
+		g.needsInspect = true
+		return fmt.Sprintf("inspect(%s)", g.expr(exp, nil))
+	case typecheck.Unknown, typecheck.Null:
+		g.fail(exp, "type of printed value is not known statically")
+	}
+	return g.expr(exp, nil)
+}
+
+// expr renders an expression. want is the type the context expects, used to
+// give empty array literals a concrete Go type; it may be nil.
+func (g *goGen) expr(exp ast.Expression, want *typecheck.Type) string {
+	switch e := exp.(type) {
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(e.Value, 10)
+	case *ast.StringLiteral:
+		return strconv.Quote(e.Value)
+	case *ast.Boolean:
+		return strconv.FormatBool(e.Value)
+	case *ast.Identifier:
+		if _, ok := g.lookup(e.Value); !ok || !g.info.TypeOf(e).Known() {
+			g.fail(e, "type of %s is not known statically (is it defined here?)", e.Value)
+		}
+		return goName(e.Value)
+	case *ast.ArrayLiteral:
+		return g.arrayLiteral(e, want)
+	case *ast.IndexExpression:
+		if g.info.TypeOf(e.Left).Kind == typecheck.String {
+			g.fail(e, "indexing strings is not supported yet")
+		}
+		left := g.expr(e.Left, nil)
+		if lit, ok := e.Index.(*ast.IntegerLiteral); ok && lit.Value >= 0 {
+			return fmt.Sprintf("%s[%d]", left, lit.Value)
+		}
+		// Any other index may be negative and count from the end.
+		g.needsAt = true
+		return fmt.Sprintf("%s[at(len(%s), %s)]", left, left, g.expr(e.Index, nil))
+	case *ast.SliceExpression:
+		g.fail(e, "slices are not supported yet")
+		return ""
+	case *ast.CallExpression:
+		if e.Function.TokenLiteral() == "jod_bhai" {
+			g.fail(e, "jod_bhai is not supported yet: it grows the array in place, which Go's append does not")
+			return ""
+		}
+	case *ast.PrefixExpression:
+		if e.Operator == "!" {
+			return fmt.Sprintf("!(%s)", g.condition(e.Right))
+		}
+		return fmt.Sprintf("%s(%s)", e.Operator, g.expr(e.Right, nil))
+	case *ast.InfixExpression:
+		return fmt.Sprintf("(%s %s %s)", g.expr(e.Left, nil), e.Operator, g.expr(e.Right, nil))
+	case nil:
+		g.fail(&ast.Program{}, "missing expression")
+		return ""
+	}
+	g.fail(exp, "unsupported expression %T", exp)
+	return ""
+}
+
+func (g *goGen) arrayLiteral(al *ast.ArrayLiteral, want *typecheck.Type) string {
+	t := g.info.TypeOf(al)
+	if !t.Known() && want != nil && want.Kind == typecheck.Array {
+		t = want
+	}
+	gt, ok := goType(t)
+	if !ok {
+		g.fail(al, "element type of array is not known statically")
+		return ""
+	}
+
+	elems := make([]string, len(al.Elements))
+	for i, el := range al.Elements {
+		elems[i] = g.expr(el, t.Elem)
+	}
+	return fmt.Sprintf("%s{%s}", gt, strings.Join(elems, ", "))
+}
@@ -0,0 +1,205 @@
+package transpile
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	brolangast "github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/evaluator"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/object"
+	brolangparser "github.com/ankush-web-eng/brolang/parser"
+)
+
+var conformancePrograms = []string{
+	`bhai_sun x = 5;
+    bol_bhai(x);`,
+	`bhai_sun x = 5;
+    x = x + 1;`,
+	`bhai_sun name = "bro";
+    bhai_sun ok = sach;
+    bol_bhai(name);
+    bol_bhai(ok);`,
+	`bhai_sun total = 0;
+    chal_bhai (bhai_sun i = 0; i < 5; i = i + 1) {
+        agar (i == 3) {
+            aage_bhad_bhai;
+        }
+        total = total + i;
+        bol_bhai(total);
+    }`,
+	`bhai_sun n = 3;
+    jaha_tak (n) {
+        bol_bhai(n);
+        n = n - 1;
+    }`,
+	`bhai_sun x = 0;
+    jaha_tak (sach) {
+        x = x + 1;
+        agar (x > 4) {
+            bol_bhai(x);
+            bas_kar_bhai;
+        }
+    }`,
+	`bhai_sun arr = [10, 20, 30];
+    bhai_sun grid: [][]int = [[1, 2], []];
+    bol_bhai(arr);
+    bol_bhai(2 * arr[1]);
+    bol_bhai(grid);`,
+	`bhai_sun arr = [10, 20, 30];
+    bhai_sun i = 0 - 2;
+    arr[-1] = arr[i] + arr[1 - 1];
+    bol_bhai(arr);
+    bol_bhai(arr[-3]);`,
+	`bhai_sun x = 7;
+    agar (x < 5) {
+        bol_bhai("small");
+    } nahi_to_agar (x < 10) {
+        bhai_sun label = "medium"
+        bol_bhai(label);
+    } nahi_to {
+        bol_bhai("large");
+    }`,
+	`chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {
+        chal_bhai (bhai_sun j = 0; j < i; j = j + 1) {
+            bol_bhai(i * 10 + j);
+        }
+    }`,
+	`bhai_sun len = 2;
+    bhai_sun type = len % 2;
+    bol_bhai(type);`,
+	`bhai_sun squares = [0, 0, 0];
+    chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {
+        squares[i] = i * i;
+    }
+    bhai_sun grid = [[1, 2], [3, 4]];
+    grid[1][0] = squares[2] + 1;
+    bol_bhai(squares);
+    bol_bhai(grid);`,
+	`bhai_sun names = ["ek", "do", "teen"];
+    chal_bhai (bhai_sun i, name me names) {
+        agar (i == 1) {
+            aage_bhad_bhai;
+        }
+        bol_bhai(name);
+    }
+    chal_bhai (bhai_sun n me 3..7) {
+        agar (n == 6) {
+            bas_kar_bhai;
+        }
+        bhai_sun sq = n * n;
+        bol_bhai(sq);
+    }
+    chal_bhai (bhai_sun i, n me 10..12) {
+        bol_bhai(i);
+        bol_bhai(n);
+    }
+    chal_bhai (bhai_sun n me 5..2) {
+        bol_bhai(n);
+    }`,
+	`bhai_sun x = 1;
+    bhai_sun total = 0;
+    agar (x == 1) {
+        bhai_sun x = "andar";
+        bol_bhai(x);
+        bhai_sun x = "phir se";
+        bol_bhai(x);
+    }
+    bol_bhai(x);
+    chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {
+        total = total + i;
+        bhai_sun total = 100 + i;
+        bol_bhai(total);
+    }
+    bol_bhai(total);`,
+}
+
+func interpret(program *brolangast.Program) string {
+	env := object.NewEnvironment()
+	evaluator.Eval(program, env)
+	return env.OutputBuilder.String()
+}
+
+func parseBrolang(t *testing.T, input string) *brolangast.Program {
+	p := brolangparser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// typeCheckGo compiles the generated source with go/types, without leaving the test process.
+func typeCheckGo(t *testing.T, src string) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("main", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated source does not type check: %v\n%s", err, src)
+	}
+}
+
+func TestToGoMatchesInterpreter(t *testing.T) {
+	goTool, lookErr := exec.LookPath("go")
+
+	for i, input := range conformancePrograms {
+		expected := interpret(parseBrolang(t, input))
+
+		src, err := ToGo(parseBrolang(t, input))
+		if err != nil {
+			t.Fatalf("program %d - transpile failed: %v", i, err)
+		}
+		typeCheckGo(t, src)
+
+		if testing.Short() || lookErr != nil {
+			continue
+		}
+
+		dir := t.TempDir()
+		file := filepath.Join(dir, "main.go")
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(goTool, "run", file)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("program %d - go run failed: %v\n%s\n%s", i, err, out, src)
+		}
+		if string(out) != expected {
+			t.Errorf("program %d - output differs.\ninterpreter=%q\ngo=%q", i, expected, out)
+		}
+	}
+}
+
+func TestToGoRejectsDynamicPrograms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`bhai_sun x = 5; x = "five"; bol_bhai(x);`, "changes type"},
+		{`bhai_sun x: int = "five";`, "type error"},
+		{`bol_bhai(y);`, "not known statically"},
+		{`bhai_sun arr = [1, 2]; bol_bhai(arr[1:]);`, "not supported"},
+		{`chal_bhai (bhai_sun c me "abc") { bol_bhai(c); }`, "not supported"},
+		{`bhai_sun arr = [1]; jod_bhai(arr, 2);`, "jod_bhai is not supported"},
+	}
+
+	for _, tt := range tests {
+		_, err := ToGo(parseBrolang(t, tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s - expected error containing %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
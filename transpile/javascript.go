@@ -0,0 +1,424 @@
+package transpile
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ankush-web-eng/brolang/ast"
+)
+
+//go:embed runtime.js
+var jsRuntime string
+
+// jsHostHooks lets a page collect the output by defining brolangWrite and
+// brolangFail, and provide input by defining brolangRead, before loading the
+// script; elsewhere output goes to the console and there is no input.
+const jsHostHooks = `})(
+  typeof brolangWrite === "function" ? brolangWrite : (line) => console.log(line),
+  typeof brolangFail === "function"
+    ? brolangFail
+    : (message) => {
+        console.error(message);
+        if (typeof process !== "undefined") process.exitCode = 1;
+      },
+  typeof brolangRead === "function" ? brolangRead : null,
+);
+`
+
+// ToJS converts a program into a self-contained JavaScript file that can run
+// in a browser or under node. Unlike ToGo it accepts every program the
+// evaluator accepts: values stay dynamically typed and the bundled runtime
+// reproduces the evaluator's error messages, array element checks, scoping
+// and iteration limit. The script calls write(line) for every printed line
+// and fail(message) if the program stops with an error; suna_bhai calls
+// read(), which returns the next line of input.
+func ToJS(program *ast.Program) (string, error) {
+	g := &jsGen{jsState: &jsState{}, env: "$env0", indent: 1}
+	for _, stmt := range program.Statements {
+		g.topLevel(stmt)
+	}
+	if g.err != nil {
+		return "", g.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by brolang transpile. DO NOT EDIT.\n\n")
+	out.WriteString("(function (write, fail, read) {\n\"use strict\";\n\n")
+	out.WriteString(jsRuntime)
+	out.WriteString("\nconst $env0 = new Env(null);\ntry {\n")
+	out.Write(g.buf.Bytes())
+	out.WriteString("} catch (e) {\n")
+	out.WriteString("  if (e instanceof BroError) fail(e.inspect());\n")
+	out.WriteString("  else if (e instanceof GoPanic) fail(e.message);\n")
+	out.WriteString("  else throw e;\n}\n")
+	out.WriteString(jsHostHooks)
+	return out.String(), nil
+}
+
+// jsState is shared by a generator and the ones it creates for nested functions.
+type jsState struct {
+	names int
+	err   error
+}
+
+type jsGen struct {
+	*jsState
+	buf    bytes.Buffer
+	indent int
+	env    string  // JS variable holding the current environment
+	loop   *jsLoop // innermost loop of the current function, nil outside loops
+	result string  // variable receiving the value of every statement, "" if unused
+	inFunc bool    // generating an agar or loop used as a value
+}
+
+type jsLoop struct {
+	bodyLabel string // label that aage_bhad_bhai breaks out of; "" for jaha_tak
+}
+
+func (g *jsGen) fail(node ast.Node, format string, args ...interface{}) {
+	if g.err == nil {
+		tok := ast.StartToken(node)
+		g.err = fmt.Errorf("%d:%d: cannot transpile: %s", tok.Line, tok.Column, fmt.Sprintf(format, args...))
+	}
+}
+
+func (g *jsGen) line(format string, args ...interface{}) {
+	g.buf.WriteString(strings.Repeat("  ", g.indent))
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *jsGen) name(prefix string) string {
+	g.names++
+	return prefix + strconv.Itoa(g.names)
+}
+
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// store prefixes a statement's value with an assignment to the result
+// variable when the enclosing agar or loop is used as a value.
+func (g *jsGen) store(js string) string {
+	if g.result == "" {
+		return js
+	}
+	return g.result + " = " + js
+}
+
+// topLevel emits a statement of the program. The evaluator ignores
+// bas_kar_bhai and aage_bhad_bhai outside loops apart from skipping the rest
+// of the enclosing statement, which the $top label reproduces.
+func (g *jsGen) topLevel(stmt ast.Statement) {
+	if !hasLoopControl(stmt) {
+		g.statement(stmt)
+		return
+	}
+	g.line("$top: {")
+	g.indent++
+	g.statement(stmt)
+	g.indent--
+	g.line("}")
+}
+
+// hasLoopControl reports whether node contains bas_kar_bhai or aage_bhad_bhai
+// outside of any loop.
+func hasLoopControl(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BreakStatement, *ast.ContinueStatement:
+			found = true
+		case *ast.WhileExpression, *ast.ForExpression, *ast.ForEachExpression:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+func (g *jsGen) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		if s.Value == nil {
+			g.line("throw error(%s);", jsString("invalid let statement"))
+			return
+		}
+		g.line("%s;", g.store(fmt.Sprintf("let_(%s, %s, %s)", g.env, jsString(s.Name.Value), g.expr(s.Value))))
+	case *ast.AssignStatement:
+		g.line("%s;", g.store(fmt.Sprintf("assign(%s, %s, %s)", g.env, jsString(s.Name.Value), g.expr(s.Value))))
+	case *ast.IndexAssignStatement:
+		target := s.Target
+		g.line("%s;", g.store(fmt.Sprintf("setIndex(%s, %s, %s)", g.expr(target.Left), g.expr(target.Index), g.expr(s.Value))))
+	case *ast.PrintStatement:
+		if s.Expression == nil {
+			g.line("throw error(%s);", jsString("invalid print statement"))
+			return
+		}
+		g.line("%s;", g.store(fmt.Sprintf("print(%s)", g.expr(s.Expression))))
+	case *ast.BreakStatement:
+		g.loopControl("BREAK", "NULL", "break;")
+	case *ast.ContinueStatement:
+		cont := "continue;"
+		if g.loop != nil && g.loop.bodyLabel != "" {
+			cont = "break " + g.loop.bodyLabel + ";"
+		}
+		g.loopControl("CONTINUE", "CONTINUE", cont)
+	case *ast.BlockStatement:
+		g.line("{")
+		g.block(s)
+		g.line("}")
+	case *ast.ExpressionStatement:
+		g.expressionStatement(s)
+	case *ast.TryStatement, *ast.ThrowStatement:
+		g.fail(stmt, "%s is not supported yet", stmt.TokenLiteral())
+	default:
+		g.fail(stmt, "unsupported statement %T", stmt)
+	}
+}
+
+// loopControl emits bas_kar_bhai or aage_bhad_bhai. Inside a loop the loop's
+// value becomes loopValue; in an agar used as a value the control object
+// itself becomes the value, as it does in the evaluator.
+func (g *jsGen) loopControl(control, loopValue, jump string) {
+	switch {
+	case g.loop != nil:
+		if g.result != "" {
+			g.line("%s = %s;", g.result, loopValue)
+		}
+		g.line("%s", jump)
+	case g.inFunc:
+		g.line("return %s;", control)
+	default:
+		g.line("break $top;")
+	}
+}
+
+// block emits the statements of a block, which like every block in the
+// evaluator gets an environment of its own.
+func (g *jsGen) block(block *ast.BlockStatement) {
+	g.indent++
+	if len(block.Statements) == 0 && g.result != "" {
+		// An empty block evaluates to a nil object.Object.
+		g.line("%s = undefined;", g.result)
+	}
+	if len(block.Statements) > 0 {
+		outerEnv := g.env
+		g.env = g.name("$env")
+		g.line("const %s = new Env(%s);", g.env, outerEnv)
+		for _, stmt := range block.Statements {
+			g.statement(stmt)
+		}
+		g.env = outerEnv
+	}
+	g.indent--
+}
+
+func (g *jsGen) expressionStatement(es *ast.ExpressionStatement) {
+	switch e := es.Expression.(type) {
+	case nil:
+		g.line("unknownNode(%s);", jsString("<nil>"))
+	case *ast.IfExpression:
+		g.ifStatement(e)
+	case *ast.WhileExpression:
+		g.whileStatement(e)
+	case *ast.ForExpression:
+		g.forStatement(e)
+	case *ast.ForEachExpression:
+		g.forEachStatement(e)
+	default:
+		g.line("%s;", g.store(g.expr(e)))
+	}
+}
+
+func (g *jsGen) ifStatement(ie *ast.IfExpression) {
+	g.line("if (truthy(%s)) {", g.expr(ie.Condition))
+	g.block(ie.Consequence)
+	for _, elseIf := range ie.ElseIf {
+		g.line("} else if (truthy(%s)) {", g.expr(elseIf.Condition))
+		g.block(elseIf.Consequence)
+	}
+	switch {
+	case ie.Alternative != nil:
+		g.line("} else {")
+		g.block(ie.Alternative)
+	case g.result != "":
+		g.line("} else {")
+		g.line("  %s = NULL;", g.result)
+	}
+	g.line("}")
+}
+
+// whileStatement and forStatement open one environment per loop, as the
+// evaluator does, under the one each pass of the body gets, and count
+// condition checks against the iteration limit.
+func (g *jsGen) whileStatement(we *ast.WhileExpression) {
+	outerEnv, outerLoop := g.openLoop(&jsLoop{})
+	counter := g.name("$n")
+	g.line("for (let %s = 1; ; %s++) {", counter, counter)
+	g.indent++
+	g.line("tick(%s);", counter)
+	g.line("if (!truthy(%s)) break;", g.expr(we.Condition))
+	g.indent--
+	g.block(we.Body)
+	g.line("}")
+	g.closeLoop(outerEnv, outerLoop)
+}
+
+// The body of a chal_bhai loop is a labelled block so that aage_bhad_bhai can
+// leave it and still run the update statement.
+func (g *jsGen) forStatement(fe *ast.ForExpression) {
+	loop := &jsLoop{}
+	outerEnv, outerLoop := g.openLoop(loop)
+	if fe.Init != nil {
+		g.untracked(fe.Init)
+	}
+	loop.bodyLabel = g.name("$body")
+	counter := g.name("$n")
+	g.line("for (let %s = 1; ; %s++) {", counter, counter)
+	g.indent++
+	g.line("tick(%s);", counter)
+	if fe.Condition != nil {
+		g.line("if (!truthy(%s)) break;", g.expr(fe.Condition))
+	}
+	g.line("%s: {", loop.bodyLabel)
+	g.block(fe.Body)
+	g.line("}")
+	if fe.Update != nil {
+		g.untracked(fe.Update)
+	}
+	g.indent--
+	g.line("}")
+	g.closeLoop(outerEnv, outerLoop)
+}
+
+// forEachStatement takes the number of passes from the runtime's iterate or
+// range when the loop starts, as the evaluator does, and counts the passes
+// against the iteration limit.
+func (g *jsGen) forEachStatement(fe *ast.ForEachExpression) {
+	outerEnv, outerLoop := g.openLoop(&jsLoop{})
+	items := g.name("$items")
+	if rng, ok := fe.Iterable.(*ast.RangeExpression); ok {
+		g.line("const %s = range(%s, %s);", items, g.expr(rng.Start), g.expr(rng.End))
+	} else {
+		g.line("const %s = iterate(%s);", items, g.expr(fe.Iterable))
+	}
+	counter := g.name("$i")
+	g.line("for (let %s = 0; %s < %s.length; %s++) {", counter, counter, items, counter)
+	g.indent++
+	g.line("tick(%s + 1);", counter)
+	if fe.Index != nil {
+		g.line("%s.set(%s, BigInt(%s));", g.env, jsString(fe.Index.Value), counter)
+	}
+	g.line("%s.set(%s, %s.at(%s));", g.env, jsString(fe.Value.Value), items, counter)
+	g.indent--
+	g.block(fe.Body)
+	g.line("}")
+	g.closeLoop(outerEnv, outerLoop)
+}
+
+// untracked emits a statement whose value the evaluator discards.
+func (g *jsGen) untracked(stmt ast.Statement) {
+	result := g.result
+	g.result = ""
+	g.statement(stmt)
+	g.result = result
+}
+
+func (g *jsGen) openLoop(loop *jsLoop) (string, *jsLoop) {
+	outerEnv, outerLoop := g.env, g.loop
+	g.line("{")
+	g.indent++
+	g.env = g.name("$env")
+	g.line("const %s = new Env(%s);", g.env, outerEnv)
+	if g.result != "" {
+		g.line("%s = NULL;", g.result)
+	}
+	g.loop = loop
+	return outerEnv, outerLoop
+}
+
+func (g *jsGen) closeLoop(outerEnv string, outerLoop *jsLoop) {
+	g.env, g.loop = outerEnv, outerLoop
+	g.indent--
+	g.line("}")
+}
+
+// expr renders an expression. Agar and loops used as values become
+// immediately invoked functions returning the value the evaluator would give.
+func (g *jsGen) expr(exp ast.Expression) string {
+	switch e := exp.(type) {
+	case *ast.IntegerLiteral:
+		if e.Value < 0 {
+			return fmt.Sprintf("(%dn)", e.Value)
+		}
+		return fmt.Sprintf("%dn", e.Value)
+	case *ast.StringLiteral:
+		return jsString(e.Value)
+	case *ast.Boolean:
+		return strconv.FormatBool(e.Value)
+	case *ast.Identifier:
+		return fmt.Sprintf("%s.get(%s)", g.env, jsString(e.Value))
+	case *ast.ArrayLiteral:
+		return fmt.Sprintf("array(%s)", g.list(e.Elements))
+	case *ast.IndexExpression:
+		return fmt.Sprintf("index(%s, %s)", g.expr(e.Left), g.expr(e.Index))
+	case *ast.SliceExpression:
+		return fmt.Sprintf("slice(%s, %s, %s)", g.expr(e.Left), g.thunk(e.Start), g.thunk(e.End))
+	case *ast.PrefixExpression:
+		return fmt.Sprintf("prefix(%s, %s)", jsString(e.Operator), g.expr(e.Right))
+	case *ast.InfixExpression:
+		return fmt.Sprintf("infix(%s, %s, %s)", jsString(e.Operator), g.expr(e.Left), g.expr(e.Right))
+	case *ast.CallExpression:
+		if e.Function.TokenLiteral() == "bol_bhai" {
+			return fmt.Sprintf("println(%s)", g.list(e.Arguments))
+		}
+		if e.Function.TokenLiteral() == "suna_bhai" {
+			return fmt.Sprintf("input(%d)", len(e.Arguments))
+		}
+		if e.Function.TokenLiteral() == "jod_bhai" {
+			if len(e.Arguments) < 2 {
+				return "append(null)"
+			}
+			return fmt.Sprintf("append(%s)", g.list(e.Arguments))
+		}
+		return fmt.Sprintf("call(%s)", jsString(e.Function.TokenLiteral()))
+	case *ast.IfExpression, *ast.WhileExpression, *ast.ForExpression, *ast.ForEachExpression:
+		return g.valueFunc(&ast.ExpressionStatement{Expression: e})
+	case nil:
+		return fmt.Sprintf("unknownNode(%s)", jsString("<nil>"))
+	}
+	g.fail(exp, "unsupported expression %T", exp)
+	return ""
+}
+
+// thunk renders an expression evaluated later by the runtime, or null if it
+// is missing.
+func (g *jsGen) thunk(exp ast.Expression) string {
+	if exp == nil {
+		return "null"
+	}
+	return "() => " + g.expr(exp)
+}
+
+func (g *jsGen) list(exps []ast.Expression) string {
+	parts := make([]string, len(exps))
+	for i, exp := range exps {
+		parts[i] = g.expr(exp)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (g *jsGen) valueFunc(stmt ast.Statement) string {
+	inner := &jsGen{jsState: g.jsState, indent: g.indent + 1, env: g.env, inFunc: true}
+	inner.result = inner.name("$v")
+	inner.line("let %s;", inner.result)
+	inner.statement(stmt)
+	inner.line("return %s;", inner.result)
+	return "(() => {\n" + inner.buf.String() + strings.Repeat("  ", g.indent) + "})()"
+}
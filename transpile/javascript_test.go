@@ -0,0 +1,156 @@
+package transpile
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	brolangast "github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/evaluator"
+	"github.com/ankush-web-eng/brolang/object"
+)
+
+// jsPrograms exercise behaviour that only the JavaScript target reproduces:
+// values that change type, runtime errors and the evaluator's scoping.
+var jsPrograms = []string{
+	`bhai_sun x = 5;
+    x = "five";
+    bol_bhai(x);`,
+	`bhai_sun total = 0;
+    chal_bhai (bhai_sun i = 0; i < 3; i = i + 1) {
+        total = total + i;
+    }
+    bol_bhai(total);`,
+	`bhai_sun words = ["a", "b"];
+    bol_bhai(words);
+    bol_bhai(words[2]);`,
+	`bol_bhai([1, "two"]);`,
+	`bhai_sun x = 1;
+    bol_bhai(x + sach);`,
+	`bol_bhai(missing);`,
+	`bhai_sun i = 0;
+    jaha_tak (sach) {
+        i = i + 1;
+    }`,
+	`bhai_sun n = 0;
+    jaha_tak (n < 10000) {
+        n = n + 1;
+    }
+    bol_bhai(n);`,
+	`bhai_sun big = 9223372036854775807;
+    bol_bhai(big + 1);
+    bhai_sun m = 0 - 7;
+    bol_bhai(m / 2);
+    bol_bhai(m % 2);`,
+	`bhai_sun size = agar (sach) { "big" } nahi_to { "small" }
+    bol_bhai(size);
+    bol_bhai(agar (jhuth) { 1 });`,
+	`agar (sach) {
+        bol_bhai("before");
+        bas_kar_bhai;
+        bol_bhai("after");
+    }
+    bol_bhai("next");`,
+	`bhai_sun x = 3
+    agar (x) {
+        bhai_sun y = 4
+    }
+    bol_bhai(y);
+    agar ("") { bol_bhai("strings are truthy"); }`,
+	`bol_bhai(suna_bhai());`,
+	`bhai_sun arr = [1, 2, 3];
+    arr[-1] = arr[0] + 5;
+    bhai_sun alias = arr;
+    jod_bhai(alias, 4, 5);
+    bol_bhai(arr);
+    bol_bhai(arr[1:-1]);
+    bol_bhai(arr[:2]);
+    bhai_sun s = "namaste";
+    bol_bhai(s[-1]);
+    bol_bhai(s[2:4]);
+    bol_bhai(arr[2:9]);`,
+	`bhai_sun arr = [1];
+    arr[0] = "one";
+    bol_bhai(arr);
+    jod_bhai(arr, 2);`,
+	`bhai_sun s = "ab";
+    s[0] = "c";`,
+	`bhai_sun arr = [1, 2];
+    bol_bhai(arr[2:1]);`,
+	`bhai_sun e = [];
+    bol_bhai(jod_bhai(e, "x"));
+    jod_bhai(e);`,
+	`bol_bhai(5[missing:]);`,
+	`chal_bhai (bhai_sun i, c me "नमस्ते") {
+        bol_bhai(c);
+    }
+    bhai_sun arr = [1, 2];
+    chal_bhai (bhai_sun x me arr) {
+        jod_bhai(arr, x * 10);
+        arr[1] = 7;
+        bol_bhai(x);
+    }
+    bol_bhai(arr);`,
+	`chal_bhai (bhai_sun n me 0..20000) {
+        bhai_sun last = n;
+    }`,
+	`bhai_sun n = 0;
+    bol_bhai(10 % 3);
+    bol_bhai(10 / n);`,
+	`chal_bhai (bhai_sun x me 5) {
+        bol_bhai(x);
+    }`,
+	`chal_bhai (bhai_sun x me 1.."ten") {
+        bol_bhai(x);
+    }`,
+}
+
+// evaluate runs a program through the interpreter and returns what it printed
+// and the error it stopped with, if any.
+func evaluate(program *brolangast.Program) (string, string) {
+	env := object.NewEnvironment()
+	result := evaluator.Eval(program, env)
+	if result != nil && result.Type() == object.ERROR_OBJ {
+		return env.OutputBuilder.String(), result.Inspect()
+	}
+	return env.OutputBuilder.String(), ""
+}
+
+func TestToJSMatchesInterpreter(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	programs := append(append([]string{}, conformancePrograms...), jsPrograms...)
+	for i, input := range programs {
+		output, errMsg := evaluate(parseBrolang(t, input))
+
+		src, err := ToJS(parseBrolang(t, input))
+		if err != nil {
+			t.Fatalf("program %d - transpile failed: %v", i, err)
+		}
+
+		file := filepath.Join(t.TempDir(), "main.js")
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(node, file)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		runErr := cmd.Run()
+
+		if stdout.String() != output {
+			t.Errorf("program %d - output differs.\ninterpreter=%q\nnode=%q", i, output, stdout.String())
+		}
+		if got := strings.TrimSuffix(stderr.String(), "\n"); got != errMsg {
+			t.Errorf("program %d - error differs.\ninterpreter=%q\nnode=%q", i, errMsg, got)
+		}
+		if (runErr != nil) != (errMsg != "") {
+			t.Errorf("program %d - node exit status %v, interpreter error %q", i, runErr, errMsg)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+// Runtime for programs generated by transpile.ToJS. It mirrors the evaluator
+// package: integers are 64-bit (BigInt), every loop gets its own environment
+// and failures produce the same messages as object.Error.
+
+const MAX_ITERATIONS = 10000;
+
+const NULL = {
+  type: "NULL",
+  inspect: () =>
+    "Hn beta, jis keyboard se ladkiyo k DMs m milta h tu, typing na hori hogi tere se isi keyboard se!!",
+};
+const BREAK = { type: "BREAK", inspect: () => "break" };
+const CONTINUE = { type: "CONTINUE", inspect: () => "continue" };
+
+class BroError extends Error {
+  inspect() {
+    return "bhai galati kardi tune " + this.message;
+  }
+}
+
+// GoPanic stands for a Go runtime panic in the evaluator, such as a nil
+// pointer dereference. It is reported as is, without the object.Error prefix.
+class GoPanic extends Error {}
+
+function error(message) {
+  return new BroError(message);
+}
+
+class Env {
+  constructor(outer) {
+    this.store = new Map();
+    this.outer = outer;
+  }
+
+  get(name) {
+    for (let env = this; env !== null; env = env.outer) {
+      if (env.store.has(name)) {
+        return env.store.get(name);
+      }
+    }
+    throw error("Abe hosh me rehle! " + name + " kaha likha h tune bataiyo zara...");
+  }
+
+  set(name, value) {
+    this.store.set(name, value);
+    return value;
+  }
+
+  assign(name, value) {
+    for (let env = this; env !== null; env = env.outer) {
+      if (env.store.has(name)) {
+        env.store.set(name, value);
+        return value;
+      }
+    }
+    throw error("Pehle bhai_sun to likh! " + name + " naam ka koi variable hi nahi h jisme daalu!!");
+  }
+}
+
+// value stands in for a Go method call on a nil object.Object, which the
+// evaluator produces for empty blocks.
+function value(v) {
+  if (v === undefined) {
+    throw new GoPanic("runtime error: invalid memory address or nil pointer dereference");
+  }
+  return v;
+}
+
+function typeOf(v) {
+  switch (typeof value(v)) {
+    case "bigint":
+      return "INTEGER";
+    case "string":
+      return "STRING";
+    case "boolean":
+      return "BOOLEAN";
+  }
+  return Array.isArray(v) ? "ARRAY" : v.type;
+}
+
+function inspect(v) {
+  switch (typeof value(v)) {
+    case "bigint":
+    case "boolean":
+      return String(v);
+    case "string":
+      return v;
+  }
+  if (Array.isArray(v)) {
+    return "[" + v.map(inspect).join(", ") + "]";
+  }
+  return v.inspect();
+}
+
+function truthy(v) {
+  switch (typeof v) {
+    case "boolean":
+      return v;
+    case "bigint":
+      return v !== 0n;
+  }
+  return v !== NULL;
+}
+
+// let_ skips the binding when the value is missing, like evalLetStatement.
+function let_(env, name, v) {
+  return v === undefined ? v : env.set(name, v);
+}
+
+function assign(env, name, v) {
+  return env.assign(name, v);
+}
+
+function print(v) {
+  if (v === undefined) {
+    throw error("kya coder banega re tu!! Print karana bhi nahi seekha!!");
+  }
+  write(inspect(v));
+  return v;
+}
+
+function array(elements) {
+  if (elements.length > 0) {
+    const first = typeOf(elements[0]);
+    for (const el of elements.slice(1)) {
+      if (typeOf(el) !== first) {
+        throw error(
+          "Girgit mat ban, datatype mat badle array ke elements ka. " + first + " ko " + typeOf(el) + " se saath mix mat kar!!",
+        );
+      }
+    }
+  }
+  return elements;
+}
+
+function notIndexable(left) {
+  return error("Kya coder banega re tu!! Sabse basic data structure bhi nahi aata tujhe!!: " + typeOf(left));
+}
+
+// position checks an index or slice bound against a length, counting
+// negative values from the end like elementIndex and sliceBound. A bound may
+// equal the length; an index may not.
+function position(idx, length, what, bound) {
+  if (typeOf(idx) !== "INTEGER") {
+    throw error("Beta tum se nahi ho payega, jao arrays padh ke aao striver sir se! Integer daal be,S " + typeOf(idx));
+  }
+  const i = idx < 0n ? idx + BigInt(length) : idx;
+  if (i < 0n || i > BigInt(length) || (i === BigInt(length) && !bound)) {
+    throw error("Aukaat m rehle aukaat m, " + idx + " index pe kuch nahi hai! Bahar mat jaa " + what + " se!!");
+  }
+  return Number(i);
+}
+
+// Strings are indexed by character (code point), as the evaluator indexes runes.
+function index(left, idx) {
+  switch (typeOf(left)) {
+    case "ARRAY":
+      return left[position(idx, left.length, "array", false)];
+    case "STRING": {
+      const chars = Array.from(left);
+      return chars[position(idx, chars.length, "string", false)];
+    }
+  }
+  throw notIndexable(left);
+}
+
+// slice takes its bounds as functions (or null when left out) because the
+// evaluator only evaluates them once it knows left can be sliced.
+function slice(left, start, end) {
+  let items;
+  let what;
+  switch (typeOf(left)) {
+    case "ARRAY":
+      [items, what] = [left, "array"];
+      break;
+    case "STRING":
+      [items, what] = [Array.from(left), "string"];
+      break;
+    default:
+      throw notIndexable(left);
+  }
+  const from = start === null ? 0 : position(start(), items.length, what, true);
+  const to = end === null ? items.length : position(end(), items.length, what, true);
+  if (from > to) {
+    throw error("Ulta slice nahi banta bhai, " + from + " se " + to + " tak kuch nahi hai!!");
+  }
+  const part = items.slice(from, to);
+  return what === "string" ? part.join("") : part;
+}
+
+// checkElement mirrors checkElementType: v must have the type of the elements
+// of left other than the one at skip.
+function checkElement(left, v, skip) {
+  const other = left.findIndex((_, i) => i !== skip);
+  if (other !== -1 && typeOf(left[other]) !== typeOf(v)) {
+    throw error(
+      "Girgit mat ban, datatype mat badle array ke elements ka. " + typeOf(left[other]) + " ko " + typeOf(v) + " se saath mix mat kar!!",
+    );
+  }
+}
+
+function setIndex(left, idx, v) {
+  if (typeOf(left) !== "ARRAY") {
+    if (typeOf(left) === "STRING") {
+      throw error("String pathar ki lakeer h bhai, uska ek character nahi badal sakte!!");
+    }
+    throw notIndexable(left);
+  }
+  const i = position(idx, left.length, "array", false);
+  checkElement(left, v, i);
+  left[i] = v;
+  return v;
+}
+
+// append implements jod_bhai. args is null when the call has fewer than two
+// arguments, which the evaluator rejects before evaluating any of them.
+function append(args) {
+  if (args === null) {
+    throw error("jod_bhai(array, value) likh bhai, kisme kya jodna h wo bhi bata!!");
+  }
+  const [left, ...values] = args;
+  if (typeOf(left) !== "ARRAY") {
+    throw error("jod_bhai pehle array maangta h, " + typeOf(left) + " nahi!!");
+  }
+  for (const v of values) {
+    checkElement(left, v, -1);
+    left.push(v);
+  }
+  return left;
+}
+
+// iterate describes the passes of a for-each loop over an array or string:
+// how many there are and the value of each. Array elements are read as the
+// loop reaches them.
+function iterate(v) {
+  switch (typeOf(v)) {
+    case "ARRAY":
+      return { length: v.length, at: (i) => v[i] };
+    case "STRING": {
+      const chars = Array.from(v);
+      return { length: chars.length, at: (i) => chars[i] };
+    }
+  }
+  throw error("chal_bhai me sirf array, string ya range pe chal sakte h, " + typeOf(v) + " pe nahi!!");
+}
+
+// range is iterate for start..end. Ranges longer than the iteration limit are
+// cut just past it, since tick stops the loop there anyway.
+function range(start, end) {
+  if (typeOf(start) !== "INTEGER" || typeOf(end) !== "INTEGER") {
+    throw error("Range ke dono sire integer hone chahiye bhai, " + typeOf(start) + ".." + typeOf(end) + " nahi chalega!!");
+  }
+  const n = end > start ? end - start : 0n;
+  const max = BigInt(MAX_ITERATIONS + 1);
+  return { length: Number(n < max ? n : max), at: (i) => start + BigInt(i) };
+}
+
+function prefix(op, right) {
+  switch (op) {
+    case "-":
+      if (typeOf(right) !== "INTEGER") {
+        throw error("Bete '-' aur " + typeOf(right) + " ka sambandh nahi ban sakta!!");
+      }
+      return BigInt.asIntN(64, -right);
+    case "!":
+      return !truthy(right);
+  }
+  throw error("Ye konsa operator h!?!?: " + op);
+}
+
+function infix(op, left, right) {
+  if (typeOf(left) !== "INTEGER" || typeOf(right) !== "INTEGER") {
+    throw error("Bete " + typeOf(left) + ", '" + op + "', aur " + typeOf(right) + " ka sambandh nahi ban sakta!!");
+  }
+  switch (op) {
+    case "+":
+      return BigInt.asIntN(64, left + right);
+    case "-":
+      return BigInt.asIntN(64, left - right);
+    case "*":
+      return BigInt.asIntN(64, left * right);
+    case "/":
+    case "%":
+      if (right === 0n) {
+        throw error("Zero se divide karega? Maths ki class bunk ki thi kya!!");
+      }
+      return BigInt.asIntN(64, op === "/" ? left / right : left % right);
+    case "<":
+      return left < right;
+    case ">":
+      return left > right;
+    case "==":
+      return left === right;
+    case "!=":
+      return left !== right;
+    case "<=":
+      return left <= right;
+    case ">=":
+      return left >= right;
+  }
+  throw error("Ye konsa operator h!?!?: " + op);
+}
+
+// println is a bol_bhai call used as a value, which prints each argument.
+function println(args) {
+  for (const v of args) {
+    write(inspect(v));
+  }
+  return NULL;
+}
+
+// input implements suna_bhai using the read hook, which returns the next line
+// or undefined once the input is exhausted.
+function input(argc) {
+  if (argc > 0) {
+    throw error("suna_bhai() ke andar kuch mat daal, bas sun!!");
+  }
+  if (read === null) {
+    throw error("Kaun sunayega bhai? Input to diya hi nahi!!");
+  }
+  const line = read();
+  if (line === undefined || line === null) {
+    throw error("Input khatam ho gaya bhai, aur kuch nahi hai sunane ko!!");
+  }
+  const trimmed = line.trim();
+  if (/^[+-]?[0-9]+$/.test(trimmed)) {
+    const n = BigInt(trimmed);
+    if (n === BigInt.asIntN(64, n)) {
+      return n;
+    }
+  }
+  return line;
+}
+
+function call(name) {
+  throw error("Ye konsa function h ??: " + name);
+}
+
+function unknownNode(type) {
+  throw error("unknown node type: " + type);
+}
+
+// tick enforces the evaluator's iteration limit; n counts condition checks.
+function tick(n) {
+  if (n > MAX_ITERATIONS) {
+    throw error("Mere server ka bill tera BAAP bharega ? Itni badi loop chala raha h!!");
+  }
+}
@@ -0,0 +1,485 @@
+package typecheck
+
+import (
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/diagnostic"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/parser"
+	"github.com/ankush-web-eng/brolang/token"
+)
+
+// Diagnostic codes reported by the type checker.
+const (
+	UnknownTypeName = "unknown-type"
+	TypeMismatch    = "type-mismatch"
+	InvalidOperand  = "invalid-operand"
+	InvalidIndex    = "invalid-index"
+	MixedArray      = "mixed-array"
+)
+
+// Info holds the types the checker inferred.
+type Info struct {
+	// Types maps every checked expression to its type.
+	Types map[ast.Expression]*Type
+	// Defs maps the name in every bhai_sun statement to the type of the binding.
+	Defs map[*ast.Identifier]*Type
+}
+
+// TypeOf returns the inferred type of exp, or UnknownType if it was not checked.
+func (info *Info) TypeOf(exp ast.Expression) *Type {
+	if t, ok := info.Types[exp]; ok {
+		return t
+	}
+	return UnknownType
+}
+
+type variable struct {
+	typ       *Type
+	annotated bool
+}
+
+type scope struct {
+	vars  map[string]*variable
+	outer *scope
+}
+
+func (s *scope) lookup(name string) (*variable, bool) {
+	for cur := s; cur != nil; cur = cur.outer {
+		if v, ok := cur.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type checker struct {
+	info  *Info
+	scope *scope
+	diags []diagnostic.Diagnostic
+}
+
+// Check infers the types of a parsed program and reports operations that are
+// certain to fail at runtime. Anything whose type cannot be known statically
+// is given the benefit of the doubt.
+func Check(program *ast.Program) (*Info, []diagnostic.Diagnostic) {
+	c := &checker{
+		info: &Info{
+			Types: make(map[ast.Expression]*Type),
+			Defs:  make(map[*ast.Identifier]*Type),
+		},
+		scope: &scope{vars: make(map[string]*variable)},
+	}
+	c.statements(program.Statements)
+	return c.info, c.diags
+}
+
+// Source parses and type checks the given code. Syntax errors are returned on their own.
+func Source(code string) []diagnostic.Diagnostic {
+	p := parser.New(lexer.New(code))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) > 0 {
+		return p.Diagnostics()
+	}
+	_, diags := Check(program)
+	return diags
+}
+
+func (c *checker) openScope() {
+	c.scope = &scope{vars: make(map[string]*variable), outer: c.scope}
+}
+
+func (c *checker) closeScope() {
+	c.scope = c.scope.outer
+}
+
+func (c *checker) errorf(tok token.Token, code, format string, args ...interface{}) {
+	c.diags = append(c.diags, diagnostic.New(tok, diagnostic.Error, code, format, args...))
+}
+
+func (c *checker) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		c.statement(stmt)
+	}
+}
+
+func (c *checker) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		c.letStatement(s)
+	case *ast.AssignStatement:
+		c.assignStatement(s)
+	case *ast.IndexAssignStatement:
+		c.indexAssignStatement(s)
+	case *ast.PrintStatement:
+		if s != nil {
+			c.expr(s.Expression)
+		}
+	case *ast.ExpressionStatement:
+		if s != nil {
+			c.expr(s.Expression)
+		}
+	case *ast.BlockStatement:
+		if s != nil {
+			c.openScope()
+			c.statements(s.Statements)
+			c.closeScope()
+		}
+	case *ast.TryStatement:
+		c.tryStatement(s)
+	case *ast.ThrowStatement:
+		if s != nil {
+			t := c.expr(s.Value)
+			if t.Kind != Unknown && t.Kind != String && t.Kind != Error {
+				c.errorf(ast.StartToken(s.Value), TypeMismatch, "fek_bhai needs a string or a caught error, not %s", t)
+			}
+		}
+	}
+}
+
+func (c *checker) tryStatement(ts *ast.TryStatement) {
+	if ts == nil {
+		return
+	}
+	c.statement(ts.Body)
+	if ts.Catch != nil {
+		c.openScope()
+		if ts.CatchName != nil {
+			c.define(ts.CatchName, ErrorType)
+		}
+		c.statement(ts.Catch)
+		c.closeScope()
+	}
+	c.statement(ts.Finally)
+}
+
+func (c *checker) letStatement(ls *ast.LetStatement) {
+	if ls == nil || ls.Name == nil {
+		return
+	}
+	valueType := c.expr(ls.Value)
+
+	v := &variable{typ: valueType}
+	if ls.Type != nil {
+		declared := fromAnnotation(ls.Type)
+		if declared == nil {
+			c.errorf(ls.Type.Token, UnknownTypeName, "unknown type %s (use int, string, bool or []T)", ls.Type)
+			declared = UnknownType
+		} else if !assignable(valueType, declared) {
+			c.errorf(ast.StartToken(ls.Value), TypeMismatch,
+				"cannot use %s value as %s in declaration of %s", valueType, declared, ls.Name.Value)
+		}
+		v = &variable{typ: declared, annotated: true}
+	}
+
+	c.scope.vars[ls.Name.Value] = v
+	c.info.Defs[ls.Name] = v.typ
+	c.info.Types[ls.Name] = v.typ
+}
+
+func (c *checker) assignStatement(as *ast.AssignStatement) {
+	if as == nil || as.Name == nil {
+		return
+	}
+	valueType := c.expr(as.Value)
+
+	v, ok := c.scope.lookup(as.Name.Value)
+	if !ok {
+		// Assigning an undeclared name fails at runtime, which the linter reports.
+		c.info.Types[as.Name] = valueType
+		return
+	}
+
+	if v.annotated {
+		if !assignable(valueType, v.typ) {
+			c.errorf(ast.StartToken(as.Value), TypeMismatch,
+				"cannot assign %s value to %s (declared %s)", valueType, as.Name.Value, v.typ)
+		}
+	} else if valueType.Known() && v.typ.Known() && !Identical(valueType, v.typ) {
+		// Unannotated variables may change type; stop assuming anything about it.
+		v.typ = UnknownType
+	}
+	c.info.Types[as.Name] = v.typ
+}
+
+func (c *checker) indexAssignStatement(ia *ast.IndexAssignStatement) {
+	if ia == nil || ia.Target == nil {
+		return
+	}
+	elem := c.expr(ia.Target)
+	valueType := c.expr(ia.Value)
+
+	if c.info.TypeOf(ia.Target.Left).Kind == String {
+		c.errorf(ia.Token, InvalidIndex, "cannot assign to a character of a string")
+		return
+	}
+	if !assignable(valueType, elem) {
+		c.errorf(ast.StartToken(ia.Value), TypeMismatch, "cannot assign %s value to element of type %s", valueType, elem)
+	}
+}
+
+// expr checks an expression and records its type.
+func (c *checker) expr(exp ast.Expression) *Type {
+	if exp == nil {
+		return UnknownType
+	}
+	t := c.inferExpr(exp)
+	c.info.Types[exp] = t
+	return t
+}
+
+func (c *checker) inferExpr(exp ast.Expression) *Type {
+	switch e := exp.(type) {
+	case *ast.IntegerLiteral:
+		return IntType
+	case *ast.StringLiteral:
+		return StringType
+	case *ast.Boolean:
+		return BoolType
+
+	case *ast.Identifier:
+		if v, ok := c.scope.lookup(e.Value); ok {
+			return v.typ
+		}
+		return UnknownType
+
+	case *ast.ArrayLiteral:
+		return c.arrayLiteral(e)
+
+	case *ast.IndexExpression:
+		return c.indexExpression(e)
+	case *ast.SliceExpression:
+		return c.sliceExpression(e)
+
+	case *ast.PrefixExpression:
+		right := c.expr(e.Right)
+		switch e.Operator {
+		case "-":
+			if right.Kind != Unknown && right.Kind != Int {
+				c.errorf(e.Token, InvalidOperand, "operator - not defined on %s", right)
+			}
+			return IntType
+		case "!":
+			return BoolType
+		}
+		return UnknownType
+
+	case *ast.InfixExpression:
+		return c.infixExpression(e)
+
+	case *ast.CallExpression:
+		for _, arg := range e.Arguments {
+			c.expr(arg)
+		}
+		if e.Function != nil && e.Function.TokenLiteral() == "bol_bhai" {
+			return NullType
+		}
+		if e.Function != nil && e.Function.TokenLiteral() == "jod_bhai" {
+			return c.appendCall(e)
+		}
+		return UnknownType
+
+	case *ast.IfExpression:
+		c.expr(e.Condition)
+		c.statement(e.Consequence)
+		for _, elseIf := range e.ElseIf {
+			if elseIf != nil {
+				c.expr(elseIf.Condition)
+				c.statement(elseIf.Consequence)
+			}
+		}
+		c.statement(e.Alternative)
+		return UnknownType
+
+	case *ast.WhileExpression:
+		c.openScope()
+		c.expr(e.Condition)
+		c.statement(e.Body)
+		c.closeScope()
+		return UnknownType
+
+	case *ast.ForExpression:
+		c.openScope()
+		if e.Init != nil {
+			c.statement(e.Init)
+		}
+		c.expr(e.Condition)
+		c.statement(e.Body)
+		if e.Update != nil {
+			c.statement(e.Update)
+		}
+		c.closeScope()
+		return UnknownType
+
+	case *ast.ForEachExpression:
+		c.forEachExpression(e)
+		return UnknownType
+	}
+
+	return UnknownType
+}
+
+func (c *checker) forEachExpression(fe *ast.ForEachExpression) {
+	c.openScope()
+	elem := c.iterable(fe.Iterable)
+	if fe.Index != nil {
+		c.define(fe.Index, IntType)
+	}
+	if fe.Value != nil {
+		c.define(fe.Value, elem)
+	}
+	c.statement(fe.Body)
+	c.closeScope()
+}
+
+// iterable checks what a for-each loop runs over and returns the type of the
+// values it gives the loop variable.
+func (c *checker) iterable(exp ast.Expression) *Type {
+	if rng, ok := exp.(*ast.RangeExpression); ok {
+		for _, end := range []ast.Expression{rng.Start, rng.End} {
+			if t := c.expr(end); t.Kind != Unknown && t.Kind != Int {
+				c.errorf(ast.StartToken(end), InvalidOperand, "range bounds must be int, not %s", t)
+			}
+		}
+		return IntType
+	}
+
+	t := c.expr(exp)
+	switch t.Kind {
+	case Array:
+		return t.Elem
+	case String:
+		return StringType
+	case Unknown:
+		return UnknownType
+	}
+	c.errorf(ast.StartToken(exp), TypeMismatch, "cannot loop over %s value", t)
+	return UnknownType
+}
+
+// define binds a name that the loop or pakad_bhai introduces.
+func (c *checker) define(name *ast.Identifier, t *Type) {
+	c.scope.vars[name.Value] = &variable{typ: t}
+	c.info.Defs[name] = t
+	c.info.Types[name] = t
+}
+
+func (c *checker) arrayLiteral(al *ast.ArrayLiteral) *Type {
+	var elem *Type
+	for _, el := range al.Elements {
+		t := c.expr(el)
+		if t.Kind == Unknown {
+			continue
+		}
+		if elem == nil {
+			elem = t
+			continue
+		}
+		if elem.Kind != t.Kind {
+			c.errorf(ast.StartToken(el), MixedArray,
+				"array elements must all have the same type: found %s after %s", t, elem)
+		} else if !Identical(elem, t) {
+			// The evaluator only compares the outer type of nested arrays.
+			elem = ArrayOf(UnknownType)
+		}
+	}
+	if elem == nil {
+		elem = UnknownType
+	}
+	return ArrayOf(elem)
+}
+
+func (c *checker) indexExpression(ie *ast.IndexExpression) *Type {
+	left := c.expr(ie.Left)
+	index := c.expr(ie.Index)
+
+	if left.Kind == Error {
+		// galti["message"] and galti["code"]
+		if index.Kind != Unknown && index.Kind != String {
+			c.errorf(ast.StartToken(ie.Index), InvalidIndex, "error fields are named by string, not %s", index)
+		}
+		return StringType
+	}
+
+	if index.Kind != Unknown && index.Kind != Int {
+		c.errorf(ast.StartToken(ie.Index), InvalidIndex, "array index must be int, not %s", index)
+	}
+
+	switch left.Kind {
+	case Array:
+		return left.Elem
+	case String:
+		return StringType
+	case Unknown:
+		return UnknownType
+	default:
+		c.errorf(ast.StartToken(ie.Left), InvalidIndex, "cannot index %s value", left)
+		return UnknownType
+	}
+}
+
+func (c *checker) sliceExpression(se *ast.SliceExpression) *Type {
+	left := c.expr(se.Left)
+	for _, bound := range []ast.Expression{se.Start, se.End} {
+		if bound == nil {
+			continue
+		}
+		if t := c.expr(bound); t.Kind != Unknown && t.Kind != Int {
+			c.errorf(ast.StartToken(bound), InvalidIndex, "slice bound must be int, not %s", t)
+		}
+	}
+
+	switch left.Kind {
+	case Array, String, Unknown:
+		return left
+	default:
+		c.errorf(ast.StartToken(se.Left), InvalidIndex, "cannot slice %s value", left)
+		return UnknownType
+	}
+}
+
+// appendCall checks jod_bhai(arr, values...), whose arguments have already
+// been checked, and returns the type of arr.
+func (c *checker) appendCall(ce *ast.CallExpression) *Type {
+	if len(ce.Arguments) == 0 {
+		return UnknownType
+	}
+	array := c.info.TypeOf(ce.Arguments[0])
+	switch array.Kind {
+	case Array:
+	case Unknown:
+		return UnknownType
+	default:
+		c.errorf(ast.StartToken(ce.Arguments[0]), TypeMismatch, "jod_bhai needs an array, not %s", array)
+		return UnknownType
+	}
+
+	for _, arg := range ce.Arguments[1:] {
+		if t := c.info.TypeOf(arg); !assignable(t, array.Elem) {
+			c.errorf(ast.StartToken(arg), TypeMismatch, "cannot add %s value to %s", t, array)
+		}
+	}
+	return array
+}
+
+func (c *checker) infixExpression(ie *ast.InfixExpression) *Type {
+	left := c.expr(ie.Left)
+	right := c.expr(ie.Right)
+
+	// Every operator the evaluator knows works on integers only.
+	for _, operand := range []struct {
+		typ *Type
+		exp ast.Expression
+	}{{left, ie.Left}, {right, ie.Right}} {
+		if operand.typ.Kind != Unknown && operand.typ.Kind != Int {
+			c.errorf(ast.StartToken(operand.exp), InvalidOperand,
+				"operator %s not defined on %s", ie.Operator, operand.typ)
+		}
+	}
+
+	switch ie.Operator {
+	case "<", ">", "==", "!=", "<=", ">=":
+		return BoolType
+	default:
+		return IntType
+	}
+}
@@ -0,0 +1,101 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/lexer"
+	"github.com/ankush-web-eng/brolang/parser"
+)
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedCodes  []string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{`bhai_sun x = 5; bol_bhai(x + 1);`, nil, 0, 0},
+		{`bhai_sun x: int = 5;`, nil, 0, 0},
+		{`bhai_sun arr: []string = [];`, nil, 0, 0},
+		{`bhai_sun x: int = "five";`, []string{TypeMismatch}, 1, 19},
+		{`bhai_sun x: float = 5;`, []string{UnknownTypeName}, 1, 13},
+		{`bhai_sun x: int = 5;
+x = "six";`, []string{TypeMismatch}, 2, 5},
+		// Unannotated variables may change type at runtime.
+		{`bhai_sun x = 5; x = "six"; bol_bhai(x);`, nil, 0, 0},
+		{`bhai_sun s = "a";
+bol_bhai(s * 2);`, []string{InvalidOperand}, 2, 10},
+		{`bhai_sun arr = [1, 2];
+bol_bhai(arr["0"]);`, []string{InvalidIndex}, 2, 14},
+		{`bhai_sun n = 1; bol_bhai(n[0]);`, []string{InvalidIndex}, 1, 26},
+		{`bhai_sun arr = [1, sach];`, []string{MixedArray}, 1, 20},
+		{`bhai_sun arr = [[1], ["a"]];`, nil, 0, 0},
+		{`chal_bhai (bhai_sun i = 0; i < "3"; i = i + 1) { bol_bhai(i); }`, []string{InvalidOperand}, 1, 32},
+		{`jaha_tak (sach) { bhai_sun b: bool = 1; }`, []string{TypeMismatch}, 1, 38},
+		{`koshish_kar_bhai { fek_bhai "x"; } pakad_bhai (g) { bhai_sun m: string = g["message"]; }`, nil, 0, 0},
+		{`koshish_kar_bhai { fek_bhai sach; } aakhir_me { bol_bhai(1); }`, []string{TypeMismatch}, 1, 29},
+		{`koshish_kar_bhai { fek_bhai "x"; } pakad_bhai (g) { bol_bhai(g[0]); }`, []string{InvalidIndex}, 1, 64},
+		{`bhai_sun arr: []int = [1, 2]; arr[-1] = 3; bhai_sun part: []int = arr[1:]; bhai_sun c: string = "ab"[0];`, nil, 0, 0},
+		{`bhai_sun arr = [1, 2];
+arr[0] = "one";`, []string{TypeMismatch}, 2, 10},
+		{`bhai_sun s = "ab"; s[0] = "c";`, []string{InvalidIndex}, 1, 20},
+		{`bhai_sun arr = [1]; jod_bhai(arr, "two");`, []string{TypeMismatch}, 1, 35},
+		{`bhai_sun arr = [1]; bol_bhai(arr[sach:]);`, []string{InvalidIndex}, 1, 34},
+		{`chal_bhai (bhai_sun i, w me ["a"]) { bhai_sun n: int = i; bhai_sun s: string = w; }`, nil, 0, 0},
+		{`chal_bhai (bhai_sun c me "ab") { bhai_sun n: int = c; }`, []string{TypeMismatch}, 1, 52},
+		{`chal_bhai (bhai_sun n me 0.."das") { bol_bhai(n); }`, []string{InvalidOperand}, 1, 29},
+		{`chal_bhai (bhai_sun x me sach) { bol_bhai(x); }`, []string{TypeMismatch}, 1, 26},
+		{`bhai_sun x: int = 1; agar (sach) { bhai_sun x = "ek"; } bhai_sun y: int = x;`, nil, 0, 0},
+		{`bhai_sun x: int = 1; agar (sach) { bhai_sun x = "ek"; bhai_sun y: int = x; }`, []string{TypeMismatch}, 1, 73},
+	}
+
+	for i, tt := range tests {
+		diags := Source(tt.input)
+		if len(diags) != len(tt.expectedCodes) {
+			t.Errorf("test %d - wrong number of diagnostics. expected=%v, got=%v", i, tt.expectedCodes, diags)
+			continue
+		}
+		for j, code := range tt.expectedCodes {
+			if diags[j].Code != code {
+				t.Errorf("test %d - wrong code. expected=%q, got=%q", i, code, diags[j].Code)
+			}
+		}
+		if len(diags) > 0 && (diags[0].Line != tt.expectedLine || diags[0].Column != tt.expectedColumn) {
+			t.Errorf("test %d - wrong position. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, diags[0].Line, diags[0].Column)
+		}
+	}
+}
+
+func TestInferredTypes(t *testing.T) {
+	input := `
+    bhai_sun a = 1 + 2;
+    bhai_sun b: bool = sach;
+    bhai_sun c = ["x", "y"];
+    bhai_sun d = c[0];
+    bhai_sun e: []int = [];
+    bhai_sun f = [[1], [2]];
+    `
+	program := parser.New(lexer.New(input)).ParseProgram()
+	info, diags := Check(program)
+	if len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	expected := map[string]string{
+		"a": "int",
+		"b": "bool",
+		"c": "[]string",
+		"d": "string",
+		"e": "[]int",
+		"f": "[][]int",
+	}
+	for _, stmt := range program.Statements {
+		let := stmt.(*ast.LetStatement)
+		got := info.Defs[let.Name].String()
+		if got != expected[let.Name.Value] {
+			t.Errorf("%s has wrong type. expected=%s, got=%s", let.Name.Value, expected[let.Name.Value], got)
+		}
+	}
+}
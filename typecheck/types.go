@@ -0,0 +1,138 @@
+package typecheck
+
+import (
+	"github.com/ankush-web-eng/brolang/ast"
+	"github.com/ankush-web-eng/brolang/object"
+)
+
+type Kind int
+
+const (
+	Unknown Kind = iota // not known statically; never reported as an error
+	Int
+	String
+	Bool
+	Array
+	Null
+	Error // an error caught by pakad_bhai
+)
+
+// Type is the static type of an expression. Array types carry their element type.
+type Type struct {
+	Kind Kind
+	Elem *Type
+}
+
+var (
+	UnknownType = &Type{Kind: Unknown}
+	IntType     = &Type{Kind: Int}
+	StringType  = &Type{Kind: String}
+	BoolType    = &Type{Kind: Bool}
+	NullType    = &Type{Kind: Null}
+	ErrorType   = &Type{Kind: Error}
+)
+
+// ArrayOf returns the type of an array whose elements have type elem.
+func ArrayOf(elem *Type) *Type {
+	return &Type{Kind: Array, Elem: elem}
+}
+
+// String returns the type in annotation syntax, e.g. "[]int".
+func (t *Type) String() string {
+	switch t.Kind {
+	case Int:
+		return "int"
+	case String:
+		return "string"
+	case Bool:
+		return "bool"
+	case Null:
+		return "null"
+	case Error:
+		return "error"
+	case Array:
+		return "[]" + t.Elem.String()
+	default:
+		return "unknown"
+	}
+}
+
+// ObjectType returns the runtime object type values of this type will have.
+func (t *Type) ObjectType() object.ObjectType {
+	switch t.Kind {
+	case Int:
+		return object.INTEGER_OBJ
+	case String:
+		return object.STRING_OBJ
+	case Bool:
+		return object.BOOLEAN_OBJ
+	case Null:
+		return object.NULL_OBJ
+	case Error:
+		return object.EXCEPTION_OBJ
+	case Array:
+		return object.ARRAY_OBJ
+	default:
+		return ""
+	}
+}
+
+// Known reports whether the type, including any element type, is fully known.
+func (t *Type) Known() bool {
+	switch t.Kind {
+	case Unknown:
+		return false
+	case Array:
+		return t.Elem.Known()
+	default:
+		return true
+	}
+}
+
+// Identical reports whether two fully known types are the same.
+func Identical(a, b *Type) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	if a.Kind == Array {
+		return Identical(a.Elem, b.Elem)
+	}
+	return true
+}
+
+// assignable reports whether a value of type from may be stored where type to
+// is expected. Unknown on either side is always accepted, as is an empty
+// array literal (whose element type is unknown) for any array type.
+func assignable(from, to *Type) bool {
+	if from.Kind == Unknown || to.Kind == Unknown {
+		return true
+	}
+	if from.Kind != to.Kind {
+		return false
+	}
+	if from.Kind == Array {
+		return assignable(from.Elem, to.Elem)
+	}
+	return true
+}
+
+// fromAnnotation converts a parsed annotation, returning nil for unknown type names.
+func fromAnnotation(ta *ast.TypeAnnotation) *Type {
+	if ta.Elem != nil {
+		elem := fromAnnotation(ta.Elem)
+		if elem == nil {
+			return nil
+		}
+		return ArrayOf(elem)
+	}
+
+	switch ta.Name {
+	case "int":
+		return IntType
+	case "string":
+		return StringType
+	case "bool":
+		return BoolType
+	}
+	return nil
+}
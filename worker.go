@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ankush-web-eng/brolang/sandbox"
+)
+
+// workerCommand implements `brolang worker`, a sandbox worker that runs the
+// jobs the API server sends it over stdin. It is started by sandbox.Pool and
+// not meant to be run by hand.
+func workerCommand(args []string) int {
+	if err := sandbox.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "brolang worker: %v\n", err)
+		return 1
+	}
+	return 0
+}